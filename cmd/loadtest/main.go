@@ -0,0 +1,211 @@
+// Command loadtest drives a local API through an event's waiting room. Each
+// simulated user registers, joins the queue, polls until admitted and then
+// books with its admission token. It prints how long users waited and how
+// fast the room admitted them, so the rate can be checked against the
+// room's admit_per_minute.
+//
+// The event must already have an active waiting room:
+//
+//	go run ./cmd/loadtest -event 1 -users 500
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+type result struct {
+	status   int
+	err      error
+	waited   time.Duration
+	admitted time.Time
+}
+
+type client struct {
+	base  string
+	http  *http.Client
+	token string
+}
+
+func main() {
+	base := flag.String("url", "http://localhost:8080", "base URL of the API")
+	eventID := flag.Int("event", 0, "ID of an event with an active waiting room")
+	users := flag.Int("users", 100, "number of simulated users")
+	poll := flag.Duration("poll", 2*time.Second, "how often users poll their queue position")
+	timeout := flag.Duration("timeout", 10*time.Minute, "how long a user waits before giving up")
+	flag.Parse()
+	if *eventID == 0 {
+		log.Fatal("-event is required")
+	}
+
+	httpClient := &http.Client{Timeout: 30 * time.Second}
+	run := fmt.Sprintf("%d", time.Now().UnixNano())
+
+	// The event name is what bookings refer to
+	admin := &client{base: *base, http: httpClient}
+	if err := admin.signUp("loadtest-" + run + "-probe"); err != nil {
+		log.Fatalf("Failed to sign up: %v", err)
+	}
+	var event struct {
+		Name string `json:"name"`
+	}
+	if status, err := admin.do(http.MethodGet, fmt.Sprintf("/events/%d", *eventID), nil, "", &event); err != nil || status != http.StatusOK {
+		log.Fatalf("Failed to load event %d: status %d: %v", *eventID, status, err)
+	}
+	// Booking without an admission token must be turned away
+	status, err := admin.do(http.MethodPost, "/bookings", map[string]string{"user_name": "probe", "event": event.Name}, "", nil)
+	if err != nil {
+		log.Fatalf("Failed to probe the gate: %v", err)
+	}
+	if status != http.StatusForbidden {
+		log.Printf("Warning: booking without an admission token returned %d, want 403; is the waiting room active?", status)
+	}
+
+	start := time.Now()
+	results := make([]result, *users)
+	var wg sync.WaitGroup
+	for i := 0; i < *users; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			c := &client{base: *base, http: httpClient}
+			results[i] = c.queueAndBook(fmt.Sprintf("loadtest-%s-%d", run, i), *eventID, event.Name, *poll, *timeout)
+		}(i)
+	}
+	wg.Wait()
+	report(results, time.Since(start))
+}
+
+// queueAndBook takes one user from sign-up to a booking
+func (c *client) queueAndBook(username string, eventID int, event string, poll, timeout time.Duration) result {
+	if err := c.signUp(username); err != nil {
+		return result{err: err}
+	}
+	queue := fmt.Sprintf("/events/%d/queue", eventID)
+	var position struct {
+		Status string `json:"status"`
+		Token  string `json:"token"`
+	}
+	joined := time.Now()
+	status, err := c.do(http.MethodPost, queue, nil, "", &position)
+	if err != nil || status != http.StatusAccepted {
+		return result{status: status, err: fmt.Errorf("join queue: %v", err)}
+	}
+	for position.Status == "waiting" {
+		if time.Since(joined) > timeout {
+			return result{err: fmt.Errorf("still waiting after %s", timeout)}
+		}
+		time.Sleep(poll)
+		if status, err := c.do(http.MethodGet, queue, nil, "", &position); err != nil || status != http.StatusOK {
+			return result{status: status, err: fmt.Errorf("poll queue: %v", err)}
+		}
+	}
+	if position.Status != "admitted" {
+		return result{err: fmt.Errorf("left the queue %s", position.Status)}
+	}
+	res := result{waited: time.Since(joined), admitted: time.Now()}
+	res.status, res.err = c.do(http.MethodPost, "/bookings", map[string]string{"user_name": username, "event": event}, position.Token, nil)
+	return res
+}
+
+func (c *client) signUp(username string) error {
+	creds := map[string]string{"username": username, "password": "loadtest-password"}
+	if status, err := c.do(http.MethodPost, "/register", creds, "", nil); err != nil || status >= 300 {
+		return fmt.Errorf("register: status %d: %v", status, err)
+	}
+	var login struct {
+		Token string `json:"token"`
+	}
+	if status, err := c.do(http.MethodPost, "/login", creds, "", &login); err != nil || status != http.StatusOK {
+		return fmt.Errorf("login: status %d: %v", status, err)
+	}
+	c.token = login.Token
+	return nil
+}
+
+// do sends a JSON request and decodes a successful JSON response into out
+func (c *client) do(method, path string, body interface{}, admission string, out interface{}) (int, error) {
+	var payload io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return 0, err
+		}
+		payload = bytes.NewReader(b)
+	}
+	req, err := http.NewRequest(method, c.base+path, payload)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	if admission != "" {
+		req.Header.Set("X-Admission-Token", admission)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if out != nil && resp.StatusCode < 300 {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return resp.StatusCode, err
+		}
+	}
+	return resp.StatusCode, nil
+}
+
+func report(results []result, elapsed time.Duration) {
+	statuses := map[int]int{}
+	var waits []time.Duration
+	var first, last time.Time
+	failed := 0
+	for _, r := range results {
+		if r.err != nil {
+			failed++
+			log.Printf("User failed: %v", r.err)
+			continue
+		}
+		statuses[r.status]++
+		waits = append(waits, r.waited)
+		if first.IsZero() || r.admitted.Before(first) {
+			first = r.admitted
+		}
+		if r.admitted.After(last) {
+			last = r.admitted
+		}
+	}
+	fmt.Printf("users: %d, failed: %d, elapsed: %s\n", len(results), failed, elapsed.Round(time.Second))
+	codes := make([]int, 0, len(statuses))
+	for code := range statuses {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	for _, code := range codes {
+		fmt.Printf("booking status %d: %d\n", code, statuses[code])
+	}
+	if len(waits) == 0 {
+		return
+	}
+	sort.Slice(waits, func(i, j int) bool { return waits[i] < waits[j] })
+	fmt.Printf("queue wait p50: %s, p95: %s, max: %s\n",
+		percentile(waits, 50), percentile(waits, 95), waits[len(waits)-1].Round(time.Millisecond))
+	if span := last.Sub(first); span >= time.Minute {
+		fmt.Printf("admitted per minute: %.1f\n", float64(len(waits)-1)/span.Minutes())
+	}
+}
+
+func percentile(sorted []time.Duration, p int) time.Duration {
+	i := (len(sorted) - 1) * p / 100
+	return sorted[i].Round(time.Millisecond)
+}
@@ -0,0 +1 @@
+ALTER TABLE bookings DROP COLUMN user_id;
\ No newline at end of file
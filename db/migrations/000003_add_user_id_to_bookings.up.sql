@@ -0,0 +1 @@
+ALTER TABLE bookings ADD COLUMN user_id INTEGER REFERENCES users(id);
\ No newline at end of file
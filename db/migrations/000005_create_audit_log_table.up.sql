@@ -0,0 +1,30 @@
+CREATE TABLE audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    entity_type VARCHAR(64) NOT NULL,
+    entity_id BIGINT NOT NULL,
+    action VARCHAR(32) NOT NULL,
+    actor_user_id INTEGER,
+    request_id VARCHAR(128),
+    before JSONB,
+    after JSONB,
+    diff JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX audit_log_entity_idx ON audit_log (entity_type, entity_id, created_at);
+CREATE INDEX audit_log_actor_idx ON audit_log (actor_user_id, created_at);
+CREATE INDEX audit_log_created_at_idx ON audit_log (created_at);
+
+CREATE FUNCTION audit_log_immutable() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_log_no_modify
+    BEFORE UPDATE OR DELETE ON audit_log
+    FOR EACH ROW EXECUTE FUNCTION audit_log_immutable();
+
+CREATE TRIGGER audit_log_no_truncate
+    BEFORE TRUNCATE ON audit_log
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_log_immutable();
@@ -0,0 +1,3 @@
+DROP INDEX bookings_event_idx;
+DROP TABLE holds;
+DROP TABLE events;
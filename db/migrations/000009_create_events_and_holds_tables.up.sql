@@ -0,0 +1,24 @@
+CREATE TABLE events (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL UNIQUE,
+    capacity INTEGER CHECK (capacity >= 0),
+    organizer_id INTEGER REFERENCES users(id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE holds (
+    id SERIAL PRIMARY KEY,
+    event VARCHAR(255) NOT NULL,
+    user_id INTEGER REFERENCES users(id),
+    user_name VARCHAR(255) NOT NULL,
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    status VARCHAR(16) NOT NULL DEFAULT 'active',
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT holds_status_check CHECK (status IN ('active', 'converted', 'released', 'expired'))
+);
+
+CREATE INDEX holds_active_event_idx ON holds (event, expires_at) WHERE status = 'active';
+CREATE INDEX bookings_event_idx ON bookings (event) WHERE is_active;
@@ -0,0 +1,21 @@
+ALTER TABLE events ADD COLUMN waitlist_claim_minutes INTEGER CHECK (waitlist_claim_minutes > 0);
+
+CREATE TABLE waitlist_entries (
+    id SERIAL PRIMARY KEY,
+    event VARCHAR(255) NOT NULL,
+    user_id INTEGER REFERENCES users(id),
+    user_name VARCHAR(255) NOT NULL,
+    quantity INTEGER NOT NULL DEFAULT 1 CHECK (quantity > 0),
+    status VARCHAR(16) NOT NULL DEFAULT 'waiting',
+    hold_id INTEGER REFERENCES holds(id),
+    offered_at TIMESTAMPTZ,
+    offer_expires_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT waitlist_entries_status_check CHECK (status IN ('waiting', 'offered', 'claimed', 'expired', 'left'))
+);
+
+CREATE INDEX waitlist_entries_waiting_idx ON waitlist_entries (event, id) WHERE status = 'waiting';
+CREATE INDEX waitlist_entries_hold_idx ON waitlist_entries (hold_id);
+CREATE UNIQUE INDEX waitlist_entries_user_event_idx ON waitlist_entries (event, user_id)
+    WHERE status IN ('waiting', 'offered');
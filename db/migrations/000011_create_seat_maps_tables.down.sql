@@ -0,0 +1,5 @@
+DROP INDEX bookings_event_seat_idx;
+ALTER TABLE bookings DROP COLUMN seat_id;
+ALTER TABLE events DROP COLUMN seat_map_id;
+DROP TABLE seats;
+DROP TABLE seat_maps;
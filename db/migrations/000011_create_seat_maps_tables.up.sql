@@ -0,0 +1,24 @@
+CREATE TABLE seat_maps (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    owner_id INTEGER REFERENCES users(id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE seats (
+    id SERIAL PRIMARY KEY,
+    seat_map_id INTEGER NOT NULL REFERENCES seat_maps(id) ON DELETE CASCADE,
+    section VARCHAR(64) NOT NULL,
+    row_label VARCHAR(16) NOT NULL,
+    number INTEGER NOT NULL,
+    position INTEGER NOT NULL,
+    accessible BOOLEAN NOT NULL DEFAULT FALSE,
+    obstructed BOOLEAN NOT NULL DEFAULT FALSE,
+    UNIQUE (seat_map_id, section, row_label, number)
+);
+
+ALTER TABLE events ADD COLUMN seat_map_id INTEGER REFERENCES seat_maps(id);
+ALTER TABLE bookings ADD COLUMN seat_id INTEGER REFERENCES seats(id);
+
+-- A seat can be held by at most one active booking per event.
+CREATE UNIQUE INDEX bookings_event_seat_idx ON bookings (event, seat_id) WHERE is_active AND seat_id IS NOT NULL;
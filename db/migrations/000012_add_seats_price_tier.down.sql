@@ -0,0 +1 @@
+ALTER TABLE seats DROP COLUMN price_tier;
@@ -0,0 +1 @@
+ALTER TABLE seats ADD COLUMN price_tier VARCHAR(64) NOT NULL DEFAULT '';
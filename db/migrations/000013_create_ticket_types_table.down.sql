@@ -0,0 +1,7 @@
+DROP INDEX bookings_ticket_type_idx;
+ALTER TABLE waitlist_entries DROP COLUMN ticket_type_id;
+ALTER TABLE holds DROP COLUMN ticket_type_id;
+ALTER TABLE bookings DROP COLUMN currency;
+ALTER TABLE bookings DROP COLUMN price;
+ALTER TABLE bookings DROP COLUMN ticket_type_id;
+DROP TABLE ticket_types;
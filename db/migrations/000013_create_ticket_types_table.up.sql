@@ -0,0 +1,24 @@
+CREATE TABLE ticket_types (
+    id SERIAL PRIMARY KEY,
+    event_id INTEGER NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    name VARCHAR(64) NOT NULL,
+    price BIGINT NOT NULL CHECK (price >= 0),
+    currency CHAR(3) NOT NULL,
+    quantity INTEGER CHECK (quantity >= 0),
+    sales_start TIMESTAMPTZ,
+    sales_end TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (event_id, name),
+    CONSTRAINT ticket_types_sales_window_check CHECK (sales_end IS NULL OR sales_start IS NULL OR sales_end > sales_start)
+);
+
+-- Bookings keep the price they were sold at, so later price changes do not
+-- rewrite what customers paid.
+ALTER TABLE bookings ADD COLUMN ticket_type_id INTEGER REFERENCES ticket_types(id);
+ALTER TABLE bookings ADD COLUMN price BIGINT;
+ALTER TABLE bookings ADD COLUMN currency CHAR(3);
+ALTER TABLE holds ADD COLUMN ticket_type_id INTEGER REFERENCES ticket_types(id);
+ALTER TABLE waitlist_entries ADD COLUMN ticket_type_id INTEGER REFERENCES ticket_types(id);
+
+CREATE INDEX bookings_ticket_type_idx ON bookings (ticket_type_id) WHERE is_active;
@@ -0,0 +1,7 @@
+DROP INDEX bookings_payment_expiry_idx;
+ALTER TABLE bookings DROP COLUMN payment_expires_at;
+DROP INDEX bookings_payment_idx;
+ALTER TABLE bookings DROP COLUMN payment_id;
+ALTER TABLE bookings DROP CONSTRAINT bookings_status_check;
+ALTER TABLE bookings DROP COLUMN status;
+DROP TABLE payments;
@@ -0,0 +1,30 @@
+CREATE TABLE payments (
+    id SERIAL PRIMARY KEY,
+    provider VARCHAR(32) NOT NULL,
+    provider_ref VARCHAR(255),
+    user_id INTEGER REFERENCES users(id),
+    amount BIGINT NOT NULL CHECK (amount > 0),
+    currency CHAR(3) NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    client_secret TEXT,
+    failure_reason TEXT,
+    authorized_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT payments_status_check CHECK (status IN ('pending', 'captured', 'failed'))
+);
+
+CREATE UNIQUE INDEX payments_provider_ref_idx ON payments (provider, provider_ref);
+
+-- Existing bookings were never paid for, so they count as confirmed.
+ALTER TABLE bookings ADD COLUMN status VARCHAR(24) NOT NULL DEFAULT 'confirmed';
+ALTER TABLE bookings ADD CONSTRAINT bookings_status_check
+    CHECK (status IN ('pending_payment', 'confirmed', 'payment_failed'));
+ALTER TABLE bookings ADD COLUMN payment_id INTEGER REFERENCES payments(id);
+
+CREATE INDEX bookings_payment_idx ON bookings (payment_id);
+
+-- Unpaid bookings give their place back once payment_expires_at passes
+ALTER TABLE bookings ADD COLUMN payment_expires_at TIMESTAMPTZ;
+CREATE INDEX bookings_payment_expiry_idx ON bookings (payment_expires_at)
+    WHERE status = 'pending_payment' AND payment_id IS NULL;
@@ -0,0 +1,10 @@
+UPDATE bookings SET status = 'confirmed' WHERE status = 'cancelled';
+ALTER TABLE bookings DROP CONSTRAINT bookings_status_check;
+ALTER TABLE bookings ADD CONSTRAINT bookings_status_check
+    CHECK (status IN ('pending_payment', 'confirmed', 'payment_failed'));
+DROP TABLE refunds;
+ALTER TABLE payments DROP CONSTRAINT payments_refunded_amount_check;
+ALTER TABLE payments DROP COLUMN refunded_amount;
+ALTER TABLE events DROP COLUMN cancellation_fee_percent;
+ALTER TABLE events DROP COLUMN full_refund_hours;
+ALTER TABLE events DROP COLUMN starts_at;
@@ -0,0 +1,30 @@
+ALTER TABLE events ADD COLUMN starts_at TIMESTAMPTZ;
+ALTER TABLE events ADD COLUMN full_refund_hours INTEGER CHECK (full_refund_hours >= 0);
+ALTER TABLE events ADD COLUMN cancellation_fee_percent INTEGER
+    CHECK (cancellation_fee_percent BETWEEN 0 AND 100);
+
+ALTER TABLE payments ADD COLUMN refunded_amount BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE payments ADD CONSTRAINT payments_refunded_amount_check
+    CHECK (refunded_amount >= 0 AND refunded_amount <= amount);
+
+CREATE TABLE refunds (
+    id SERIAL PRIMARY KEY,
+    booking_id INTEGER REFERENCES bookings(id) ON DELETE SET NULL,
+    payment_id INTEGER NOT NULL REFERENCES payments(id),
+    amount BIGINT NOT NULL CHECK (amount > 0),
+    fee BIGINT NOT NULL DEFAULT 0 CHECK (fee >= 0),
+    currency CHAR(3) NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    provider_ref VARCHAR(255),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT refunds_status_check CHECK (status IN ('pending', 'succeeded'))
+);
+
+CREATE INDEX refunds_booking_idx ON refunds (booking_id);
+CREATE INDEX refunds_payment_idx ON refunds (payment_id);
+
+-- Cancelled bookings have given their place back for good
+ALTER TABLE bookings DROP CONSTRAINT bookings_status_check;
+ALTER TABLE bookings ADD CONSTRAINT bookings_status_check
+    CHECK (status IN ('pending_payment', 'confirmed', 'payment_failed', 'cancelled'));
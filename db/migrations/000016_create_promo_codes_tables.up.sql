@@ -0,0 +1,39 @@
+CREATE TABLE promo_codes (
+    id SERIAL PRIMARY KEY,
+    code VARCHAR(64) NOT NULL,
+    kind VARCHAR(16) NOT NULL,
+    percent_off INTEGER CHECK (percent_off BETWEEN 1 AND 100),
+    amount_off BIGINT CHECK (amount_off > 0),
+    currency CHAR(3),
+    max_uses INTEGER CHECK (max_uses > 0),
+    max_uses_per_user INTEGER CHECK (max_uses_per_user > 0),
+    valid_from TIMESTAMPTZ,
+    valid_until TIMESTAMPTZ,
+    event_id INTEGER REFERENCES events(id) ON DELETE CASCADE,
+    ticket_type_id INTEGER REFERENCES ticket_types(id) ON DELETE CASCADE,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_by INTEGER REFERENCES users(id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT promo_codes_kind_check CHECK (
+        (kind = 'percent' AND percent_off IS NOT NULL) OR
+        (kind = 'fixed' AND amount_off IS NOT NULL AND currency IS NOT NULL)),
+    CONSTRAINT promo_codes_validity_check CHECK (valid_until IS NULL OR valid_from IS NULL OR valid_until > valid_from)
+);
+
+-- Codes are matched case-insensitively.
+CREATE UNIQUE INDEX promo_codes_code_idx ON promo_codes (upper(code));
+
+CREATE TABLE promo_redemptions (
+    id SERIAL PRIMARY KEY,
+    promo_code_id INTEGER NOT NULL REFERENCES promo_codes(id) ON DELETE CASCADE,
+    booking_id INTEGER NOT NULL REFERENCES bookings(id) ON DELETE CASCADE,
+    user_id INTEGER REFERENCES users(id),
+    discount BIGINT NOT NULL CHECK (discount >= 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX promo_redemptions_code_idx ON promo_redemptions (promo_code_id, user_id);
+
+ALTER TABLE bookings ADD COLUMN promo_code_id INTEGER REFERENCES promo_codes(id);
+ALTER TABLE bookings ADD COLUMN discount BIGINT;
@@ -0,0 +1,6 @@
+DROP TABLE ledger_entries;
+DROP TABLE ledger_transactions;
+DROP TABLE ledger_accounts;
+DROP FUNCTION ledger_check_balanced();
+DROP FUNCTION ledger_immutable();
+ALTER TABLE bookings DROP COLUMN platform_fee;
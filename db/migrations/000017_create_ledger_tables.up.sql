@@ -0,0 +1,75 @@
+-- The platform fee taken on a booking when its payment was captured, which
+-- a refund reverses in proportion
+ALTER TABLE bookings ADD COLUMN platform_fee BIGINT CHECK (platform_fee >= 0);
+
+CREATE TABLE ledger_accounts (
+    id SERIAL PRIMARY KEY,
+    code VARCHAR(128) NOT NULL UNIQUE,
+    kind VARCHAR(16) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT ledger_accounts_kind_check CHECK (kind IN ('asset', 'liability', 'revenue', 'expense'))
+);
+
+CREATE TABLE ledger_transactions (
+    id BIGSERIAL PRIMARY KEY,
+    kind VARCHAR(64) NOT NULL,
+    reference_type VARCHAR(64) NOT NULL,
+    reference_id BIGINT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX ledger_transactions_reference_idx ON ledger_transactions (reference_type, reference_id);
+
+-- Amounts are signed: debits are positive and credits negative, so the
+-- entries of a balanced transaction sum to zero in each currency.
+CREATE TABLE ledger_entries (
+    id BIGSERIAL PRIMARY KEY,
+    transaction_id BIGINT NOT NULL REFERENCES ledger_transactions(id),
+    account_id INTEGER NOT NULL REFERENCES ledger_accounts(id),
+    amount BIGINT NOT NULL CHECK (amount <> 0),
+    currency CHAR(3) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX ledger_entries_account_idx ON ledger_entries (account_id, currency);
+CREATE INDEX ledger_entries_transaction_idx ON ledger_entries (transaction_id);
+
+CREATE FUNCTION ledger_immutable() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION '% is append-only', TG_TABLE_NAME;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER ledger_transactions_no_modify
+    BEFORE UPDATE OR DELETE ON ledger_transactions
+    FOR EACH ROW EXECUTE FUNCTION ledger_immutable();
+
+CREATE TRIGGER ledger_transactions_no_truncate
+    BEFORE TRUNCATE ON ledger_transactions
+    FOR EACH STATEMENT EXECUTE FUNCTION ledger_immutable();
+
+CREATE TRIGGER ledger_entries_no_modify
+    BEFORE UPDATE OR DELETE ON ledger_entries
+    FOR EACH ROW EXECUTE FUNCTION ledger_immutable();
+
+CREATE TRIGGER ledger_entries_no_truncate
+    BEFORE TRUNCATE ON ledger_entries
+    FOR EACH STATEMENT EXECUTE FUNCTION ledger_immutable();
+
+-- Checked at commit, once all entries of the transaction have been written.
+CREATE FUNCTION ledger_check_balanced() RETURNS trigger AS $$
+BEGIN
+    IF EXISTS (
+        SELECT 1 FROM ledger_entries WHERE transaction_id = NEW.transaction_id
+        GROUP BY currency HAVING sum(amount) <> 0
+    ) THEN
+        RAISE EXCEPTION 'ledger transaction % is not balanced', NEW.transaction_id;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE CONSTRAINT TRIGGER ledger_entries_balanced
+    AFTER INSERT ON ledger_entries
+    DEFERRABLE INITIALLY DEFERRED
+    FOR EACH ROW EXECUTE FUNCTION ledger_check_balanced();
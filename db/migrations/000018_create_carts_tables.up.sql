@@ -0,0 +1,25 @@
+CREATE TABLE carts (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    status VARCHAR(16) NOT NULL DEFAULT 'active',
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT carts_status_check CHECK (status IN ('active', 'checked_out', 'expired'))
+);
+
+-- A user has at most one cart being filled at a time.
+CREATE UNIQUE INDEX carts_active_user_idx ON carts (user_id) WHERE status = 'active';
+
+CREATE TABLE cart_items (
+    id SERIAL PRIMARY KEY,
+    cart_id INTEGER NOT NULL REFERENCES carts(id) ON DELETE CASCADE,
+    event VARCHAR(255) NOT NULL,
+    ticket_type_id INTEGER REFERENCES ticket_types(id) ON DELETE CASCADE,
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Adding the same tickets again increases the quantity of the existing item.
+CREATE UNIQUE INDEX cart_items_ticket_idx ON cart_items (cart_id, event, (coalesce(ticket_type_id, 0)));
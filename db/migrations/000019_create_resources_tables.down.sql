@@ -0,0 +1,8 @@
+ALTER TABLE bookings DROP CONSTRAINT bookings_resource_slot_excl;
+ALTER TABLE bookings DROP CONSTRAINT bookings_slot_check;
+ALTER TABLE bookings DROP COLUMN blocked_until;
+ALTER TABLE bookings DROP COLUMN ends_at;
+ALTER TABLE bookings DROP COLUMN starts_at;
+ALTER TABLE bookings DROP COLUMN resource_id;
+DROP TABLE resource_hours;
+DROP TABLE resources;
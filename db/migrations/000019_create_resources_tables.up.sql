@@ -0,0 +1,40 @@
+CREATE EXTENSION IF NOT EXISTS btree_gist;
+
+CREATE TABLE resources (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL UNIQUE,
+    owner_id INTEGER REFERENCES users(id),
+    time_zone VARCHAR(64) NOT NULL,
+    slot_minutes INTEGER NOT NULL CHECK (slot_minutes > 0),
+    buffer_minutes INTEGER NOT NULL DEFAULT 0 CHECK (buffer_minutes >= 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Weekly opening hours in the resource's time zone. Weekdays count from
+-- Sunday = 0, as in Go's time.Weekday.
+CREATE TABLE resource_hours (
+    id SERIAL PRIMARY KEY,
+    resource_id INTEGER NOT NULL REFERENCES resources(id) ON DELETE CASCADE,
+    weekday SMALLINT NOT NULL CHECK (weekday BETWEEN 0 AND 6),
+    opens TIME NOT NULL,
+    closes TIME NOT NULL,
+    CHECK (closes > opens)
+);
+
+CREATE INDEX resource_hours_resource_idx ON resource_hours (resource_id, weekday);
+
+ALTER TABLE bookings ADD COLUMN resource_id INTEGER REFERENCES resources(id);
+ALTER TABLE bookings ADD COLUMN starts_at TIMESTAMPTZ;
+ALTER TABLE bookings ADD COLUMN ends_at TIMESTAMPTZ;
+ALTER TABLE bookings ADD COLUMN blocked_until TIMESTAMPTZ;
+ALTER TABLE bookings ADD CONSTRAINT bookings_slot_check CHECK (
+    (resource_id IS NULL AND starts_at IS NULL AND ends_at IS NULL AND blocked_until IS NULL) OR
+    (resource_id IS NOT NULL AND ends_at > starts_at AND blocked_until >= ends_at));
+
+-- Active appointments on a resource may not overlap, including the buffer
+-- that follows each one.
+ALTER TABLE bookings ADD CONSTRAINT bookings_resource_slot_excl EXCLUDE USING gist (
+    resource_id WITH =,
+    tstzrange(starts_at, blocked_until) WITH &&
+) WHERE (is_active AND resource_id IS NOT NULL);
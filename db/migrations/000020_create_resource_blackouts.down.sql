@@ -0,0 +1,7 @@
+DROP INDEX holds_resource_slot_idx;
+ALTER TABLE holds DROP CONSTRAINT holds_slot_check;
+ALTER TABLE holds DROP COLUMN blocked_until;
+ALTER TABLE holds DROP COLUMN ends_at;
+ALTER TABLE holds DROP COLUMN starts_at;
+ALTER TABLE holds DROP COLUMN resource_id;
+DROP TABLE resource_blackouts;
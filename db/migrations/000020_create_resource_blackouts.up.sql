@@ -0,0 +1,23 @@
+CREATE TABLE resource_blackouts (
+    id SERIAL PRIMARY KEY,
+    resource_id INTEGER NOT NULL REFERENCES resources(id) ON DELETE CASCADE,
+    starts_at TIMESTAMPTZ NOT NULL,
+    ends_at TIMESTAMPTZ NOT NULL,
+    reason VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CHECK (ends_at > starts_at)
+);
+
+CREATE INDEX resource_blackouts_range_idx ON resource_blackouts
+    USING gist (resource_id, tstzrange(starts_at, ends_at));
+
+ALTER TABLE holds ADD COLUMN resource_id INTEGER REFERENCES resources(id);
+ALTER TABLE holds ADD COLUMN starts_at TIMESTAMPTZ;
+ALTER TABLE holds ADD COLUMN ends_at TIMESTAMPTZ;
+ALTER TABLE holds ADD COLUMN blocked_until TIMESTAMPTZ;
+ALTER TABLE holds ADD CONSTRAINT holds_slot_check CHECK (
+    (resource_id IS NULL AND starts_at IS NULL AND ends_at IS NULL AND blocked_until IS NULL) OR
+    (resource_id IS NOT NULL AND quantity = 1 AND ends_at > starts_at AND blocked_until >= ends_at));
+
+CREATE INDEX holds_resource_slot_idx ON holds
+    USING gist (resource_id, tstzrange(starts_at, blocked_until)) WHERE status = 'active' AND resource_id IS NOT NULL;
@@ -0,0 +1,8 @@
+DROP INDEX bookings_series_idx;
+ALTER TABLE bookings DROP COLUMN series_id;
+DROP TABLE booking_series;
+DROP INDEX events_series_occurrence_idx;
+ALTER TABLE events DROP COLUMN occurrence_at;
+ALTER TABLE events DROP COLUMN series_id;
+DROP TABLE event_series_exceptions;
+DROP TABLE event_series;
@@ -0,0 +1,47 @@
+CREATE TABLE event_series (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(200) NOT NULL UNIQUE,
+    organizer_id INTEGER REFERENCES users(id),
+    rrule TEXT NOT NULL,
+    starts_at TIMESTAMPTZ NOT NULL,
+    time_zone VARCHAR(64) NOT NULL,
+    capacity INTEGER CHECK (capacity >= 0),
+    materialized_until TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Occurrences removed from a series, by the time the rule gives them
+CREATE TABLE event_series_exceptions (
+    series_id INTEGER NOT NULL REFERENCES event_series(id) ON DELETE CASCADE,
+    occurrence_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (series_id, occurrence_at)
+);
+
+-- Each occurrence is an ordinary event. occurrence_at is the time the rule
+-- gives it, which stays the key of the occurrence even if its start is
+-- overridden.
+ALTER TABLE events ADD COLUMN series_id INTEGER REFERENCES event_series(id);
+ALTER TABLE events ADD COLUMN occurrence_at TIMESTAMPTZ;
+CREATE UNIQUE INDEX events_series_occurrence_idx ON events (series_id, occurrence_at) WHERE series_id IS NOT NULL;
+
+CREATE TABLE booking_series (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER REFERENCES users(id),
+    user_name VARCHAR(255) NOT NULL,
+    rrule TEXT NOT NULL,
+    starts_at TIMESTAMPTZ NOT NULL,
+    time_zone VARCHAR(64) NOT NULL,
+    event_series_id INTEGER REFERENCES event_series(id),
+    resource_id INTEGER REFERENCES resources(id),
+    slots INTEGER NOT NULL DEFAULT 1 CHECK (slots > 0),
+    status VARCHAR(16) NOT NULL DEFAULT 'active',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT booking_series_target_check CHECK ((event_series_id IS NULL) <> (resource_id IS NULL)),
+    CONSTRAINT booking_series_status_check CHECK (status IN ('active', 'cancelled'))
+);
+
+ALTER TABLE bookings ADD COLUMN series_id INTEGER REFERENCES booking_series(id);
+CREATE INDEX bookings_series_idx ON bookings (series_id) WHERE series_id IS NOT NULL;
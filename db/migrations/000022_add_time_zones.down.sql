@@ -0,0 +1,13 @@
+ALTER TABLE holds DROP COLUMN time_zone;
+ALTER TABLE bookings DROP COLUMN time_zone;
+ALTER TABLE events DROP COLUMN time_zone;
+-- Back to wall clock times in the zone the up migration read them in
+DO $$
+DECLARE
+    zone TEXT := coalesce(nullif(current_setting('booking.legacy_time_zone', true), ''), 'UTC');
+BEGIN
+    EXECUTE format('ALTER TABLE users ALTER COLUMN updated_at TYPE TIMESTAMP USING updated_at AT TIME ZONE %L', zone);
+    EXECUTE format('ALTER TABLE users ALTER COLUMN created_at TYPE TIMESTAMP USING created_at AT TIME ZONE %L', zone);
+    EXECUTE format('ALTER TABLE bookings ALTER COLUMN updated_at TYPE TIMESTAMP USING updated_at AT TIME ZONE %L', zone);
+    EXECUTE format('ALTER TABLE bookings ALTER COLUMN created_at TYPE TIMESTAMP USING created_at AT TIME ZONE %L', zone);
+END $$;
@@ -0,0 +1,34 @@
+-- The first tables stored the application server's wall clock without a
+-- time zone: the values came from time.Now() and Postgres dropped their
+-- offset. They are read in the zone the server ran in, UTC unless the
+-- deployment says otherwise before migrating, e.g.
+--   ALTER DATABASE booking SET booking.legacy_time_zone = 'Europe/Berlin';
+-- or PGOPTIONS='-c booking.legacy_time_zone=Europe/Berlin' for the migration
+-- run. The zone must be known to Postgres, and no converted creation time
+-- may lie in the future, which catches a server ahead of the zone given.
+DO $$
+DECLARE
+    zone TEXT := coalesce(nullif(current_setting('booking.legacy_time_zone', true), ''), 'UTC');
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_timezone_names WHERE name = zone) THEN
+        RAISE EXCEPTION 'booking.legacy_time_zone: unknown time zone %', zone;
+    END IF;
+    IF EXISTS (SELECT 1 FROM bookings WHERE created_at AT TIME ZONE zone > now())
+        OR EXISTS (SELECT 1 FROM users WHERE created_at AT TIME ZONE zone > now()) THEN
+        RAISE EXCEPTION 'booking.legacy_time_zone: % puts creation times in the future', zone;
+    END IF;
+    EXECUTE format('ALTER TABLE bookings ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at AT TIME ZONE %L', zone);
+    EXECUTE format('ALTER TABLE bookings ALTER COLUMN updated_at TYPE TIMESTAMPTZ USING updated_at AT TIME ZONE %L', zone);
+    EXECUTE format('ALTER TABLE users ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at AT TIME ZONE %L', zone);
+    EXECUTE format('ALTER TABLE users ALTER COLUMN updated_at TYPE TIMESTAMPTZ USING updated_at AT TIME ZONE %L', zone);
+END $$;
+
+-- The IANA time zone of an event's venue, used to show its times locally
+ALTER TABLE events ADD COLUMN time_zone VARCHAR(64) NOT NULL DEFAULT 'UTC';
+UPDATE events e SET time_zone = s.time_zone FROM event_series s WHERE e.series_id = s.id;
+
+-- Appointments and holds on them keep the time zone of their resource
+ALTER TABLE bookings ADD COLUMN time_zone VARCHAR(64);
+UPDATE bookings b SET time_zone = r.time_zone FROM resources r WHERE b.resource_id = r.id;
+ALTER TABLE holds ADD COLUMN time_zone VARCHAR(64);
+UPDATE holds h SET time_zone = r.time_zone FROM resources r WHERE h.resource_id = r.id;
@@ -0,0 +1,9 @@
+DROP TABLE event_blackouts;
+ALTER TABLE resources DROP COLUMN cutoff_days_before;
+ALTER TABLE resources DROP COLUMN cutoff_time;
+ALTER TABLE resources DROP COLUMN max_advance_days;
+ALTER TABLE resources DROP COLUMN min_notice_minutes;
+ALTER TABLE events DROP COLUMN cutoff_days_before;
+ALTER TABLE events DROP COLUMN cutoff_time;
+ALTER TABLE events DROP COLUMN max_advance_days;
+ALTER TABLE events DROP COLUMN min_notice_minutes;
@@ -0,0 +1,24 @@
+-- Booking windows of events and resources, counted from what is booked
+-- starts. Cut-off times are local "HH:MM" times in the venue's time zone.
+ALTER TABLE events ADD COLUMN min_notice_minutes INTEGER CHECK (min_notice_minutes >= 0);
+ALTER TABLE events ADD COLUMN max_advance_days INTEGER CHECK (max_advance_days > 0);
+ALTER TABLE events ADD COLUMN cutoff_time VARCHAR(5) CHECK (cutoff_time ~ '^([01][0-9]|2[0-3]):[0-5][0-9]$');
+ALTER TABLE events ADD COLUMN cutoff_days_before INTEGER CHECK (cutoff_days_before >= 0);
+
+ALTER TABLE resources ADD COLUMN min_notice_minutes INTEGER CHECK (min_notice_minutes >= 0);
+ALTER TABLE resources ADD COLUMN max_advance_days INTEGER CHECK (max_advance_days > 0);
+ALTER TABLE resources ADD COLUMN cutoff_time VARCHAR(5) CHECK (cutoff_time ~ '^([01][0-9]|2[0-3]):[0-5][0-9]$');
+ALTER TABLE resources ADD COLUMN cutoff_days_before INTEGER CHECK (cutoff_days_before >= 0);
+
+CREATE TABLE event_blackouts (
+    id SERIAL PRIMARY KEY,
+    event_id INTEGER NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    starts_at TIMESTAMPTZ NOT NULL,
+    ends_at TIMESTAMPTZ NOT NULL,
+    reason VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CHECK (ends_at > starts_at)
+);
+
+CREATE INDEX event_blackouts_range_idx ON event_blackouts
+    USING gist (event_id, tstzrange(starts_at, ends_at));
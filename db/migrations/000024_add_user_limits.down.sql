@@ -0,0 +1,5 @@
+DROP INDEX bookings_user_created_idx;
+DROP INDEX bookings_user_event_idx;
+ALTER TABLE events DROP COLUMN max_per_user;
+ALTER TABLE events DROP CONSTRAINT events_ends_after_start;
+ALTER TABLE events DROP COLUMN ends_at;
@@ -0,0 +1,7 @@
+ALTER TABLE events ADD COLUMN ends_at TIMESTAMPTZ;
+ALTER TABLE events ADD CONSTRAINT events_ends_after_start CHECK (ends_at > starts_at);
+ALTER TABLE events ADD COLUMN max_per_user INTEGER CHECK (max_per_user > 0);
+
+-- Limit checks count a user's bookings per event and over recent periods
+CREATE INDEX bookings_user_event_idx ON bookings (user_id, event) WHERE is_active;
+CREATE INDEX bookings_user_created_idx ON bookings (user_id, created_at) WHERE is_active;
@@ -0,0 +1,2 @@
+DROP TABLE waiting_room_entries;
+DROP TABLE waiting_rooms;
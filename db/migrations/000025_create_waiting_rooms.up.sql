@@ -0,0 +1,25 @@
+CREATE TABLE waiting_rooms (
+    event_id INTEGER PRIMARY KEY REFERENCES events(id) ON DELETE CASCADE,
+    admit_per_minute INTEGER NOT NULL CHECK (admit_per_minute > 0),
+    admission_minutes INTEGER NOT NULL CHECK (admission_minutes > 0),
+    active BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- One place in the queue per user and event. Admitted entries may book
+-- until expires_at, in one transaction; used_at records when they did.
+CREATE TABLE waiting_room_entries (
+    id BIGSERIAL PRIMARY KEY,
+    event_id INTEGER NOT NULL REFERENCES waiting_rooms(event_id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    joined_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    admitted_at TIMESTAMPTZ,
+    expires_at TIMESTAMPTZ,
+    used_at TIMESTAMPTZ,
+    CHECK ((admitted_at IS NULL) = (expires_at IS NULL))
+);
+
+CREATE UNIQUE INDEX waiting_room_entries_user_idx ON waiting_room_entries (event_id, user_id);
+CREATE INDEX waiting_room_entries_queue_idx ON waiting_room_entries (event_id, id) WHERE admitted_at IS NULL;
+CREATE INDEX waiting_room_entries_admitted_idx ON waiting_room_entries (event_id, admitted_at) WHERE admitted_at IS NOT NULL;
@@ -0,0 +1,12 @@
+ALTER TABLE bookings DROP CONSTRAINT bookings_resource_slot_excl;
+ALTER TABLE bookings ADD CONSTRAINT bookings_resource_slot_excl EXCLUDE USING gist (
+    resource_id WITH =,
+    tstzrange(starts_at, blocked_until) WITH &&
+) WHERE (is_active AND resource_id IS NOT NULL);
+
+ALTER TABLE bookings DROP COLUMN checked_in_at;
+ALTER TABLE bookings DROP COLUMN oversold;
+ALTER TABLE resources DROP COLUMN overbook_limit;
+ALTER TABLE resources DROP COLUMN overbook_percent;
+ALTER TABLE events DROP COLUMN overbook_limit;
+ALTER TABLE events DROP COLUMN overbook_percent;
@@ -0,0 +1,17 @@
+ALTER TABLE events ADD COLUMN overbook_percent INTEGER CHECK (overbook_percent BETWEEN 1 AND 100);
+ALTER TABLE events ADD COLUMN overbook_limit INTEGER CHECK (overbook_limit > 0);
+ALTER TABLE resources ADD COLUMN overbook_percent INTEGER CHECK (overbook_percent BETWEEN 1 AND 100);
+ALTER TABLE resources ADD COLUMN overbook_limit INTEGER CHECK (overbook_limit > 0);
+
+-- Bookings sold beyond capacity are flagged, and check-ins recorded, so the
+-- two can be compared after the event
+ALTER TABLE bookings ADD COLUMN oversold BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE bookings ADD COLUMN checked_in_at TIMESTAMPTZ;
+
+-- Oversold appointments share their slot with another one; the rest still
+-- may not overlap
+ALTER TABLE bookings DROP CONSTRAINT bookings_resource_slot_excl;
+ALTER TABLE bookings ADD CONSTRAINT bookings_resource_slot_excl EXCLUDE USING gist (
+    resource_id WITH =,
+    tstzrange(starts_at, blocked_until) WITH &&
+) WHERE (is_active AND resource_id IS NOT NULL AND NOT oversold);
@@ -0,0 +1,10 @@
+DROP INDEX bookings_approval_expiry_idx;
+DROP INDEX bookings_pending_approval_idx;
+ALTER TABLE bookings DROP COLUMN decision_reason;
+ALTER TABLE bookings DROP COLUMN decided_at;
+ALTER TABLE bookings DROP COLUMN approval_expires_at;
+ALTER TABLE bookings DROP CONSTRAINT bookings_status_check;
+ALTER TABLE bookings ADD CONSTRAINT bookings_status_check
+    CHECK (status IN ('pending_payment', 'confirmed', 'payment_failed', 'cancelled'));
+ALTER TABLE events DROP COLUMN approval_hours;
+ALTER TABLE events DROP COLUMN requires_approval;
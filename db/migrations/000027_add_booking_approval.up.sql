@@ -0,0 +1,13 @@
+ALTER TABLE events ADD COLUMN requires_approval BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE events ADD COLUMN approval_hours INTEGER CHECK (approval_hours > 0);
+
+ALTER TABLE bookings DROP CONSTRAINT bookings_status_check;
+ALTER TABLE bookings ADD CONSTRAINT bookings_status_check
+    CHECK (status IN ('pending_payment', 'confirmed', 'payment_failed', 'cancelled', 'pending_approval', 'rejected', 'expired'));
+ALTER TABLE bookings ADD COLUMN approval_expires_at TIMESTAMPTZ;
+ALTER TABLE bookings ADD COLUMN decided_at TIMESTAMPTZ;
+ALTER TABLE bookings ADD COLUMN decision_reason VARCHAR(500);
+
+-- The organizer queue and the expiry sweeper look for pending requests
+CREATE INDEX bookings_pending_approval_idx ON bookings (event, created_at) WHERE status = 'pending_approval';
+CREATE INDEX bookings_approval_expiry_idx ON bookings (approval_expires_at) WHERE status = 'pending_approval';
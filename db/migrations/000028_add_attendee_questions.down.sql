@@ -0,0 +1,3 @@
+ALTER TABLE cart_items DROP COLUMN answers;
+ALTER TABLE bookings DROP COLUMN answers;
+ALTER TABLE events DROP COLUMN attendee_schema;
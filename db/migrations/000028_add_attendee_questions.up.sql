@@ -0,0 +1,5 @@
+ALTER TABLE events ADD COLUMN attendee_schema JSONB;
+ALTER TABLE bookings ADD COLUMN answers JSONB;
+
+-- Answers given with a cart item are used for each of its bookings
+ALTER TABLE cart_items ADD COLUMN answers JSONB;
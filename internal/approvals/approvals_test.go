@@ -0,0 +1,32 @@
+package approvals
+
+import (
+	"strings"
+	"testing"
+
+	"booking-app/internal/bookings"
+)
+
+func TestNotification(t *testing.T) {
+	reason := "Workshop is for members only"
+	tests := []struct {
+		name    string
+		booking bookings.Booking
+		subject string
+		body    string
+	}{
+		{"approved", bookings.Booking{ID: 1, Event: "Pottery", Status: bookings.StatusConfirmed}, "was approved", "is confirmed"},
+		{"approved with a price", bookings.Booking{ID: 2, Event: "Pottery", Status: bookings.StatusPendingPayment}, "was approved", "Pay for it"},
+		{"rejected", bookings.Booking{ID: 3, Event: "Pottery", Status: bookings.StatusRejected, DecisionReason: &reason}, "was declined", reason},
+		{"expired", bookings.Booking{ID: 4, Event: "Pottery", Status: bookings.StatusExpired}, "expired", "did not respond"},
+	}
+	for _, tt := range tests {
+		n := notification(tt.booking)
+		if !strings.Contains(n.Subject, tt.subject) || !strings.Contains(n.Subject, "Pottery") {
+			t.Errorf("%s: subject = %q, want it to mention %q", tt.name, n.Subject, tt.subject)
+		}
+		if !strings.Contains(n.Body, tt.body) {
+			t.Errorf("%s: body = %q, want it to mention %q", tt.name, n.Body, tt.body)
+		}
+	}
+}
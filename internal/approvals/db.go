@@ -0,0 +1,148 @@
+// Package approvals lets organizers sign off bookings for events that
+// require it. Such bookings hold their place while pending approval; the
+// organizer approves or rejects them from a queue, and requests nobody
+// answers expire. The requester is notified of every decision.
+package approvals
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"booking-app/internal/bookings"
+	"booking-app/internal/database"
+	"booking-app/internal/events"
+	"booking-app/internal/middleware"
+	"booking-app/internal/notify"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// DBStore decides bookings awaiting approval
+type DBStore struct {
+	db       *sqlx.DB
+	events   *events.DBStore
+	notifier notify.Notifier
+}
+
+func NewDBStore(db *sqlx.DB, eventStore *events.DBStore, notifier notify.Notifier) *DBStore {
+	return &DBStore{db: db, events: eventStore, notifier: notifier}
+}
+
+// Queue lists an event's bookings awaiting approval, oldest first
+func (s *DBStore) Queue(ctx context.Context, e events.Event) ([]bookings.Booking, error) {
+	list := []bookings.Booking{}
+	err := s.db.SelectContext(ctx, &list, `SELECT * FROM bookings
+              WHERE event = $1 AND status = 'pending_approval' ORDER BY created_at, id`, e.Name)
+	return list, err
+}
+
+// Decide approves or rejects a booking awaiting approval. Only admins and
+// the organizer of the booking's event may decide.
+func (s *DBStore) Decide(ctx context.Context, id int, approve bool, reason string) (bookings.Booking, error) {
+	var b bookings.Booking
+	err := database.WithTx(ctx, s.db, func(tx *sqlx.Tx) error {
+		before, err := bookings.LockBookingTx(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if err := s.checkOrganizer(ctx, before.Event); err != nil {
+			return err
+		}
+		b, err = bookings.DecideTx(ctx, tx, before, approve, reason)
+		return err
+	})
+	if err != nil {
+		return bookings.Booking{}, err
+	}
+	s.notify(ctx, b)
+	return b, nil
+}
+
+// Expire expires the requests whose approval window has passed and tells
+// their requesters
+func (s *DBStore) Expire(ctx context.Context) ([]bookings.Booking, error) {
+	var expired []bookings.Booking
+	err := database.WithTx(ctx, s.db, func(tx *sqlx.Tx) error {
+		var err error
+		expired, err = bookings.ExpireApprovalsTx(ctx, tx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range expired {
+		s.notify(ctx, b)
+	}
+	return expired, nil
+}
+
+// RunSweeper expires unanswered requests every interval until ctx is
+// cancelled
+func (s *DBStore) RunSweeper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			expired, err := s.Expire(ctx)
+			if err != nil {
+				log.Printf("Failed to expire approval requests: %v", err)
+				continue
+			}
+			if len(expired) > 0 {
+				log.Printf("Expired %d approval requests", len(expired))
+			}
+		}
+	}
+}
+
+func (s *DBStore) checkOrganizer(ctx context.Context, event string) error {
+	if middleware.IsAdmin(ctx) {
+		return nil
+	}
+	e, err := s.events.GetEventByName(ctx, event)
+	if errors.Is(err, events.ErrEventNotFound) {
+		return bookings.ErrForbidden
+	}
+	if err != nil {
+		return err
+	}
+	if !events.CanManage(ctx, e) {
+		return bookings.ErrForbidden
+	}
+	return nil
+}
+
+func (s *DBStore) notify(ctx context.Context, b bookings.Booking) {
+	if err := s.notifier.Notify(ctx, notification(b)); err != nil {
+		log.Printf("Failed to notify the requester of booking %d: %v", b.ID, err)
+	}
+}
+
+// notification tells the requester of a booking what became of it
+func notification(b bookings.Booking) notify.Notification {
+	n := notify.Notification{UserID: b.UserID, UserName: b.UserName}
+	switch b.Status {
+	case bookings.StatusRejected:
+		n.Subject = fmt.Sprintf("Your booking for %s was declined", b.Event)
+		n.Body = fmt.Sprintf("The organizer declined booking %d.", b.ID)
+	case bookings.StatusExpired:
+		n.Subject = fmt.Sprintf("Your booking request for %s expired", b.Event)
+		n.Body = fmt.Sprintf("The organizer did not respond to booking %d in time, so it was cancelled.", b.ID)
+	case bookings.StatusPendingPayment:
+		n.Subject = fmt.Sprintf("Your booking for %s was approved", b.Event)
+		n.Body = fmt.Sprintf("Booking %d was approved. Pay for it to confirm your place.", b.ID)
+	default:
+		n.Subject = fmt.Sprintf("Your booking for %s was approved", b.Event)
+		n.Body = fmt.Sprintf("Booking %d is confirmed.", b.ID)
+	}
+	if b.DecisionReason != nil {
+		n.Body += " Reason: " + *b.DecisionReason
+	}
+	return n
+}
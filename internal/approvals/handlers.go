@@ -0,0 +1,113 @@
+package approvals
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"booking-app/internal/bookings"
+	"booking-app/internal/events"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+)
+
+var validate = validator.New()
+
+type Handler struct {
+	store  *DBStore
+	events *events.DBStore
+}
+
+func NewHandler(store *DBStore, eventStore *events.DBStore) *Handler {
+	return &Handler{store: store, events: eventStore}
+}
+
+// QueueHandler lists the bookings of an event awaiting its organizer's approval
+func (h *Handler) QueueHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	e, err := h.events.GetEvent(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if !events.CanManage(r.Context(), e) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	list, err := h.store.Queue(r.Context(), e)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// ApproveHandler approves a booking; the body may give a reason
+func (h *Handler) ApproveHandler(w http.ResponseWriter, r *http.Request) {
+	h.decide(w, r, true)
+}
+
+// RejectHandler rejects a booking; the body must give a reason
+func (h *Handler) RejectHandler(w http.ResponseWriter, r *http.Request) {
+	h.decide(w, r, false)
+}
+
+func (h *Handler) decide(w http.ResponseWriter, r *http.Request, approve bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	var input struct {
+		Reason string `json:"reason" validate:"max=500"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+	if err := validate.Struct(&input); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !approve && input.Reason == "" {
+		http.Error(w, "A reason is required to reject a booking", http.StatusBadRequest)
+		return
+	}
+	b, err := h.store.Decide(r.Context(), id, approve, input.Reason)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, b)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, bookings.ErrBookingNotFound), errors.Is(err, events.ErrEventNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, bookings.ErrForbidden):
+		http.Error(w, "Forbidden", http.StatusForbidden)
+	case errors.Is(err, bookings.ErrNotPending):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		log.Printf("Approval request failed: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,124 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"time"
+
+	"booking-app/internal/middleware"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/jmoiron/sqlx/types"
+)
+
+// Entry is one immutable row of the audit trail
+type Entry struct {
+	ID          int64          `json:"id" db:"id"`
+	EntityType  string         `json:"entity_type" db:"entity_type"`
+	EntityID    int64          `json:"entity_id" db:"entity_id"`
+	Action      string         `json:"action" db:"action"`
+	ActorUserID *int           `json:"actor_user_id,omitempty" db:"actor_user_id"`
+	RequestID   *string        `json:"request_id,omitempty" db:"request_id"`
+	Before      types.JSONText `json:"before" db:"before"`
+	After       types.JSONText `json:"after" db:"after"`
+	Diff        types.JSONText `json:"diff" db:"diff"`
+	CreatedAt   time.Time      `json:"created_at" db:"created_at"`
+}
+
+// Record describes a change to be written to the audit trail. Before is nil
+// for creations and After is nil for deletions.
+type Record struct {
+	EntityType string
+	EntityID   int64
+	Action     string
+	Before     interface{}
+	After      interface{}
+}
+
+// Change is the before and after value of a single field
+type Change struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// Write appends a record to the audit trail using the caller's transaction,
+// so the audit row commits or rolls back together with the change itself.
+// The actor and request ID are taken from ctx.
+func Write(ctx context.Context, tx sqlx.ExecerContext, rec Record) error {
+	before, err := toFields(rec.Before)
+	if err != nil {
+		return err
+	}
+	after, err := toFields(rec.After)
+	if err != nil {
+		return err
+	}
+	diff, err := json.Marshal(Diff(before, after))
+	if err != nil {
+		return err
+	}
+	var actor *int
+	if userID, ok := middleware.UserIDFromContext(ctx); ok {
+		actor = &userID
+	}
+	var requestID *string
+	if id := middleware.RequestIDFromContext(ctx); id != "" {
+		requestID = &id
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO audit_log (entity_type, entity_id, action, actor_user_id, request_id, before, after, diff)
+              VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		rec.EntityType, rec.EntityID, rec.Action, actor, requestID, jsonOrNull(before), jsonOrNull(after), string(diff))
+	return err
+}
+
+// Diff returns the top-level fields whose values differ between before and after
+func Diff(before, after map[string]interface{}) map[string]Change {
+	diff := make(map[string]Change)
+	for field, from := range before {
+		to, ok := after[field]
+		if !ok || !reflect.DeepEqual(from, to) {
+			diff[field] = Change{From: from, To: to}
+		}
+	}
+	for field, to := range after {
+		if _, ok := before[field]; !ok {
+			diff[field] = Change{To: to}
+		}
+	}
+	return diff
+}
+
+// ForEntity returns the audit trail of one entity, oldest first
+func ForEntity(ctx context.Context, q sqlx.QueryerContext, entityType string, entityID int64) ([]Entry, error) {
+	var entries []Entry
+	err := sqlx.SelectContext(ctx, q, &entries,
+		"SELECT * FROM audit_log WHERE entity_type = $1 AND entity_id = $2 ORDER BY id", entityType, entityID)
+	return entries, err
+}
+
+func toFields(v interface{}) (map[string]interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+func jsonOrNull(fields map[string]interface{}) interface{} {
+	if fields == nil {
+		return nil
+	}
+	raw, err := json.Marshal(fields)
+	if err != nil {
+		return nil
+	}
+	return string(raw)
+}
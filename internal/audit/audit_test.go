@@ -0,0 +1,26 @@
+package audit
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	before := map[string]interface{}{"event": "Concert", "is_active": true, "user_name": "alice"}
+	after := map[string]interface{}{"event": "Opera", "is_active": true, "seat": "A1"}
+	want := map[string]Change{
+		"event":     {From: "Concert", To: "Opera"},
+		"user_name": {From: "alice"},
+		"seat":      {To: "A1"},
+	}
+	if got := Diff(before, after); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected diff %+v, got %+v", want, got)
+	}
+}
+
+func TestDiffCreate(t *testing.T) {
+	got := Diff(nil, map[string]interface{}{"id": 1.0})
+	if len(got) != 1 || got["id"].To != 1.0 || got["id"].From != nil {
+		t.Errorf("Expected creation diff with only id, got %+v", got)
+	}
+}
@@ -0,0 +1,67 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Store queries the audit trail in PostgreSQL
+type Store struct {
+	db *sqlx.DB
+}
+
+func NewStore(db *sqlx.DB) *Store {
+	return &Store{db: db}
+}
+
+// Filter narrows an audit search; zero values are ignored
+type Filter struct {
+	ActorUserID *int
+	EntityType  string
+	EntityID    *int64
+	From        *time.Time
+	To          *time.Time
+	Limit       int
+}
+
+func (s *Store) Search(ctx context.Context, f Filter) ([]Entry, error) {
+	var conds []string
+	var args []interface{}
+	add := func(cond string, arg interface{}) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+	if f.ActorUserID != nil {
+		add("actor_user_id = $%d", *f.ActorUserID)
+	}
+	if f.EntityType != "" {
+		add("entity_type = $%d", f.EntityType)
+	}
+	if f.EntityID != nil {
+		add("entity_id = $%d", *f.EntityID)
+	}
+	if f.From != nil {
+		add("created_at >= $%d", *f.From)
+	}
+	if f.To != nil {
+		add("created_at < $%d", *f.To)
+	}
+	query := "SELECT * FROM audit_log"
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+	limit := f.Limit
+	if limit <= 0 || limit > 1000 {
+		limit = 100
+	}
+	args = append(args, limit)
+	query += fmt.Sprintf(" ORDER BY created_at DESC, id DESC LIMIT $%d", len(args))
+
+	var entries []Entry
+	err := s.db.SelectContext(ctx, &entries, query, args...)
+	return entries, err
+}
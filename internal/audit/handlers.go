@@ -0,0 +1,70 @@
+package audit
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type Handler struct {
+	store *Store
+}
+
+func NewHandler(store *Store) *Handler {
+	return &Handler{store: store}
+}
+
+// Search lists audit entries filtered by actor_user_id, entity_type,
+// entity_id and an RFC 3339 from/to time range.
+func (h *Handler) Search(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	var f Filter
+	if v := q.Get("actor_user_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			http.Error(w, "Invalid actor_user_id", http.StatusBadRequest)
+			return
+		}
+		f.ActorUserID = &id
+	}
+	f.EntityType = q.Get("entity_type")
+	if v := q.Get("entity_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid entity_id", http.StatusBadRequest)
+			return
+		}
+		f.EntityID = &id
+	}
+	for _, p := range []struct {
+		name string
+		dst  **time.Time
+	}{{"from", &f.From}, {"to", &f.To}} {
+		if v := q.Get(p.name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				http.Error(w, "Invalid "+p.name+" time, expected RFC 3339", http.StatusBadRequest)
+				return
+			}
+			*p.dst = &t
+		}
+	}
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		f.Limit = limit
+	}
+	entries, err := h.store.Search(r.Context(), f)
+	if err != nil {
+		http.Error(w, "Failed to search audit log", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(entries); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
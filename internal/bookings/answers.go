@@ -0,0 +1,91 @@
+package bookings
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"booking-app/internal/events"
+	"booking-app/internal/jsonschema"
+	"booking-app/internal/middleware"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/jmoiron/sqlx/types"
+	"github.com/lib/pq"
+)
+
+var ErrInvalidAnswers = errors.New("invalid attendee answers")
+
+// checkAnswers validates the answers to an event's attendee questions and
+// returns what to store. Missing answers count as an empty form, so events
+// with required questions cannot be booked without them; events that ask
+// nothing take no answers.
+func checkAnswers(ctx context.Context, q sqlx.QueryerContext, event string, answers json.RawMessage) (*types.JSONText, error) {
+	if string(answers) == "null" {
+		answers = nil
+	}
+	var schema *types.JSONText
+	err := sqlx.GetContext(ctx, q, &schema, "SELECT attendee_schema FROM events WHERE name = $1", event)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if schema == nil {
+		if answers != nil && string(answers) != "{}" {
+			return nil, fmt.Errorf("%w: %s asks no questions", ErrInvalidAnswers, event)
+		}
+		return nil, nil
+	}
+	questions, err := jsonschema.Compile(*schema)
+	if err != nil {
+		return nil, fmt.Errorf("attendee schema of %s: %w", event, err)
+	}
+	if answers == nil {
+		answers = json.RawMessage("{}")
+	}
+	if err := questions.Validate(answers); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAnswers, err)
+	}
+	stored := types.JSONText(answers)
+	return &stored, nil
+}
+
+// redactAnswers drops the attendee answers of the listed bookings that the
+// caller may not read
+func (s *DBStore) redactAnswers(ctx context.Context, list []Booking) error {
+	var names []string
+	for _, b := range list {
+		if b.Answers != nil {
+			names = append(names, b.Event)
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	var rows []events.Event
+	err := s.db.SelectContext(ctx, &rows, "SELECT name, organizer_id FROM events WHERE name = ANY($1)", pq.Array(names))
+	if err != nil {
+		return err
+	}
+	byName := make(map[string]events.Event, len(rows))
+	for _, e := range rows {
+		byName[e.Name] = e
+	}
+	for i := range list {
+		if list[i].Answers != nil && !canReadAnswers(ctx, list[i], byName[list[i].Event]) {
+			list[i].Answers = nil
+		}
+	}
+	return nil
+}
+
+// canReadAnswers allows the booking's owner, the organizer of its event and
+// admins to read its attendee answers
+func canReadAnswers(ctx context.Context, b Booking, e events.Event) bool {
+	if events.CanManage(ctx, e) {
+		return true
+	}
+	userID, ok := middleware.UserIDFromContext(ctx)
+	return ok && b.UserID != nil && *b.UserID == userID
+}
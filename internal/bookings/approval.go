@@ -0,0 +1,112 @@
+package bookings
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var ErrNotPending = errors.New("booking is not awaiting approval")
+
+// DefaultApprovalWindow is how long a booking waits for approval when its
+// event does not say
+const DefaultApprovalWindow = 72 * time.Hour
+
+// approvalWindow returns how long bookings of an event wait for approval,
+// or zero if the event does not require approval
+func approvalWindow(ctx context.Context, q sqlx.QueryerContext, event string) (time.Duration, error) {
+	var e struct {
+		RequiresApproval bool `db:"requires_approval"`
+		ApprovalHours    *int `db:"approval_hours"`
+	}
+	err := sqlx.GetContext(ctx, q, &e, "SELECT requires_approval, approval_hours FROM events WHERE name = $1", event)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && !e.RequiresApproval) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if e.ApprovalHours == nil {
+		return DefaultApprovalWindow, nil
+	}
+	return time.Duration(*e.ApprovalHours) * time.Hour, nil
+}
+
+// resetApproval puts a booking that was moved to an event requiring
+// approval back in line for a decision, with the new event's window
+func resetApproval(ctx context.Context, tx *sqlx.Tx, before Booking, b *Booking) error {
+	if before.Event == b.Event || !b.IsActive {
+		return nil
+	}
+	window, err := approvalWindow(ctx, tx, b.Event)
+	if err != nil || window == 0 {
+		return err
+	}
+	return tx.GetContext(ctx, b, `UPDATE bookings SET status = $1, approval_expires_at = $2, decided_at = NULL,
+              decision_reason = NULL, payment_expires_at = NULL, updated_at = now()
+              WHERE id = $3 RETURNING *`, StatusPendingApproval, time.Now().Add(window), b.ID)
+}
+
+// DecideTx approves or rejects a locked booking that awaits approval. An
+// approved booking goes on to await payment if it has a price and is
+// confirmed otherwise; a rejected one gives its place back. Bookings whose
+// approval window has passed can no longer be decided.
+func DecideTx(ctx context.Context, tx *sqlx.Tx, before Booking, approve bool, reason string) (Booking, error) {
+	if !before.IsActive || before.Status != StatusPendingApproval {
+		return Booking{}, ErrNotPending
+	}
+	if before.ApprovalExpiresAt != nil && !before.ApprovalExpiresAt.After(time.Now()) {
+		return Booking{}, fmt.Errorf("%w: its approval window has passed", ErrNotPending)
+	}
+	status, action := StatusRejected, "reject"
+	var paymentExpires *time.Time
+	if approve {
+		status, action = StatusConfirmed, "approve"
+		if before.Price != nil && *before.Price > 0 {
+			expires := time.Now().Add(PaymentWindow)
+			status, paymentExpires = StatusPendingPayment, &expires
+		}
+	}
+	var decisionReason *string
+	if reason != "" {
+		decisionReason = &reason
+	}
+	var after Booking
+	err := tx.GetContext(ctx, &after, `UPDATE bookings SET status = $1, is_active = $2, decided_at = now(),
+              decision_reason = $3, approval_expires_at = NULL, payment_expires_at = $4, updated_at = now()
+              WHERE id = $5 RETURNING *`,
+		status, approve, decisionReason, paymentExpires, before.ID)
+	if err != nil {
+		return Booking{}, err
+	}
+	return after, recordChange(ctx, tx, before.ID, action, before, after)
+}
+
+// ExpireApprovalsTx expires the bookings whose approval window has passed,
+// giving their places back, and returns them
+func ExpireApprovalsTx(ctx context.Context, tx *sqlx.Tx) ([]Booking, error) {
+	var pending []Booking
+	err := tx.SelectContext(ctx, &pending, `SELECT * FROM bookings
+              WHERE status = 'pending_approval' AND approval_expires_at <= now() ORDER BY id FOR UPDATE SKIP LOCKED`)
+	if err != nil {
+		return nil, err
+	}
+	expired := make([]Booking, 0, len(pending))
+	for _, before := range pending {
+		var after Booking
+		err := tx.GetContext(ctx, &after, `UPDATE bookings SET status = 'expired', is_active = FALSE, updated_at = now()
+              WHERE id = $1 RETURNING *`, before.ID)
+		if err != nil {
+			return nil, err
+		}
+		if err := recordChange(ctx, tx, before.ID, "expire", before, after); err != nil {
+			return nil, err
+		}
+		expired = append(expired, after)
+	}
+	return expired, nil
+}
@@ -1,9 +1,14 @@
 package bookings
 
 import (
+	"encoding/json"
 	"errors"
 	"sync"
 	"time"
+
+	"booking-app/internal/timezone"
+
+	"github.com/jmoiron/sqlx/types"
 )
 
 type Booking struct {
@@ -13,6 +18,138 @@ type Booking struct {
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
 	IsActive  bool      `json:"is_active" db:"is_active"`
+	UserID    *int      `json:"user_id,omitempty" db:"user_id"`
+	SeatID    *int      `json:"seat_id,omitempty" db:"seat_id"`
+	// TicketTypeID, Price and Currency record what was sold and for how
+	// much, in minor currency units, at the time of booking.
+	TicketTypeID *int    `json:"ticket_type_id,omitempty" db:"ticket_type_id"`
+	Price        *int64  `json:"price,omitempty" db:"price"`
+	Currency     *string `json:"currency,omitempty" db:"currency"`
+	Status       string  `json:"status" db:"status"`
+	PaymentID    *int    `json:"payment_id,omitempty" db:"payment_id"`
+	// PromoCodeID and Discount record a promo code redeemed by the booking;
+	// Price is what remains to pay after the discount.
+	PromoCodeID *int   `json:"promo_code_id,omitempty" db:"promo_code_id"`
+	Discount    *int64 `json:"discount,omitempty" db:"discount"`
+	// PlatformFee is the platform's share taken when the booking was paid for
+	PlatformFee *int64 `json:"-" db:"platform_fee"`
+	// ResourceID, StartsAt and EndsAt are set for appointments. The resource
+	// stays blocked until BlockedUntil, which adds its buffer to EndsAt.
+	// TimeZone is the resource's, in which StartsAt and EndsAt are also
+	// shown locally.
+	ResourceID   *int       `json:"resource_id,omitempty" db:"resource_id"`
+	StartsAt     *time.Time `json:"starts_at,omitempty" db:"starts_at"`
+	EndsAt       *time.Time `json:"ends_at,omitempty" db:"ends_at"`
+	BlockedUntil *time.Time `json:"-" db:"blocked_until"`
+	TimeZone     *string    `json:"time_zone,omitempty" db:"time_zone"`
+	// SeriesID is set for bookings made together as a recurring booking
+	SeriesID *int `json:"series_id,omitempty" db:"series_id"`
+	// Oversold is set for bookings sold beyond the capacity of their event
+	// or slot under its overbooking policy. CheckedInAt records when the
+	// customer showed up.
+	Oversold    bool       `json:"oversold" db:"oversold"`
+	CheckedInAt *time.Time `json:"checked_in_at,omitempty" db:"checked_in_at"`
+	// ApprovalExpiresAt is when a booking pending approval expires. DecidedAt
+	// and DecisionReason record the organizer's decision.
+	ApprovalExpiresAt *time.Time `json:"approval_expires_at,omitempty" db:"approval_expires_at"`
+	DecidedAt         *time.Time `json:"decided_at,omitempty" db:"decided_at"`
+	DecisionReason    *string    `json:"decision_reason,omitempty" db:"decision_reason"`
+	// PaymentExpiresAt is when a booking awaiting payment gives its place
+	// back if nobody has started paying for it
+	PaymentExpiresAt *time.Time `json:"payment_expires_at,omitempty" db:"payment_expires_at"`
+	// Answers are the customer's answers to the attendee questions of the
+	// event, validated against its schema when booked
+	Answers *types.JSONText `json:"answers,omitempty" db:"answers"`
+}
+
+// MarshalJSON adds the venue-local times of an appointment next to the UTC
+// ones stored in the booking.
+func (b Booking) MarshalJSON() ([]byte, error) {
+	type booking Booking
+	out := struct {
+		booking
+		StartsAtLocal *time.Time `json:"starts_at_local,omitempty"`
+		EndsAtLocal   *time.Time `json:"ends_at_local,omitempty"`
+	}{booking: booking(b)}
+	if b.TimeZone != nil {
+		out.StartsAtLocal = timezone.In(b.StartsAt, *b.TimeZone)
+		out.EndsAtLocal = timezone.In(b.EndsAt, *b.TimeZone)
+	}
+	return json.Marshal(out)
+}
+
+// slot returns the appointment slot of a booking, if any
+func (b Booking) slot() *Slot {
+	if b.ResourceID == nil {
+		return nil
+	}
+	slot := &Slot{ResourceID: *b.ResourceID, StartsAt: *b.StartsAt, EndsAt: *b.EndsAt, BlockedUntil: *b.BlockedUntil}
+	if b.TimeZone != nil {
+		slot.TimeZone = *b.TimeZone
+	}
+	return slot
+}
+
+// Booking statuses. Bookings with a price start out pending payment and are
+// confirmed once their payment is captured, or expire if it is not paid for
+// within PaymentWindow; free bookings are confirmed straight away. On events that require approval bookings start out pending
+// approval instead, and move on once the organizer approves them or end up
+// rejected, or expired if nobody decides in time. Cancelled bookings have
+// given their place, and any refund due, back for good.
+const (
+	StatusPendingPayment  = "pending_payment"
+	StatusConfirmed       = "confirmed"
+	StatusPaymentFailed   = "payment_failed"
+	StatusPendingApproval = "pending_approval"
+	StatusRejected        = "rejected"
+	StatusExpired         = "expired"
+	StatusCancelled       = "cancelled"
+)
+
+// final reports whether a booking with the given status has given up its
+// place for good and can never be reactivated
+func final(status string) bool {
+	switch status {
+	case StatusPaymentFailed, StatusRejected, StatusExpired, StatusCancelled:
+		return true
+	}
+	return false
+}
+
+// paid reports whether money has been or is being collected for a booking,
+// so that it can only be cancelled through a refund
+func (b Booking) paid() bool {
+	return b.PaymentID != nil && (b.Status == StatusConfirmed || b.Status == StatusPendingPayment)
+}
+
+// NewBooking describes a booking to create. UserID defaults to the
+// authenticated user; SeatID is only set for events with assigned seating
+// and TicketTypeID is required for events that sell ticket types. PromoCode
+// optionally discounts the ticket price. Slot makes the booking an
+// appointment on a resource. Answers respond to the event's attendee
+// questions, if it asks any. Held marks a booking converted from a hold,
+// whose waiting room admission was used when the hold was made.
+type NewBooking struct {
+	UserName     string
+	Event        string
+	UserID       *int
+	SeatID       *int
+	TicketTypeID *int
+	PromoCode    string
+	Slot         *Slot
+	SeriesID     *int
+	Answers      json.RawMessage
+	Held         bool
+}
+
+// Slot is the time an appointment occupies a resource. Overlapping slots
+// on the same resource are rejected by the database.
+type Slot struct {
+	ResourceID   int
+	StartsAt     time.Time
+	EndsAt       time.Time
+	BlockedUntil time.Time
+	TimeZone     string
 }
 
 // Store manages bookings in memory
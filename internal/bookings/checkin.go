@@ -0,0 +1,146 @@
+package bookings
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+
+	"booking-app/internal/database"
+	"booking-app/internal/middleware"
+	"booking-app/internal/notify"
+	"booking-app/internal/timezone"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	ErrNotCheckable     = errors.New("only active, confirmed bookings can be checked in")
+	ErrAlreadyCheckedIn = errors.New("booking is already checked in")
+)
+
+// CheckIn records that the customer of a booking showed up. Admins, the
+// organizer of its event and the owner of its resource may check customers
+// in. Once check-ins exceed the physical capacity of the event, or of the
+// slot of an appointment, the organizer or owner is notified.
+func (s *DBStore) CheckIn(ctx context.Context, id int) (Booking, error) {
+	var b Booking
+	var alert *notify.Notification
+	err := database.WithTx(ctx, s.db, func(tx *sqlx.Tx) error {
+		before, err := lockBooking(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		ok, err := canCheckIn(ctx, tx, before)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrForbidden
+		}
+		if !before.IsActive || before.Status != StatusConfirmed {
+			return ErrNotCheckable
+		}
+		if before.CheckedInAt != nil {
+			return ErrAlreadyCheckedIn
+		}
+		err = tx.GetContext(ctx, &b, `UPDATE bookings SET checked_in_at = now(), updated_at = now()
+              WHERE id = $1 RETURNING *`, id)
+		if err != nil {
+			return err
+		}
+		if err := recordChange(ctx, tx, id, "check_in", before, b); err != nil {
+			return err
+		}
+		alert, err = overCapacity(ctx, tx, b)
+		return err
+	})
+	if err != nil {
+		return Booking{}, err
+	}
+	if alert != nil {
+		if err := s.notifier.Notify(ctx, *alert); err != nil {
+			log.Printf("Failed to notify about check-ins over capacity for booking %d: %v", id, err)
+		}
+	}
+	return b, nil
+}
+
+func canCheckIn(ctx context.Context, tx *sqlx.Tx, b Booking) (bool, error) {
+	if middleware.IsAdmin(ctx) {
+		return true, nil
+	}
+	userID, ok := middleware.UserIDFromContext(ctx)
+	if !ok {
+		return false, nil
+	}
+	var allowed bool
+	err := tx.GetContext(ctx, &allowed, `SELECT EXISTS (
+              SELECT 1 FROM events WHERE name = $1 AND organizer_id = $3
+              UNION ALL
+              SELECT 1 FROM resources WHERE id = $2 AND owner_id = $3)`, b.Event, b.ResourceID, userID)
+	return allowed, err
+}
+
+// overCapacity returns the notification to send when a check-in is the
+// first beyond physical capacity: of the slot for appointments, which holds
+// one, and of the event otherwise. The event or resource row is locked
+// first, so concurrent check-ins are counted one after the other.
+func overCapacity(ctx context.Context, tx *sqlx.Tx, b Booking) (*notify.Notification, error) {
+	var manager struct {
+		Name     string  `db:"name"`
+		Capacity *int    `db:"capacity"`
+		UserID   *int    `db:"user_id"`
+		UserName *string `db:"username"`
+	}
+	var checkedIn int
+	if b.ResourceID != nil {
+		err := tx.GetContext(ctx, &manager, `SELECT r.name, 1 AS capacity, r.owner_id AS user_id, u.username
+              FROM resources r LEFT JOIN users u ON u.id = r.owner_id WHERE r.id = $1 FOR UPDATE OF r`, *b.ResourceID)
+		if err != nil {
+			return nil, err
+		}
+		err = tx.GetContext(ctx, &checkedIn, `SELECT count(*) FROM bookings
+              WHERE resource_id = $1 AND is_active AND checked_in_at IS NOT NULL
+              AND tstzrange(starts_at, ends_at) && tstzrange($2, $3)`, *b.ResourceID, b.StartsAt, b.EndsAt)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		err := tx.GetContext(ctx, &manager, `SELECT e.name, e.capacity, e.organizer_id AS user_id, u.username
+              FROM events e LEFT JOIN users u ON u.id = e.organizer_id WHERE e.name = $1 FOR UPDATE OF e`, b.Event)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		err = tx.GetContext(ctx, &checkedIn, `SELECT count(*) FROM bookings
+              WHERE event = $1 AND is_active AND checked_in_at IS NOT NULL`, b.Event)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if manager.Capacity == nil || checkedIn != *manager.Capacity+1 || manager.UserID == nil {
+		return nil, nil
+	}
+	n := &notify.Notification{
+		UserID:  manager.UserID,
+		Subject: fmt.Sprintf("Check-ins exceed the capacity of %s", manager.Name),
+		Body: fmt.Sprintf("%d customers have checked in to %s, which has room for %d. Booking %d was the first over capacity.",
+			checkedIn, manager.Name, *manager.Capacity, b.ID),
+	}
+	if manager.UserName != nil {
+		n.UserName = *manager.UserName
+	}
+	if b.ResourceID != nil {
+		zone := ""
+		if b.TimeZone != nil {
+			zone = *b.TimeZone
+		}
+		n.Body = fmt.Sprintf("%d customers have checked in to the appointment on %s at %s, which has room for one. Booking %d was the first over capacity.",
+			checkedIn, manager.Name, timezone.In(b.StartsAt, zone).Format("2006-01-02 15:04 MST"), b.ID)
+	}
+	return n, nil
+}
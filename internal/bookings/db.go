@@ -2,17 +2,42 @@ package bookings
 
 import (
 	"context"
+	"database/sql"
 	"errors"
+	"fmt"
 	"log"
 	"time"
 
+	"booking-app/internal/audit"
+	"booking-app/internal/database"
+	"booking-app/internal/events"
+	"booking-app/internal/middleware"
+	"booking-app/internal/notify"
+	"booking-app/internal/outbox"
+	"booking-app/internal/overbooking"
+	"booking-app/internal/promos"
+	"booking-app/internal/rules"
+	"booking-app/internal/waitingroom"
+
 	"github.com/jmoiron/sqlx"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
+)
+
+// entityType is the type bookings are recorded under in the audit trail and outbox
+const entityType = "booking"
+
+// Event types written to the outbox when bookings change
+const (
+	EventCreated   = "booking.created"
+	EventUpdated   = "booking.updated"
+	EventCancelled = "booking.cancelled"
 )
 
 // DBStore manages bookings in PostgreSQL
 type DBStore struct {
-	db *sqlx.DB
+	db       *sqlx.DB
+	limits   Limits
+	notifier notify.Notifier
 }
 
 func NewDBStore(dsn string) (*DBStore, error) {
@@ -20,27 +45,169 @@ func NewDBStore(dsn string) (*DBStore, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &DBStore{db: db}, nil
+	return &DBStore{db: db, notifier: notify.LogNotifier{}}, nil
 }
 
 func (s *DBStore) CreateBooking(ctx context.Context, user, event string) (Booking, error) {
-	if user == "" || event == "" {
+	return s.Create(ctx, NewBooking{UserName: user, Event: event})
+}
+
+// Create creates a booking in its own transaction
+func (s *DBStore) Create(ctx context.Context, nb NewBooking) (Booking, error) {
+	var b Booking
+	err := database.WithTx(ctx, s.db, func(tx *sqlx.Tx) error {
+		var err error
+		b, err = s.CreateBookingTx(ctx, tx, nb)
+		return err
+	})
+	if err != nil {
+		return Booking{}, err
+	}
+	return b, nil
+}
+
+// CreateBookingTx creates a booking inside the caller's transaction, failing
+// with rules.ErrNotBookable if the booking rules of its event or resource
+// do not allow it yet or any more, with ErrLimitReached or ErrOverlap if
+// the user may not book any more or at that time, with events.ErrEventFull
+// if the event has no places left, with events.ErrSoldOut or
+// events.ErrNotOnSale if its ticket type cannot be sold, with a promos error
+// if its promo code cannot be redeemed, ErrSeatTaken if its seat is already
+// booked, ErrSlotTaken if its slot overlaps another, ErrInvalidAnswers if
+// its answers do not satisfy the event's attendee questions and
+// waitingroom.ErrNotAdmitted if the event has a waiting room the user has
+// not been admitted from.
+func (s *DBStore) CreateBookingTx(ctx context.Context, tx *sqlx.Tx, nb NewBooking) (Booking, error) {
+	if nb.UserName == "" || nb.Event == "" {
 		return Booking{}, errors.New("user and event cannot be empty")
 	}
+	now := time.Now().UTC()
 	b := Booking{
-		UserName:  user,
-		Event:     event,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		UserName:  nb.UserName,
+		Event:     nb.Event,
+		CreatedAt: now,
+		UpdatedAt: now,
 		IsActive:  true,
+		UserID:    nb.UserID,
+		SeatID:    nb.SeatID,
+		SeriesID:  nb.SeriesID,
 	}
-	query := `INSERT INTO bookings (user_name, event, created_at, updated_at, is_active) 
-              VALUES (:user_name, :event, :created_at, :updated_at, :is_active) 
-              RETURNING *`
-	rows, err := s.db.NamedQueryContext(ctx, query, b)
+	if nb.Slot != nil {
+		b.ResourceID = &nb.Slot.ResourceID
+		b.StartsAt = &nb.Slot.StartsAt
+		b.EndsAt = &nb.Slot.EndsAt
+		b.BlockedUntil = &nb.Slot.BlockedUntil
+		b.TimeZone = &nb.Slot.TimeZone
+	}
+	if userID, ok := middleware.UserIDFromContext(ctx); ok && b.UserID == nil {
+		b.UserID = &userID
+	}
+	if err := CheckRulesTx(ctx, tx, nb.Event, nb.Slot, now); err != nil {
+		return Booking{}, err
+	}
+	if err := CheckLimitsTx(ctx, tx, s.limits, b.UserID, nb.Event, 1, nb.Slot); err != nil {
+		return Booking{}, err
+	}
+	// Appointments are keyed by their resource and only labelled with its
+	// name, so the settings of an event never apply to them
+	var ticket *events.TicketType
+	var window time.Duration
+	if nb.Slot == nil {
+		if !nb.Held {
+			if err := waitingroom.UseAdmissionTx(ctx, tx, nb.Event, b.UserID); err != nil {
+				return Booking{}, err
+			}
+		}
+		answers, err := checkAnswers(ctx, tx, nb.Event, nb.Answers)
+		if err != nil {
+			return Booking{}, err
+		}
+		b.Answers = answers
+		oversold, err := events.ReserveCapacity(ctx, tx, nb.Event, 1)
+		if err != nil {
+			return Booking{}, err
+		}
+		b.Oversold = oversold > 0
+		if ticket, err = events.ReserveTicket(ctx, tx, nb.Event, nb.TicketTypeID, 1); err != nil {
+			return Booking{}, err
+		}
+		if window, err = approvalWindow(ctx, tx, nb.Event); err != nil {
+			return Booking{}, err
+		}
+	}
+	var promo *promos.PromoCode
+	if ticket != nil {
+		price := ticket.Price
+		b.TicketTypeID = &ticket.ID
+		b.Price = &price
+		b.Currency = &ticket.Currency
+	}
+	if nb.PromoCode != "" {
+		target := promos.Target{}
+		if ticket != nil {
+			target = promos.Target{EventID: &ticket.EventID, TicketTypeID: &ticket.ID, Price: ticket.Price, Currency: ticket.Currency}
+		}
+		p, discount, err := promos.ReserveTx(ctx, tx, nb.PromoCode, target, b.UserID)
+		if err != nil {
+			return Booking{}, err
+		}
+		promo = &p
+		price := *b.Price - discount
+		b.PromoCodeID = &p.ID
+		b.Discount = &discount
+		b.Price = &price
+	}
+	b.Status = StatusConfirmed
+	if b.Price != nil && *b.Price > 0 {
+		expires := now.Add(PaymentWindow)
+		b.Status = StatusPendingPayment
+		b.PaymentExpiresAt = &expires
+	}
+	if window > 0 {
+		expires := now.Add(window)
+		b.Status = StatusPendingApproval
+		b.ApprovalExpiresAt = &expires
+		b.PaymentExpiresAt = nil
+	}
+	if nb.SeatID != nil {
+		if err := checkSeat(ctx, tx, nb.Event, *nb.SeatID); err != nil {
+			return Booking{}, err
+		}
+	}
+	if nb.Slot != nil {
+		oversold, err := LockSlotTx(ctx, tx, *nb.Slot)
+		if err != nil {
+			return Booking{}, err
+		}
+		b.Oversold = b.Oversold || oversold
+	}
+	b, err := insertBooking(ctx, tx, b)
 	if err != nil {
 		return Booking{}, err
 	}
+	if promo != nil {
+		if err := promos.RedeemTx(ctx, tx, promo.ID, b.ID, b.UserID, *b.Discount); err != nil {
+			return Booking{}, err
+		}
+	}
+	if err := recordChange(ctx, tx, b.ID, "create", nil, b); err != nil {
+		return Booking{}, err
+	}
+	return b, nil
+}
+
+func insertBooking(ctx context.Context, tx *sqlx.Tx, b Booking) (Booking, error) {
+	query := `INSERT INTO bookings (user_name, event, created_at, updated_at, is_active, user_id, seat_id,
+              ticket_type_id, price, currency, status, promo_code_id, discount,
+              resource_id, starts_at, ends_at, blocked_until, time_zone, series_id, oversold, approval_expires_at, payment_expires_at, answers) 
+              VALUES (:user_name, :event, :created_at, :updated_at, :is_active, :user_id, :seat_id,
+              :ticket_type_id, :price, :currency, :status, :promo_code_id, :discount,
+              :resource_id, :starts_at, :ends_at, :blocked_until, :time_zone, :series_id, :oversold, :approval_expires_at, :payment_expires_at, :answers) 
+              RETURNING *`
+	rows, err := sqlx.NamedQueryContext(ctx, tx, query, b)
+	if err != nil {
+		return Booking{}, conflict(err)
+	}
 	defer func() {
 		if err := rows.Close(); err != nil {
 			log.Printf("Failed to close rows in CreateBooking: %v", err)
@@ -84,45 +251,243 @@ func (s *DBStore) UpdateBooking(ctx context.Context, id int, user, event string)
 	if user == "" || event == "" {
 		return Booking{}, errors.New("user and event cannot be empty")
 	}
-	b := Booking{
-		ID:        id,
-		UserName:  user,
-		Event:     event,
-		UpdatedAt: time.Now(),
-		IsActive:  true,
-	}
-	query := `UPDATE bookings SET user_name = :user_name, event = :event, updated_at = :updated_at, is_active = :is_active 
-              WHERE id = :id RETURNING *`
-	rows, err := s.db.NamedQueryContext(ctx, query, b)
+	var b Booking
+	err := database.WithTx(ctx, s.db, func(tx *sqlx.Tx) error {
+		before, err := lockBooking(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if !canModify(ctx, before) {
+			return ErrForbidden
+		}
+		oversold, err := s.reserveForChange(ctx, tx, before, event, true)
+		if err != nil {
+			return err
+		}
+		query := `UPDATE bookings SET user_name = $1, event = $2, updated_at = $3, is_active = $4, oversold = $5 
+              WHERE id = $6 RETURNING *`
+		if err := tx.GetContext(ctx, &b, query, user, event, time.Now(), true, oversold, id); err != nil {
+			return conflict(err)
+		}
+		if err := resetApproval(ctx, tx, before, &b); err != nil {
+			return err
+		}
+		return recordChange(ctx, tx, id, auditAction(before, b), before, b)
+	})
 	if err != nil {
 		return Booking{}, err
 	}
-	defer func() {
-		if err := rows.Close(); err != nil {
-			log.Printf("Failed to close rows in UpdateBooking: %v", err)
+	return b, nil
+}
+
+func (s *DBStore) DeleteBooking(ctx context.Context, id int) error {
+	return database.WithTx(ctx, s.db, func(tx *sqlx.Tx) error {
+		before, err := lockBooking(ctx, tx, id)
+		if err != nil {
+			return err
 		}
-	}()
-	if rows.Next() {
-		if err := rows.StructScan(&b); err != nil {
-			return Booking{}, err
+		if !canModify(ctx, before) {
+			return ErrForbidden
 		}
-	} else {
-		return Booking{}, errors.New("booking not found")
+		if before.paid() {
+			return ErrPaidBooking
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM bookings WHERE id = $1", id); err != nil {
+			return err
+		}
+		return recordChange(ctx, tx, id, "delete", before, nil)
+	})
+}
+
+// GetBookingHistory returns the audit trail of a booking, oldest first. The
+// history outlives the booking itself, so deleted bookings still have one.
+func (s *DBStore) GetBookingHistory(ctx context.Context, id int) ([]audit.Entry, error) {
+	entries, err := audit.ForEntity(ctx, s.db, entityType, int64(id))
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, ErrBookingNotFound
+	}
+	return entries, nil
+}
+
+// lockBooking loads a booking and locks its row until the transaction ends
+func lockBooking(ctx context.Context, tx *sqlx.Tx, id int) (Booking, error) {
+	var b Booking
+	if err := tx.GetContext(ctx, &b, "SELECT * FROM bookings WHERE id = $1 FOR UPDATE", id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Booking{}, ErrBookingNotFound
+		}
+		return Booking{}, err
 	}
 	return b, nil
 }
 
-func (s *DBStore) DeleteBooking(ctx context.Context, id int) error {
-	result, err := s.db.ExecContext(ctx, "DELETE FROM bookings WHERE id = $1", id)
+// reserveForChange checks capacity when an update moves a booking to another
+// event or reactivates it, since only then does it take up a new place, and
+// returns whether the booking is oversold afterwards. Seated and ticketed
+// bookings cannot move between events because seats and ticket types belong
+// to a single event.
+func (s *DBStore) reserveForChange(ctx context.Context, tx *sqlx.Tx, before Booking, event string, active bool) (bool, error) {
+	if before.SeatID != nil && before.Event != event {
+		return false, fmt.Errorf("%w: event cannot be changed for a seated booking", ErrInvalidField)
+	}
+	if before.TicketTypeID != nil && before.Event != event {
+		return false, fmt.Errorf("%w: event cannot be changed for a ticketed booking", ErrInvalidField)
+	}
+	if before.ResourceID != nil && before.Event != event {
+		return false, fmt.Errorf("%w: event cannot be changed for an appointment", ErrInvalidField)
+	}
+	if before.Answers != nil && before.Event != event {
+		return false, fmt.Errorf("%w: event cannot be changed for a booking with attendee answers", ErrInvalidField)
+	}
+	if before.Event != event {
+		if _, err := checkAnswers(ctx, tx, event, nil); err != nil {
+			return false, err
+		}
+	}
+	if final(before.Status) && active {
+		return false, fmt.Errorf("%w: a %s booking cannot be reactivated", ErrInvalidField, before.Status)
+	}
+	if before.IsActive && !active && before.paid() {
+		return false, ErrPaidBooking
+	}
+	if !active || (before.IsActive && before.Event == event) {
+		return before.Oversold, nil
+	}
+	if err := CheckRulesTx(ctx, tx, event, before.slot(), time.Now()); err != nil {
+		return false, err
+	}
+	if before.Event != event && before.paid() {
+		window, err := approvalWindow(ctx, tx, event)
+		if err != nil {
+			return false, err
+		}
+		if window > 0 {
+			return false, fmt.Errorf("%w: a paid booking cannot move to an event that requires approval", ErrInvalidField)
+		}
+	}
+	if before.slot() == nil {
+		if err := waitingroom.UseAdmissionTx(ctx, tx, event, before.UserID); err != nil {
+			return false, err
+		}
+	}
+	// The booking itself is left out so it neither counts twice towards a
+	// limit nor overlaps with its old event.
+	if err := checkLimits(ctx, tx, s.limits, before.UserID, event, 1, before.slot(), before.ID); err != nil {
+		return false, err
+	}
+	if slot := before.slot(); slot != nil {
+		return LockSlotTx(ctx, tx, *slot)
+	}
+	oversold, err := events.ReserveCapacity(ctx, tx, event, 1)
 	if err != nil {
-		return err
+		return false, err
+	}
+	if before.TicketTypeID != nil {
+		if _, err := events.ReserveTicket(ctx, tx, event, before.TicketTypeID, 1); err != nil {
+			return false, err
+		}
 	}
-	rows, err := result.RowsAffected()
+	return oversold > 0, nil
+}
+
+// checkSeat verifies that a seat belongs to the seat map of the event
+func checkSeat(ctx context.Context, tx *sqlx.Tx, event string, seatID int) error {
+	var found bool
+	err := tx.GetContext(ctx, &found, `SELECT EXISTS (
+              SELECT 1 FROM seats s JOIN events e ON e.seat_map_id = s.seat_map_id
+              WHERE e.name = $1 AND s.id = $2)`, event, seatID)
 	if err != nil {
 		return err
 	}
-	if rows == 0 {
-		return errors.New("booking not found")
+	if !found {
+		return ErrUnknownSeat
 	}
 	return nil
 }
+
+// LockSlotTx locks a slot's resource until the caller's transaction ends and
+// fails with ErrSlotTaken if active appointments or holds overlapping the
+// slot leave no room for another under the resource's overbooking policy.
+// It returns whether the slot is oversold, i.e. anything overlaps it. The
+// database also rejects overlaps between appointments that are not oversold,
+// but holds are only checked here, so everything that takes a slot must
+// call this first.
+func LockSlotTx(ctx context.Context, tx *sqlx.Tx, slot Slot) (oversold bool, err error) {
+	var policy overbooking.Policy
+	err = tx.GetContext(ctx, &policy, "SELECT overbook_percent, overbook_limit FROM resources WHERE id = $1 FOR UPDATE", slot.ResourceID)
+	if err != nil {
+		return false, err
+	}
+	var taken int
+	err = tx.GetContext(ctx, &taken, `SELECT
+              (SELECT count(*) FROM bookings WHERE resource_id = $1 AND is_active
+               AND tstzrange(starts_at, blocked_until) && tstzrange($2, $3)) +
+              (SELECT count(*) FROM holds WHERE resource_id = $1 AND status = 'active' AND expires_at > now()
+               AND tstzrange(starts_at, blocked_until) && tstzrange($2, $3))`,
+		slot.ResourceID, slot.StartsAt, slot.BlockedUntil)
+	if err != nil {
+		return false, err
+	}
+	if taken > policy.Allowance(1) {
+		return false, ErrSlotTaken
+	}
+	return taken > 0, nil
+}
+
+// CheckRulesTx checks a booking against the rules of its event or, for
+// appointments, of its resource
+func CheckRulesTx(ctx context.Context, tx *sqlx.Tx, event string, slot *Slot, now time.Time) error {
+	if slot != nil {
+		return rules.CheckSlotTx(ctx, tx, slot.ResourceID, slot.StartsAt, slot.EndsAt, now)
+	}
+	return rules.CheckEventTx(ctx, tx, event, now)
+}
+
+// conflict translates violations of the one-booking-per-seat index and of
+// the constraint against overlapping appointments
+func conflict(err error) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return err
+	}
+	switch {
+	case pqErr.Code == "23505" && pqErr.Constraint == "bookings_event_seat_idx":
+		return ErrSeatTaken
+	case pqErr.Code == "23P01" && pqErr.Constraint == "bookings_resource_slot_excl":
+		return ErrSlotTaken
+	}
+	return err
+}
+
+// auditAction names a booking change, distinguishing cancellations from other updates
+func auditAction(before, after Booking) string {
+	if before.IsActive && !after.IsActive {
+		return "cancel"
+	}
+	return "update"
+}
+
+// recordChange writes the audit entry and outbox event for a booking change
+// inside the transaction that makes it. before is nil for creations and
+// after is nil for deletions.
+func recordChange(ctx context.Context, tx *sqlx.Tx, id int, action string, before, after interface{}) error {
+	if err := audit.Write(ctx, tx, audit.Record{EntityType: entityType, EntityID: int64(id), Action: action, Before: before, After: after}); err != nil {
+		return err
+	}
+	msg := outbox.Message{AggregateType: entityType, AggregateID: int64(id), Data: after}
+	switch action {
+	case "create":
+		msg.Type = EventCreated
+	case "cancel", "reject", "expire":
+		msg.Type = EventCancelled
+	case "delete":
+		msg.Type = EventCancelled
+		msg.Data = before
+	default:
+		msg.Type = EventUpdated
+	}
+	return outbox.Enqueue(ctx, tx, msg)
+}
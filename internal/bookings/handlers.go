@@ -1,17 +1,32 @@
 package bookings
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"io"
 	"log"
+	"mime"
 	"net/http"
 	"strconv"
 
+	"booking-app/internal/audit"
+	"booking-app/internal/events"
+	"booking-app/internal/jsonpatch"
+	"booking-app/internal/middleware"
+	"booking-app/internal/promos"
+	"booking-app/internal/rules"
+	"booking-app/internal/waitingroom"
+
 	"github.com/go-playground/validator/v10"
 	"github.com/gorilla/mux"
 )
 
 var validate = validator.New()
 
+// maxPatchBytes limits the size of PATCH request bodies
+const maxPatchBytes = 1 << 20
+
 type Handler struct {
 	store *DBStore
 }
@@ -22,6 +37,9 @@ func NewHandler(store *DBStore) *Handler {
 
 func (h *Handler) ListBookings(w http.ResponseWriter, r *http.Request) {
 	bookings, err := h.store.GetAllBookings(r.Context())
+	if err == nil {
+		err = h.store.redactAnswers(r.Context(), bookings)
+	}
 	if err != nil {
 		http.Error(w, "Failed to fetch bookings", http.StatusInternalServerError)
 		return
@@ -44,16 +62,25 @@ func (h *Handler) GetBookingHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusNotFound)
 		return
 	}
+	list := []Booking{booking}
+	if err := h.store.redactAnswers(r.Context(), list); err != nil {
+		http.Error(w, "Failed to fetch booking", http.StatusInternalServerError)
+		return
+	}
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(booking); err != nil {
+	if err := json.NewEncoder(w).Encode(list[0]); err != nil {
 		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
 	}
 }
 
 func (h *Handler) CreateBookingHandler(w http.ResponseWriter, r *http.Request) {
 	var input struct {
-		UserName string `json:"user_name" validate:"required"`
-		Event    string `json:"event" validate:"required"`
+		UserName     string `json:"user_name" validate:"required"`
+		Event        string `json:"event" validate:"required"`
+		TicketTypeID *int   `json:"ticket_type_id"`
+		PromoCode    string `json:"promo_code" validate:"max=64"`
+		// Answers respond to the event's attendee questions
+		Answers json.RawMessage `json:"answers"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
@@ -63,7 +90,25 @@ func (h *Handler) CreateBookingHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	booking, err := h.store.CreateBooking(r.Context(), input.UserName, input.Event)
+	booking, err := h.store.Create(r.Context(), NewBooking{
+		UserName:     input.UserName,
+		Event:        input.Event,
+		TicketTypeID: input.TicketTypeID,
+		PromoCode:    input.PromoCode,
+		Answers:      input.Answers,
+	})
+	if errors.Is(err, waitingroom.ErrNotAdmitted) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if unavailable(err) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if errors.Is(err, ErrInvalidAnswers) {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
@@ -95,6 +140,14 @@ func (h *Handler) UpdateBookingHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	booking, err := h.store.UpdateBooking(r.Context(), id, input.UserName, input.Event)
+	if errors.Is(err, ErrForbidden) || errors.Is(err, waitingroom.ErrNotAdmitted) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if unavailable(err) || errors.Is(err, ErrSeatTaken) || errors.Is(err, ErrSlotTaken) || errors.Is(err, ErrPaidBooking) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
@@ -105,6 +158,68 @@ func (h *Handler) UpdateBookingHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// PatchBookingHandler applies a JSON Merge Patch (RFC 7396) or JSON Patch
+// (RFC 6902) document to a booking, selected by the request Content-Type.
+func (h *Handler) PatchBookingHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxPatchBytes))
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	var apply PatchFunc
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "application/merge-patch+json", "application/json":
+		apply = func(doc []byte) ([]byte, error) { return jsonpatch.MergePatch(doc, body) }
+	case "application/json-patch+json":
+		apply = func(doc []byte) ([]byte, error) { return jsonpatch.Apply(doc, body) }
+	default:
+		w.Header().Set("Accept-Patch", "application/merge-patch+json, application/json-patch+json")
+		http.Error(w, "Unsupported patch format", http.StatusUnsupportedMediaType)
+		return
+	}
+	booking, err := h.store.PatchBooking(r.Context(), id, apply)
+	if err != nil {
+		http.Error(w, err.Error(), patchErrorStatus(err))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(booking); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+// unavailable reports whether err means the event, ticket type or promo code
+// cannot take another booking right now, or the booking rules or the user's
+// limits do not allow one
+func unavailable(err error) bool {
+	return errors.Is(err, events.ErrEventFull) || errors.Is(err, events.ErrSoldOut) || errors.Is(err, events.ErrNotOnSale) ||
+		errors.Is(err, promos.ErrExhausted) || errors.Is(err, promos.ErrUserLimit) || errors.Is(err, rules.ErrNotBookable) ||
+		errors.Is(err, ErrLimitReached) || errors.Is(err, ErrOverlap)
+}
+
+func patchErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrBookingNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrForbidden), errors.Is(err, ErrReadOnlyField), errors.Is(err, waitingroom.ErrNotAdmitted):
+		return http.StatusForbidden
+	case errors.Is(err, jsonpatch.ErrTestFailed), unavailable(err), errors.Is(err, ErrSeatTaken), errors.Is(err, ErrSlotTaken),
+		errors.Is(err, ErrPaidBooking):
+		return http.StatusConflict
+	case errors.Is(err, ErrInvalidField), errors.Is(err, ErrInvalidAnswers), errors.Is(err, jsonpatch.ErrInvalidPatch):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
 func (h *Handler) DeleteBookingHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id, err := strconv.Atoi(vars["id"])
@@ -112,17 +227,106 @@ func (h *Handler) DeleteBookingHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid ID", http.StatusBadRequest)
 		return
 	}
-	if err := h.store.DeleteBooking(r.Context(), id); err != nil {
+	err = h.store.DeleteBooking(r.Context(), id)
+	if errors.Is(err, ErrForbidden) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if errors.Is(err, ErrPaidBooking) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
 		http.Error(w, err.Error(), http.StatusNotFound)
 		return
 	}
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// GetBookingHistoryHandler returns the audit trail of a booking to its owner or an admin
+func (h *Handler) GetBookingHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	entries, err := h.store.GetBookingHistory(r.Context(), id)
+	if errors.Is(err, ErrBookingNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to fetch booking history", http.StatusInternalServerError)
+		return
+	}
+	if !canViewHistory(r.Context(), entries) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(entries); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+// canViewHistory allows admins, and otherwise the user who owned the booking
+// when it was created. Bookings created without an owner are visible to all.
+func canViewHistory(ctx context.Context, entries []audit.Entry) bool {
+	if middleware.IsAdmin(ctx) {
+		return true
+	}
+	var snapshot struct {
+		UserID *int `json:"user_id"`
+	}
+	first := entries[0].After
+	if len(first) == 0 || first.Unmarshal(&snapshot) != nil {
+		first = entries[0].Before
+		if len(first) == 0 || first.Unmarshal(&snapshot) != nil {
+			return false
+		}
+	}
+	userID, ok := middleware.UserIDFromContext(ctx)
+	return snapshot.UserID == nil || (ok && *snapshot.UserID == userID)
+}
+
+// CheckInHandler records that a booking's customer showed up
+func (h *Handler) CheckInHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	booking, err := h.store.CheckIn(r.Context(), id)
+	switch {
+	case errors.Is(err, ErrBookingNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, ErrForbidden):
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	case errors.Is(err, ErrNotCheckable), errors.Is(err, ErrAlreadyCheckedIn):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		log.Printf("Failed to check in booking %d: %v", id, err)
+		http.Error(w, "Failed to check in booking", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(booking); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
 func (h *Handler) GetBookingsByEventHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	event := vars["event"]
 	bookings, err := h.store.GetBookingsByEvent(r.Context(), event)
+	if err == nil {
+		err = h.store.redactAnswers(r.Context(), bookings)
+	}
 	if err != nil {
 		http.Error(w, "Failed to fetch bookings", http.StatusInternalServerError)
 		return
@@ -0,0 +1,139 @@
+package bookings
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	ErrLimitReached = errors.New("booking limit reached")
+	ErrOverlap      = errors.New("booking overlaps another booking of the same user")
+)
+
+// userLockClass namespaces the advisory locks taken on a user's bookings
+const userLockClass = 4601
+
+// Limits cap what a single user can book across all events. PerPeriod
+// limits the bookings a user makes within any Period, zero meaning no
+// limit. PreventOverlap rejects a booking whose time overlaps another active
+// booking of the user; events without both a start and an end time are not
+// checked. Per-event limits are an event setting.
+type Limits struct {
+	PerPeriod      int
+	Period         time.Duration
+	PreventOverlap bool
+}
+
+// SetLimits sets the limits enforced on new bookings and on bookings moved
+// to another event or reactivated. It must be called
+// before the store is used.
+func (s *DBStore) SetLimits(l Limits) {
+	s.limits = l
+}
+
+// CheckLimitsTx checks that a user may book quantity more places on an
+// event, or the given appointment slot, failing with ErrLimitReached or
+// ErrOverlap. Per-event limits do not apply to appointments. Bookings of
+// anonymous users are not limited.
+//
+// It takes a transaction-scoped advisory lock on the user, so concurrent
+// bookings by the same user are checked one after the other and cannot
+// both slip under a limit; the lock is held until the booking commits.
+func CheckLimitsTx(ctx context.Context, tx *sqlx.Tx, l Limits, userID *int, event string, quantity int, slot *Slot) error {
+	return checkLimits(ctx, tx, l, userID, event, quantity, slot, 0)
+}
+
+// checkLimits is CheckLimitsTx leaving out the booking with ID exclude,
+// which an update is about to move or reactivate
+func checkLimits(ctx context.Context, tx *sqlx.Tx, l Limits, userID *int, event string, quantity int, slot *Slot, exclude int) error {
+	if userID == nil {
+		return nil
+	}
+	if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1, $2)", userLockClass, *userID); err != nil {
+		return err
+	}
+	var perEvent *int
+	if slot == nil {
+		err := tx.GetContext(ctx, &perEvent, "SELECT max_per_user FROM events WHERE name = $1", event)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+	}
+	if perEvent != nil {
+		var taken int
+		err := tx.GetContext(ctx, &taken, `SELECT
+              (SELECT count(*) FROM bookings WHERE user_id = $1 AND event = $2 AND is_active AND id <> $3) +
+              (SELECT coalesce(sum(quantity), 0) FROM holds
+               WHERE user_id = $1 AND event = $2 AND status = 'active' AND expires_at > now())`, *userID, event, exclude)
+		if err != nil {
+			return err
+		}
+		if taken+quantity > *perEvent {
+			return fmt.Errorf("%w: at most %d per user for this event, you have %d", ErrLimitReached, *perEvent, taken)
+		}
+	}
+	if l.PerPeriod > 0 {
+		var recent int
+		err := tx.GetContext(ctx, &recent, `SELECT count(*) FROM bookings
+              WHERE user_id = $1 AND is_active AND created_at > $2 AND id <> $3`, *userID, time.Now().Add(-l.Period), exclude)
+		if err != nil {
+			return err
+		}
+		if recent+quantity > l.PerPeriod {
+			return fmt.Errorf("%w: at most %d bookings per user within %s", ErrLimitReached, l.PerPeriod, hours(l.Period))
+		}
+	}
+	if l.PreventOverlap {
+		return checkOverlap(ctx, tx, *userID, event, slot, exclude)
+	}
+	return nil
+}
+
+// checkOverlap rejects a booking whose time overlaps an active booking of
+// the user on another event. An appointment's time is its slot; an event's
+// runs from its start to its end. Bookings whose time is not fully known are
+// not checked, on either side.
+func checkOverlap(ctx context.Context, tx *sqlx.Tx, userID int, event string, slot *Slot, exclude int) error {
+	var span struct {
+		StartsAt *time.Time `db:"starts_at"`
+		EndsAt   *time.Time `db:"ends_at"`
+	}
+	if slot != nil {
+		span.StartsAt, span.EndsAt = &slot.StartsAt, &slot.EndsAt
+	} else if err := tx.GetContext(ctx, &span, "SELECT starts_at, ends_at FROM events WHERE name = $1", event); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	if span.StartsAt == nil || span.EndsAt == nil {
+		return nil
+	}
+	var other []string
+	err := tx.SelectContext(ctx, &other, `SELECT b.event FROM bookings b
+              LEFT JOIN events e ON b.resource_id IS NULL AND e.name = b.event
+              WHERE b.user_id = $1 AND b.is_active AND b.status <> $2 AND b.event <> $3 AND b.id <> $6
+              AND coalesce(b.starts_at, e.starts_at) IS NOT NULL AND coalesce(b.ends_at, e.ends_at) IS NOT NULL
+              AND tstzrange(coalesce(b.starts_at, e.starts_at), coalesce(b.ends_at, e.ends_at)) && tstzrange($4, $5)
+              LIMIT 1`, userID, StatusPaymentFailed, event, *span.StartsAt, *span.EndsAt, exclude)
+	if err != nil {
+		return err
+	}
+	if len(other) > 0 {
+		return fmt.Errorf("%w: %s", ErrOverlap, other[0])
+	}
+	return nil
+}
+
+// hours formats a limit period
+func hours(d time.Duration) string {
+	if d%time.Hour == 0 {
+		if d == time.Hour {
+			return "1 hour"
+		}
+		return fmt.Sprintf("%d hours", d/time.Hour)
+	}
+	return d.String()
+}
@@ -0,0 +1,181 @@
+package bookings
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"booking-app/internal/database"
+	"booking-app/internal/middleware"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	ErrBookingNotFound = errors.New("booking not found")
+	ErrForbidden       = errors.New("not allowed to modify this booking")
+	ErrReadOnlyField   = errors.New("field is read-only")
+	ErrInvalidField    = errors.New("invalid field")
+	ErrSeatTaken       = errors.New("seat is already booked")
+	ErrUnknownSeat     = errors.New("seat does not belong to the event's seat map")
+	ErrSlotTaken       = errors.New("resource is already booked at this time")
+	ErrPaidBooking     = errors.New("booking has been paid for and can only be cancelled with a refund")
+)
+
+// PatchFunc transforms the JSON representation of a booking into its patched form
+type PatchFunc func(doc []byte) ([]byte, error)
+
+// readOnlyFields cannot be changed through a patch
+var readOnlyFields = map[string]bool{
+	"id":                  true,
+	"user_id":             true,
+	"seat_id":             true,
+	"ticket_type_id":      true,
+	"price":               true,
+	"currency":            true,
+	"status":              true,
+	"payment_id":          true,
+	"promo_code_id":       true,
+	"discount":            true,
+	"resource_id":         true,
+	"starts_at":           true,
+	"ends_at":             true,
+	"time_zone":           true,
+	"starts_at_local":     true,
+	"ends_at_local":       true,
+	"series_id":           true,
+	"oversold":            true,
+	"checked_in_at":       true,
+	"approval_expires_at": true,
+	"payment_expires_at":  true,
+	"decided_at":          true,
+	"decision_reason":     true,
+	"answers":             true,
+	"created_at":          true,
+	"updated_at":          true,
+}
+
+// PatchBooking applies a patch to a booking while holding a row lock, so
+// concurrent patches to different fields never overwrite each other.
+func (s *DBStore) PatchBooking(ctx context.Context, id int, apply PatchFunc) (Booking, error) {
+	var b Booking
+	err := database.WithTx(ctx, s.db, func(tx *sqlx.Tx) error {
+		current, err := lockBooking(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if !canModify(ctx, current) {
+			return ErrForbidden
+		}
+
+		doc, err := json.Marshal(current)
+		if err != nil {
+			return err
+		}
+		patched, err := apply(doc)
+		if err != nil {
+			return err
+		}
+		next, err := decodePatched(doc, patched, current)
+		if err != nil {
+			return err
+		}
+		oversold, err := s.reserveForChange(ctx, tx, current, next.Event, next.IsActive)
+		if err != nil {
+			return err
+		}
+
+		query := `UPDATE bookings SET user_name = $1, event = $2, is_active = $3, oversold = $4, updated_at = $5
+              WHERE id = $6 RETURNING *`
+		if err := tx.GetContext(ctx, &b, query, next.UserName, next.Event, next.IsActive, oversold, time.Now(), id); err != nil {
+			return conflict(err)
+		}
+		if err := resetApproval(ctx, tx, current, &b); err != nil {
+			return err
+		}
+		return recordChange(ctx, tx, id, auditAction(current, b), current, b)
+	})
+	if err != nil {
+		return Booking{}, err
+	}
+	return b, nil
+}
+
+// canModify allows admins, and otherwise only the booking's owner, to change
+// or delete it. Bookings without an owner can only be changed by admins.
+func canModify(ctx context.Context, b Booking) bool {
+	if middleware.IsAdmin(ctx) {
+		return true
+	}
+	userID, ok := middleware.UserIDFromContext(ctx)
+	return ok && b.UserID != nil && *b.UserID == userID
+}
+
+// decodePatched validates a patched document field by field and returns
+// the booking it describes.
+func decodePatched(original, patched []byte, current Booking) (Booking, error) {
+	var before, after map[string]json.RawMessage
+	if err := json.Unmarshal(original, &before); err != nil {
+		return Booking{}, err
+	}
+	if err := json.Unmarshal(patched, &after); err != nil {
+		return Booking{}, fmt.Errorf("%w: patched booking must be a JSON object", ErrInvalidField)
+	}
+
+	for field := range readOnlyFields {
+		if !sameJSON(before[field], after[field]) {
+			return Booking{}, fmt.Errorf("%w: %s", ErrReadOnlyField, field)
+		}
+	}
+	for field := range after {
+		if _, known := before[field]; !known && !readOnlyFields[field] {
+			return Booking{}, fmt.Errorf("%w: unknown field %s", ErrInvalidField, field)
+		}
+	}
+
+	next := current
+	if err := decodeString(after, "user_name", &next.UserName); err != nil {
+		return Booking{}, err
+	}
+	if err := decodeString(after, "event", &next.Event); err != nil {
+		return Booking{}, err
+	}
+	raw, ok := after["is_active"]
+	if !ok {
+		return Booking{}, fmt.Errorf("%w: is_active cannot be removed", ErrInvalidField)
+	}
+	if err := json.Unmarshal(raw, &next.IsActive); err != nil || string(raw) == "null" {
+		return Booking{}, fmt.Errorf("%w: is_active must be a boolean", ErrInvalidField)
+	}
+	return next, nil
+}
+
+func decodeString(fields map[string]json.RawMessage, name string, dst *string) error {
+	raw, ok := fields[name]
+	if !ok {
+		return fmt.Errorf("%w: %s cannot be removed", ErrInvalidField, name)
+	}
+	var value string
+	if err := json.Unmarshal(raw, &value); err != nil || string(raw) == "null" {
+		return fmt.Errorf("%w: %s must be a string", ErrInvalidField, name)
+	}
+	if value == "" || len(value) > 255 {
+		return fmt.Errorf("%w: %s must be between 1 and 255 characters", ErrInvalidField, name)
+	}
+	*dst = value
+	return nil
+}
+
+func sameJSON(a, b json.RawMessage) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	var va, vb interface{}
+	if json.Unmarshal(a, &va) != nil || json.Unmarshal(b, &vb) != nil {
+		return false
+	}
+	return reflect.DeepEqual(va, vb)
+}
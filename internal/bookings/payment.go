@@ -0,0 +1,151 @@
+package bookings
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"booking-app/internal/middleware"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var ErrNotPayable = errors.New("booking is not awaiting payment")
+
+// PaymentWindow is how long a booking may await payment before its place is
+// given back. A payment started for it has as long again to complete.
+const PaymentWindow = 30 * time.Minute
+
+// LockForPaymentTx locks bookings that are about to be paid for and checks
+// that each one belongs to the caller, is active, awaits payment and is not
+// already part of another payment. Rows are locked in ID order so concurrent
+// payments for overlapping bookings cannot deadlock.
+func LockForPaymentTx(ctx context.Context, tx *sqlx.Tx, ids []int) ([]Booking, error) {
+	sorted := append([]int(nil), ids...)
+	sort.Ints(sorted)
+	var locked []Booking
+	for i, id := range sorted {
+		if i > 0 && sorted[i-1] == id {
+			continue
+		}
+		b, err := lockBooking(ctx, tx, id)
+		if err != nil {
+			return nil, err
+		}
+		if !canPay(ctx, b) {
+			return nil, ErrForbidden
+		}
+		if !b.IsActive || b.Status != StatusPendingPayment || b.PaymentID != nil {
+			return nil, ErrNotPayable
+		}
+		locked = append(locked, b)
+	}
+	return locked, nil
+}
+
+// AttachPaymentTx records the payment that pays for the given bookings
+func AttachPaymentTx(ctx context.Context, tx *sqlx.Tx, bookings []Booking, paymentID int) error {
+	for _, before := range bookings {
+		var after Booking
+		err := tx.GetContext(ctx, &after, `UPDATE bookings SET payment_id = $1, updated_at = now()
+              WHERE id = $2 RETURNING *`, paymentID, before.ID)
+		if err != nil {
+			return err
+		}
+		if err := recordChange(ctx, tx, before.ID, "update", before, after); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DetachPaymentTx unlinks bookings from a payment that was never started with
+// the provider, so they can be paid for again.
+func DetachPaymentTx(ctx context.Context, tx *sqlx.Tx, paymentID int) error {
+	_, err := tx.ExecContext(ctx, `UPDATE bookings SET payment_id = NULL, updated_at = now()
+              WHERE payment_id = $1 AND status = 'pending_payment'`, paymentID)
+	return err
+}
+
+// SettlePaymentTx confirms the bookings of a captured payment, or cancels
+// them and gives their places back if the payment failed. Bookings already
+// settled are left alone, so settling twice is harmless.
+func SettlePaymentTx(ctx context.Context, tx *sqlx.Tx, paymentID int, paid bool) ([]Booking, error) {
+	var pending []Booking
+	err := tx.SelectContext(ctx, &pending, `SELECT * FROM bookings
+              WHERE payment_id = $1 AND status = 'pending_payment' ORDER BY id FOR UPDATE`, paymentID)
+	if err != nil {
+		return nil, err
+	}
+	status, active := StatusConfirmed, true
+	if !paid {
+		status, active = StatusPaymentFailed, false
+	}
+	settled := make([]Booking, 0, len(pending))
+	for _, before := range pending {
+		var after Booking
+		err := tx.GetContext(ctx, &after, `UPDATE bookings SET status = $1, is_active = is_active AND $2, updated_at = now()
+              WHERE id = $3 RETURNING *`, status, active, before.ID)
+		if err != nil {
+			return nil, err
+		}
+		if err := recordChange(ctx, tx, before.ID, auditAction(before, after), before, after); err != nil {
+			return nil, err
+		}
+		settled = append(settled, after)
+	}
+	return settled, nil
+}
+
+// ExpirePaymentsTx expires the bookings that were not paid for in time and
+// have no payment under way, giving their places back, and returns them
+func ExpirePaymentsTx(ctx context.Context, tx *sqlx.Tx) ([]Booking, error) {
+	var unpaid []Booking
+	err := tx.SelectContext(ctx, &unpaid, `SELECT * FROM bookings WHERE status = 'pending_payment'
+              AND payment_id IS NULL AND payment_expires_at <= now() ORDER BY id FOR UPDATE SKIP LOCKED`)
+	if err != nil {
+		return nil, err
+	}
+	expired := make([]Booking, 0, len(unpaid))
+	for _, before := range unpaid {
+		var after Booking
+		err := tx.GetContext(ctx, &after, `UPDATE bookings SET status = 'expired', is_active = FALSE, updated_at = now()
+              WHERE id = $1 RETURNING *`, before.ID)
+		if err != nil {
+			return nil, err
+		}
+		if err := recordChange(ctx, tx, before.ID, "expire", before, after); err != nil {
+			return nil, err
+		}
+		expired = append(expired, after)
+	}
+	return expired, nil
+}
+
+// LockBookingTx loads a booking and locks its row until tx ends
+func LockBookingTx(ctx context.Context, tx *sqlx.Tx, id int) (Booking, error) {
+	return lockBooking(ctx, tx, id)
+}
+
+// CancelBookingTx cancels a locked booking for good, giving its place back.
+// Paid bookings must only be cancelled together with their refund.
+func CancelBookingTx(ctx context.Context, tx *sqlx.Tx, before Booking) (Booking, error) {
+	var after Booking
+	err := tx.GetContext(ctx, &after, `UPDATE bookings SET status = 'cancelled', is_active = FALSE, updated_at = now()
+              WHERE id = $1 RETURNING *`, before.ID)
+	if err != nil {
+		return Booking{}, err
+	}
+	return after, recordChange(ctx, tx, before.ID, auditAction(before, after), before, after)
+}
+
+// canPay allows admins and the booking's owner to pay for it. Bookings
+// without an owner can only be paid for by admins.
+func canPay(ctx context.Context, b Booking) bool {
+	if middleware.IsAdmin(ctx) {
+		return true
+	}
+	userID, ok := middleware.UserIDFromContext(ctx)
+	return ok && b.UserID != nil && *b.UserID == userID
+}
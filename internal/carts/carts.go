@@ -0,0 +1,129 @@
+package carts
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	"booking-app/internal/bookings"
+	"booking-app/internal/payments"
+
+	"github.com/jmoiron/sqlx/types"
+)
+
+// Cart statuses. An active cart past its expiry is marked expired the next
+// time its owner uses it.
+const (
+	StatusActive     = "active"
+	StatusCheckedOut = "checked_out"
+	StatusExpired    = "expired"
+)
+
+// TTL is how long a cart lives after it was last changed
+const TTL = 30 * time.Minute
+
+// MaxItemQuantity caps the places a single cart item can book
+const MaxItemQuantity = 50
+
+// Cart collects tickets for several events to book in one checkout. Items
+// do not reserve places; availability is only checked at checkout.
+type Cart struct {
+	ID        int       `json:"id" db:"id"`
+	UserID    int       `json:"user_id" db:"user_id"`
+	Status    string    `json:"status" db:"status"`
+	ExpiresAt time.Time `json:"expires_at" db:"expires_at"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+	Items     []Item    `json:"items" db:"-"`
+	Quantity  int       `json:"quantity" db:"-"`
+	Totals    []Total   `json:"totals" db:"-"`
+}
+
+// Item is a number of places on an event, of one ticket type for events that
+// sell them. UnitPrice and Currency are the ticket type's current price.
+// Answers respond to the event's attendee questions for each of its places.
+type Item struct {
+	ID           int             `json:"id" db:"id"`
+	CartID       int             `json:"cart_id" db:"cart_id"`
+	Event        string          `json:"event" db:"event"`
+	TicketTypeID *int            `json:"ticket_type_id,omitempty" db:"ticket_type_id"`
+	Quantity     int             `json:"quantity" db:"quantity"`
+	UnitPrice    *int64          `json:"unit_price,omitempty" db:"unit_price"`
+	Currency     *string         `json:"currency,omitempty" db:"currency"`
+	Answers      *types.JSONText `json:"answers,omitempty" db:"answers"`
+	CreatedAt    time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time       `json:"updated_at" db:"updated_at"`
+}
+
+// NewItem describes tickets to add to a cart. Answers replace those of the
+// item when the same tickets are added again.
+type NewItem struct {
+	Event        string
+	TicketTypeID *int
+	Quantity     int
+	Answers      json.RawMessage
+}
+
+// Total is the amount due for a cart's items in one currency, before any
+// promo code discount
+type Total struct {
+	Currency string `json:"currency"`
+	Amount   int64  `json:"amount"`
+}
+
+// Checkout is the result of checking out a cart. Payment is set when the
+// bookings have something to pay; if it could not be started, PaymentError
+// says why and the bookings can still be paid through the payments API.
+type Checkout struct {
+	Cart         Cart               `json:"cart"`
+	Bookings     []bookings.Booking `json:"bookings"`
+	Payment      *payments.Payment  `json:"payment,omitempty"`
+	PaymentError string             `json:"payment_error,omitempty"`
+}
+
+// setItems attaches items to a cart and computes its totals
+func (c *Cart) setItems(items []Item) {
+	c.Items = items
+	c.Quantity, c.Totals = totals(items)
+}
+
+// totals counts the places in items and sums their prices per currency.
+// Currencies with nothing to pay are left out.
+func totals(items []Item) (int, []Total) {
+	quantity := 0
+	sums := make(map[string]int64)
+	for _, it := range items {
+		quantity += it.Quantity
+		if it.UnitPrice != nil && it.Currency != nil {
+			sums[*it.Currency] += *it.UnitPrice * int64(it.Quantity)
+		}
+	}
+	list := []Total{}
+	for currency, amount := range sums {
+		if amount > 0 {
+			list = append(list, Total{Currency: currency, Amount: amount})
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Currency < list[j].Currency })
+	return quantity, list
+}
+
+// lockOrder returns items sorted so that checkouts lock events and ticket
+// types in the same order and cannot deadlock each other
+func lockOrder(items []Item) []Item {
+	sorted := append([]Item(nil), items...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Event != sorted[j].Event {
+			return sorted[i].Event < sorted[j].Event
+		}
+		return ticketTypeID(sorted[i]) < ticketTypeID(sorted[j])
+	})
+	return sorted
+}
+
+func ticketTypeID(it Item) int {
+	if it.TicketTypeID == nil {
+		return 0
+	}
+	return *it.TicketTypeID
+}
@@ -0,0 +1,49 @@
+package carts
+
+import "testing"
+
+func TestTotals(t *testing.T) {
+	price := func(n int64) *int64 { return &n }
+	eur, usd := "EUR", "USD"
+	one, two := 1, 2
+	items := []Item{
+		{Event: "concert", TicketTypeID: &one, Quantity: 2, UnitPrice: price(2500), Currency: &eur},
+		{Event: "concert", TicketTypeID: &two, Quantity: 1, UnitPrice: price(4000), Currency: &eur},
+		{Event: "meetup", Quantity: 3},
+		{Event: "workshop", Quantity: 1, UnitPrice: price(0), Currency: &usd},
+	}
+	quantity, got := totals(items)
+	if quantity != 7 {
+		t.Errorf("Expected 7 places, got %d", quantity)
+	}
+	if len(got) != 1 || got[0] != (Total{Currency: "EUR", Amount: 9000}) {
+		t.Errorf("Expected a single EUR total of 9000, got %+v", got)
+	}
+
+	items = append(items, Item{Event: "festival", Quantity: 1, UnitPrice: price(1000), Currency: &usd})
+	if _, got := totals(items); len(got) != 2 || got[0].Currency != "EUR" || got[1].Currency != "USD" {
+		t.Errorf("Expected EUR and USD totals in order, got %+v", got)
+	}
+	if _, got := totals(nil); got == nil || len(got) != 0 {
+		t.Errorf("Expected an empty list for an empty cart, got %#v", got)
+	}
+}
+
+func TestLockOrder(t *testing.T) {
+	one, two := 1, 2
+	items := []Item{
+		{ID: 1, Event: "b", TicketTypeID: &two},
+		{ID: 2, Event: "a"},
+		{ID: 3, Event: "b", TicketTypeID: &one},
+	}
+	sorted := lockOrder(items)
+	want := []int{2, 3, 1}
+	for i, it := range sorted {
+		if it.ID != want[i] {
+			t.Fatalf("Expected items in order %v, got %+v", want, sorted)
+		}
+	}
+	if items[0].ID != 1 {
+		t.Error("lockOrder must not reorder the cart's items")
+	}
+}
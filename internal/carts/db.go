@@ -0,0 +1,259 @@
+package carts
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"booking-app/internal/bookings"
+	"booking-app/internal/database"
+	"booking-app/internal/events"
+	"booking-app/internal/middleware"
+	"booking-app/internal/payments"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/jmoiron/sqlx/types"
+)
+
+var (
+	ErrCartNotFound = errors.New("no active cart")
+	ErrItemNotFound = errors.New("cart item not found")
+	ErrCartEmpty    = errors.New("cart is empty")
+	ErrTooMany      = fmt.Errorf("a cart item can book at most %d places", MaxItemQuantity)
+	ErrUnauthorized = errors.New("carts require an authenticated user")
+)
+
+// DBStore manages carts in PostgreSQL
+type DBStore struct {
+	db       *sqlx.DB
+	bookings *bookings.DBStore
+	payments *payments.DBStore
+}
+
+func NewDBStore(db *sqlx.DB, bookingStore *bookings.DBStore, paymentStore *payments.DBStore) *DBStore {
+	return &DBStore{db: db, bookings: bookingStore, payments: paymentStore}
+}
+
+// Get returns the caller's active cart with its items and totals
+func (s *DBStore) Get(ctx context.Context) (Cart, error) {
+	userID, ok := middleware.UserIDFromContext(ctx)
+	if !ok {
+		return Cart{}, ErrUnauthorized
+	}
+	var c Cart
+	err := s.db.GetContext(ctx, &c, `SELECT * FROM carts
+              WHERE user_id = $1 AND status = 'active' AND expires_at > now()`, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Cart{}, ErrCartNotFound
+	}
+	if err != nil {
+		return Cart{}, err
+	}
+	items, err := listItems(ctx, s.db, c.ID)
+	if err != nil {
+		return Cart{}, err
+	}
+	c.setItems(items)
+	return c, nil
+}
+
+// AddItem adds tickets to the caller's cart, starting a new cart if there
+// is no active one. The ticket type must belong to the event and be on
+// sale, but places are not reserved until checkout.
+func (s *DBStore) AddItem(ctx context.Context, ni NewItem) (Cart, error) {
+	if ni.Event == "" || ni.Quantity <= 0 {
+		return Cart{}, errors.New("event and a positive quantity are required")
+	}
+	if ni.Quantity > MaxItemQuantity {
+		return Cart{}, ErrTooMany
+	}
+	var c Cart
+	err := database.WithTx(ctx, s.db, func(tx *sqlx.Tx) error {
+		if _, err := events.ReserveTicket(ctx, tx, ni.Event, ni.TicketTypeID, 0); err != nil {
+			return err
+		}
+		var err error
+		if c, err = touchCart(ctx, tx); err != nil {
+			return err
+		}
+		var answers *types.JSONText
+		if len(ni.Answers) > 0 && string(ni.Answers) != "null" {
+			a := types.JSONText(ni.Answers)
+			answers = &a
+		}
+		var id int
+		err = tx.GetContext(ctx, &id, `INSERT INTO cart_items (cart_id, event, ticket_type_id, quantity, answers)
+              VALUES ($1, $2, $3, $4, $6)
+              ON CONFLICT (cart_id, event, (coalesce(ticket_type_id, 0)))
+              DO UPDATE SET quantity = cart_items.quantity + EXCLUDED.quantity,
+              answers = coalesce(EXCLUDED.answers, cart_items.answers), updated_at = now()
+              WHERE cart_items.quantity + EXCLUDED.quantity <= $5
+              RETURNING id`, c.ID, ni.Event, ni.TicketTypeID, ni.Quantity, MaxItemQuantity, answers)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrTooMany
+		}
+		if err != nil {
+			return err
+		}
+		items, err := listItems(ctx, tx, c.ID)
+		c.setItems(items)
+		return err
+	})
+	if err != nil {
+		return Cart{}, err
+	}
+	return c, nil
+}
+
+// RemoveItem removes an item from the caller's cart
+func (s *DBStore) RemoveItem(ctx context.Context, itemID int) (Cart, error) {
+	var c Cart
+	err := database.WithTx(ctx, s.db, func(tx *sqlx.Tx) error {
+		var err error
+		if c, err = lockActiveCart(ctx, tx); err != nil {
+			return err
+		}
+		res, err := tx.ExecContext(ctx, "DELETE FROM cart_items WHERE id = $1 AND cart_id = $2", itemID, c.ID)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return err
+		} else if n == 0 {
+			return ErrItemNotFound
+		}
+		if c, err = touchCart(ctx, tx); err != nil {
+			return err
+		}
+		items, err := listItems(ctx, tx, c.ID)
+		c.setItems(items)
+		return err
+	})
+	if err != nil {
+		return Cart{}, err
+	}
+	return c, nil
+}
+
+// Checkout books every place in the caller's cart in one transaction, so
+// either all bookings are created or, if any item is unavailable, none are.
+// If the bookings have something to pay, a payment is then started for them
+// all at once.
+func (s *DBStore) Checkout(ctx context.Context, userName string) (Checkout, error) {
+	var co Checkout
+	err := database.WithTx(ctx, s.db, func(tx *sqlx.Tx) error {
+		c, err := lockActiveCart(ctx, tx)
+		if err != nil {
+			return err
+		}
+		items, err := listItems(ctx, tx, c.ID)
+		if err != nil {
+			return err
+		}
+		if len(items) == 0 {
+			return ErrCartEmpty
+		}
+		c.setItems(items)
+		if len(c.Totals) > 1 {
+			return payments.ErrMixedCurrency
+		}
+		for _, it := range lockOrder(items) {
+			var answers json.RawMessage
+			if it.Answers != nil {
+				answers = json.RawMessage(*it.Answers)
+			}
+			for i := 0; i < it.Quantity; i++ {
+				b, err := s.bookings.CreateBookingTx(ctx, tx, bookings.NewBooking{
+					UserName:     userName,
+					Event:        it.Event,
+					TicketTypeID: it.TicketTypeID,
+					Answers:      answers,
+				})
+				if err != nil {
+					return fmt.Errorf("%s: %w", it.Event, err)
+				}
+				co.Bookings = append(co.Bookings, b)
+			}
+		}
+		err = tx.GetContext(ctx, &c, `UPDATE carts SET status = 'checked_out', updated_at = now()
+              WHERE id = $1 RETURNING *`, c.ID)
+		c.setItems(items)
+		co.Cart = c
+		return err
+	})
+	if err != nil {
+		return Checkout{}, err
+	}
+
+	var due []int
+	for _, b := range co.Bookings {
+		if b.Status == bookings.StatusPendingPayment {
+			due = append(due, b.ID)
+		}
+	}
+	if len(due) == 0 {
+		return co, nil
+	}
+	p, err := s.payments.Start(ctx, due)
+	if err != nil {
+		log.Printf("Failed to start payment for cart %d: %v", co.Cart.ID, err)
+		co.PaymentError = err.Error()
+		return co, nil
+	}
+	co.Payment = &p
+	return co, nil
+}
+
+// touchCart returns the caller's active cart, locked and with its expiry
+// pushed back, creating it if needed. An expired cart is closed first so
+// its stale items are not revived.
+func touchCart(ctx context.Context, tx *sqlx.Tx) (Cart, error) {
+	userID, ok := middleware.UserIDFromContext(ctx)
+	if !ok {
+		return Cart{}, ErrUnauthorized
+	}
+	if err := expireCarts(ctx, tx, userID); err != nil {
+		return Cart{}, err
+	}
+	var c Cart
+	err := tx.GetContext(ctx, &c, `INSERT INTO carts (user_id, expires_at) VALUES ($1, $2)
+              ON CONFLICT (user_id) WHERE status = 'active'
+              DO UPDATE SET expires_at = EXCLUDED.expires_at, updated_at = now()
+              RETURNING *`, userID, time.Now().Add(TTL))
+	return c, err
+}
+
+// lockActiveCart locks the caller's cart if it has one that has not expired
+func lockActiveCart(ctx context.Context, tx *sqlx.Tx) (Cart, error) {
+	userID, ok := middleware.UserIDFromContext(ctx)
+	if !ok {
+		return Cart{}, ErrUnauthorized
+	}
+	if err := expireCarts(ctx, tx, userID); err != nil {
+		return Cart{}, err
+	}
+	var c Cart
+	err := tx.GetContext(ctx, &c, "SELECT * FROM carts WHERE user_id = $1 AND status = 'active' FOR UPDATE", userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Cart{}, ErrCartNotFound
+	}
+	return c, err
+}
+
+func expireCarts(ctx context.Context, tx *sqlx.Tx, userID int) error {
+	_, err := tx.ExecContext(ctx, `UPDATE carts SET status = 'expired', updated_at = now()
+              WHERE user_id = $1 AND status = 'active' AND expires_at <= now()`, userID)
+	return err
+}
+
+func listItems(ctx context.Context, q sqlx.QueryerContext, cartID int) ([]Item, error) {
+	items := []Item{}
+	err := sqlx.SelectContext(ctx, q, &items, `SELECT i.*, t.price AS unit_price, t.currency
+              FROM cart_items i LEFT JOIN ticket_types t ON t.id = i.ticket_type_id
+              WHERE i.cart_id = $1 ORDER BY i.id`, cartID)
+	return items, err
+}
@@ -0,0 +1,127 @@
+package carts
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"booking-app/internal/bookings"
+	"booking-app/internal/events"
+	"booking-app/internal/payments"
+	"booking-app/internal/rules"
+	"booking-app/internal/waitingroom"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+)
+
+var validate = validator.New()
+
+type Handler struct {
+	store *DBStore
+}
+
+func NewHandler(store *DBStore) *Handler {
+	return &Handler{store: store}
+}
+
+func (h *Handler) GetHandler(w http.ResponseWriter, r *http.Request) {
+	c, err := h.store.Get(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, c)
+}
+
+func (h *Handler) AddItemHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Event        string `json:"event" validate:"required"`
+		TicketTypeID *int   `json:"ticket_type_id"`
+		Quantity     int    `json:"quantity" validate:"required,min=1"`
+		// Answers respond to the event's attendee questions
+		Answers json.RawMessage `json:"answers"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := validate.Struct(&input); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	c, err := h.store.AddItem(r.Context(), NewItem{Event: input.Event, TicketTypeID: input.TicketTypeID, Quantity: input.Quantity,
+		Answers: input.Answers})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, c)
+}
+
+func (h *Handler) RemoveItemHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	c, err := h.store.RemoveItem(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, c)
+}
+
+func (h *Handler) CheckoutHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		UserName string `json:"user_name" validate:"required"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := validate.Struct(&input); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	co, err := h.store.Checkout(r.Context(), input.UserName)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, co)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrCartNotFound), errors.Is(err, ErrItemNotFound), errors.Is(err, events.ErrTicketTypeNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrUnauthorized):
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+	case errors.Is(err, waitingroom.ErrNotAdmitted):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, events.ErrEventFull), errors.Is(err, events.ErrSoldOut), errors.Is(err, events.ErrNotOnSale),
+		errors.Is(err, rules.ErrNotBookable),
+		errors.Is(err, bookings.ErrLimitReached), errors.Is(err, bookings.ErrOverlap):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, bookings.ErrInvalidAnswers):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	case errors.Is(err, ErrCartEmpty), errors.Is(err, ErrTooMany), errors.Is(err, payments.ErrMixedCurrency),
+		errors.Is(err, events.ErrTicketTypeRequired):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		log.Printf("Cart error: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
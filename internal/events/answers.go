@@ -0,0 +1,118 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx/types"
+)
+
+// AttendeeAnswers are the answers given with one active booking of an event
+type AttendeeAnswers struct {
+	BookingID int            `json:"booking_id" db:"id"`
+	UserName  string         `json:"user_name" db:"user_name"`
+	UserID    *int           `json:"user_id,omitempty" db:"user_id"`
+	Status    string         `json:"status" db:"status"`
+	CreatedAt time.Time      `json:"created_at" db:"created_at"`
+	Answers   types.JSONText `json:"answers" db:"answers"`
+}
+
+// Answers returns the attendee answers of an event's active bookings,
+// oldest booking first
+func (s *DBStore) Answers(ctx context.Context, e Event) ([]AttendeeAnswers, error) {
+	list := []AttendeeAnswers{}
+	err := s.db.SelectContext(ctx, &list, `SELECT id, user_name, user_id, status, created_at, answers
+              FROM bookings WHERE event = $1 AND is_active AND answers IS NOT NULL ORDER BY id`, e.Name)
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// writeAnswersCSV writes one row per booking, with a column per question.
+// Questions are the top-level properties of the schema, or, for answers
+// given under a schema since removed, the fields found in the answers.
+// Text answers are written as they are and others as JSON. Text that a
+// spreadsheet would run as a formula is escaped, see safeText.
+func writeAnswersCSV(w io.Writer, questions []string, list []AttendeeAnswers) error {
+	if questions == nil {
+		questions = answeredFields(list)
+	}
+	out := csv.NewWriter(w)
+	header := []string{"booking_id", "user_name", "status", "created_at"}
+	for _, q := range questions {
+		header = append(header, safeText(q))
+	}
+	if err := out.Write(header); err != nil {
+		return err
+	}
+	for _, a := range list {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(a.Answers, &fields); err != nil {
+			fields = nil
+		}
+		row := []string{strconv.Itoa(a.BookingID), safeText(a.UserName), a.Status, a.CreatedAt.UTC().Format(time.RFC3339)}
+		for _, q := range questions {
+			row = append(row, cell(fields[q]))
+		}
+		if err := out.Write(row); err != nil {
+			return err
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
+
+func answeredFields(list []AttendeeAnswers) []string {
+	seen := map[string]bool{}
+	for _, a := range list {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(a.Answers, &fields); err != nil {
+			continue
+		}
+		for name := range fields {
+			seen[name] = true
+		}
+	}
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// cell formats one answer for a CSV cell: empty if missing or null, the
+// text of strings and compact JSON otherwise
+func cell(raw json.RawMessage) string {
+	if len(raw) == 0 || string(raw) == "null" {
+		return ""
+	}
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return safeText(text)
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, raw); err != nil {
+		return string(raw)
+	}
+	return compact.String()
+}
+
+// safeText keeps spreadsheets from running customer text as a formula by
+// prefixing a quote to text starting with a character that begins one.
+// JSON cells need no escaping: they start with a bracket, a digit, a sign
+// of a number or a literal.
+func safeText(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
@@ -0,0 +1,57 @@
+package events
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestWriteAnswersCSV(t *testing.T) {
+	at := time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC)
+	list := []AttendeeAnswers{
+		{BookingID: 1, UserName: "ann", Status: "confirmed", CreatedAt: at,
+			Answers: []byte(`{"diet": "vegan", "shirt": "M", "contact": {"name": "Bob", "phone": "123"}}`)},
+		{BookingID: 2, UserName: "bo, jr", Status: "pending_payment", CreatedAt: at,
+			Answers: []byte(`{"diet": null, "tags": ["a", "b"], "age": 30}`)},
+	}
+	tests := []struct {
+		name      string
+		questions []string
+		want      string
+	}{
+		{"schema order", []string{"diet", "contact"},
+			"booking_id,user_name,status,created_at,diet,contact\n" +
+				"1,ann,confirmed,2026-03-01T09:30:00Z,vegan,\"{\"\"name\"\":\"\"Bob\"\",\"\"phone\"\":\"\"123\"\"}\"\n" +
+				"2,\"bo, jr\",pending_payment,2026-03-01T09:30:00Z,,\n"},
+		{"without schema", nil,
+			"booking_id,user_name,status,created_at,age,contact,diet,shirt,tags\n" +
+				"1,ann,confirmed,2026-03-01T09:30:00Z,,\"{\"\"name\"\":\"\"Bob\"\",\"\"phone\"\":\"\"123\"\"}\",vegan,M,\n" +
+				"2,\"bo, jr\",pending_payment,2026-03-01T09:30:00Z,30,,,,\"[\"\"a\"\",\"\"b\"\"]\"\n"},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := writeAnswersCSV(&buf, tt.questions, list); err != nil {
+			t.Fatalf("%s: writeAnswersCSV failed: %v", tt.name, err)
+		}
+		if got := buf.String(); got != tt.want {
+			t.Errorf("%s: Expected\n%s\ngot\n%s", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestWriteAnswersCSVEscapesFormulas(t *testing.T) {
+	at := time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC)
+	list := []AttendeeAnswers{
+		{BookingID: 1, UserName: "@SUM(A1)", Status: "confirmed", CreatedAt: at,
+			Answers: []byte(`{"diet": "=HYPERLINK(\"x\")", "note": "+1", "age": -3, "dash": "-x"}`)},
+	}
+	want := "booking_id,user_name,status,created_at,age,dash,diet,note\n" +
+		"1,'@SUM(A1),confirmed,2026-03-01T09:30:00Z,-3,'-x,\"'=HYPERLINK(\"\"x\"\")\",'+1\n"
+	var buf bytes.Buffer
+	if err := writeAnswersCSV(&buf, nil, list); err != nil {
+		t.Fatalf("writeAnswersCSV failed: %v", err)
+	}
+	if got := buf.String(); got != want {
+		t.Errorf("Expected\n%s\ngot\n%s", want, got)
+	}
+}
@@ -0,0 +1,49 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var ErrBlackoutNotFound = errors.New("blackout not found")
+
+// Blackout is a period in which an event cannot be booked if it starts
+// within it, such as a holiday
+type Blackout struct {
+	ID        int       `json:"id" db:"id"`
+	EventID   int       `json:"event_id" db:"event_id"`
+	StartsAt  time.Time `json:"starts_at" db:"starts_at" validate:"required"`
+	EndsAt    time.Time `json:"ends_at" db:"ends_at" validate:"required,gtfield=StartsAt"`
+	Reason    string    `json:"reason" db:"reason" validate:"max=255"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+func (s *DBStore) CreateBlackout(ctx context.Context, b Blackout) (Blackout, error) {
+	err := s.db.GetContext(ctx, &b, `INSERT INTO event_blackouts (event_id, starts_at, ends_at, reason)
+              VALUES ($1, $2, $3, $4) RETURNING *`, b.EventID, b.StartsAt, b.EndsAt, b.Reason)
+	return b, err
+}
+
+// ListBlackouts returns an event's blackouts that have not ended yet
+func (s *DBStore) ListBlackouts(ctx context.Context, eventID int) ([]Blackout, error) {
+	list := []Blackout{}
+	err := s.db.SelectContext(ctx, &list, `SELECT * FROM event_blackouts
+              WHERE event_id = $1 AND ends_at > now() ORDER BY starts_at`, eventID)
+	return list, err
+}
+
+func (s *DBStore) DeleteBlackout(ctx context.Context, eventID, id int) error {
+	res, err := s.db.ExecContext(ctx, "DELETE FROM event_blackouts WHERE id = $1 AND event_id = $2", id, eventID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrBlackoutNotFound
+	}
+	return nil
+}
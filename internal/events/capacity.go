@@ -0,0 +1,52 @@
+package events
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"booking-app/internal/overbooking"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var ErrEventFull = errors.New("event is full")
+
+// ReserveCapacity checks that quantity more places fit on the named event,
+// including its overbooking allowance, and returns how many of them go
+// beyond its capacity. It locks the event row, so concurrent reservations
+// for the same event are serialized until tx ends; the caller must write
+// its booking or hold in tx. Active bookings and unexpired active holds
+// count against capacity.
+func ReserveCapacity(ctx context.Context, tx *sqlx.Tx, name string, quantity int) (oversold int, err error) {
+	var e struct {
+		Capacity *int `db:"capacity"`
+		overbooking.Policy
+	}
+	err = tx.GetContext(ctx, &e, "SELECT capacity, overbook_percent, overbook_limit FROM events WHERE name = $1 FOR UPDATE", name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if e.Capacity == nil {
+		return 0, nil
+	}
+	used, err := usedPlaces(ctx, tx, name)
+	if err != nil {
+		return 0, err
+	}
+	if used+quantity > *e.Capacity+e.Allowance(*e.Capacity) {
+		return 0, ErrEventFull
+	}
+	return overbooking.Oversold(*e.Capacity, used, quantity), nil
+}
+
+func usedPlaces(ctx context.Context, q sqlx.QueryerContext, name string) (int, error) {
+	var used int
+	err := sqlx.GetContext(ctx, q, &used, `SELECT
+              (SELECT count(*) FROM bookings WHERE event = $1 AND is_active) +
+              (SELECT coalesce(sum(quantity), 0) FROM holds WHERE event = $1 AND status = 'active' AND expires_at > now())`, name)
+	return used, err
+}
@@ -0,0 +1,238 @@
+package events
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"booking-app/internal/database"
+	"booking-app/internal/middleware"
+	"booking-app/internal/outbox"
+	"booking-app/internal/timezone"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrEventNotFound = errors.New("event not found")
+	ErrEventExists   = errors.New("event already exists")
+	ErrInvalidRef    = errors.New("event settings refer to a record that does not exist")
+	ErrForeignMap    = errors.New("seat map belongs to another organizer")
+)
+
+// DBStore manages events in PostgreSQL
+type DBStore struct {
+	db *sqlx.DB
+}
+
+func NewDBStore(db *sqlx.DB) *DBStore {
+	return &DBStore{db: db}
+}
+
+// settingsColumns lists the columns backing Settings
+var settingsColumns = []string{
+	"capacity",
+	"waitlist_claim_minutes",
+	"seat_map_id",
+	"starts_at",
+	"ends_at",
+	"max_per_user",
+	"full_refund_hours",
+	"cancellation_fee_percent",
+	"time_zone",
+	"min_notice_minutes",
+	"max_advance_days",
+	"cutoff_time",
+	"cutoff_days_before",
+	"overbook_percent",
+	"overbook_limit",
+	"requires_approval",
+	"approval_hours",
+	"attendee_schema",
+}
+
+// settingsSQL returns the settings column list and matching named parameters
+func settingsSQL() (columns, params string) {
+	return strings.Join(settingsColumns, ", "), ":" + strings.Join(settingsColumns, ", :")
+}
+
+func (s *DBStore) CreateEvent(ctx context.Context, e Event) (Event, error) {
+	var created Event
+	if e.TimeZone == "" {
+		e.TimeZone = timezone.Default
+	}
+	err := database.WithTx(ctx, s.db, func(tx *sqlx.Tx) error {
+		if err := ClaimNameTx(ctx, tx, e.Name); err != nil {
+			return err
+		}
+		// Bookings made under a name before its event existed would come
+		// under the new event's organizer, so only admins may create events
+		// for them.
+		if !middleware.IsAdmin(ctx) {
+			var taken bool
+			if err := tx.GetContext(ctx, &taken, "SELECT EXISTS (SELECT 1 FROM bookings WHERE event = $1)", e.Name); err != nil {
+				return err
+			}
+			if taken {
+				return ErrEventExists
+			}
+		}
+		columns, params := settingsSQL()
+		query := `INSERT INTO events (name, organizer_id, ` + columns + `)
+              VALUES (:name, :organizer_id, ` + params + `)
+              ON CONFLICT (name) DO NOTHING RETURNING *`
+		err := namedGet(ctx, tx, &created, query, e)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrEventExists
+		}
+		return invalidRef(err)
+	})
+	if err != nil {
+		return Event{}, err
+	}
+	created.Localize()
+	return created, nil
+}
+
+// nameLockClass namespaces the advisory locks taken on event names
+const nameLockClass = 4602
+
+// ClaimNameTx locks a name for an event or resource about to be created
+// until tx ends, failing with ErrEventExists if an event or a resource
+// already has it. Appointments are booked under their resource's name, so
+// events and resources share one namespace.
+func ClaimNameTx(ctx context.Context, tx *sqlx.Tx, name string) error {
+	if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1, hashtext($2))", nameLockClass, name); err != nil {
+		return err
+	}
+	var taken bool
+	err := tx.GetContext(ctx, &taken, `SELECT EXISTS (SELECT 1 FROM events WHERE name = $1)
+              OR EXISTS (SELECT 1 FROM resources WHERE name = $1)`, name)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return ErrEventExists
+	}
+	return nil
+}
+
+func (s *DBStore) GetEvent(ctx context.Context, id int) (Event, error) {
+	var e Event
+	err := s.db.GetContext(ctx, &e, "SELECT * FROM events WHERE id = $1", id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Event{}, ErrEventNotFound
+	}
+	e.Localize()
+	return e, err
+}
+
+func (s *DBStore) GetEventByName(ctx context.Context, name string) (Event, error) {
+	var e Event
+	err := s.db.GetContext(ctx, &e, "SELECT * FROM events WHERE name = $1", name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Event{}, ErrEventNotFound
+	}
+	e.Localize()
+	return e, err
+}
+
+func (s *DBStore) ListEvents(ctx context.Context) ([]Event, error) {
+	var list []Event
+	err := s.db.SelectContext(ctx, &list, "SELECT * FROM events ORDER BY id")
+	for i := range list {
+		list[i].Localize()
+	}
+	return list, err
+}
+
+// UpdateSettings replaces an event's settings and records the change in the
+// outbox, since more capacity may let waiting customers in.
+func (s *DBStore) UpdateSettings(ctx context.Context, id int, settings Settings) (Event, error) {
+	if settings.TimeZone == "" {
+		settings.TimeZone = timezone.Default
+	}
+	e := Event{ID: id, Settings: settings}
+	err := database.WithTx(ctx, s.db, func(tx *sqlx.Tx) error {
+		columns, params := settingsSQL()
+		query := `UPDATE events SET (` + columns + `) = ROW(` + params + `), updated_at = now()
+              WHERE id = :id RETURNING *`
+		if err := namedGet(ctx, tx, &e, query, e); err != nil {
+			return err
+		}
+		return outbox.Enqueue(ctx, tx, outbox.Message{Type: EventUpdated, AggregateType: "event", AggregateID: int64(id), Data: e})
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return Event{}, ErrEventNotFound
+	}
+	e.Localize()
+	return e, invalidRef(err)
+}
+
+// CheckSeatMap allows admins to seat an event with any seat map and others
+// only with one they imported. Unknown maps are left to the foreign key.
+func (s *DBStore) CheckSeatMap(ctx context.Context, id *int) error {
+	if id == nil || middleware.IsAdmin(ctx) {
+		return nil
+	}
+	var owner *int
+	err := s.db.GetContext(ctx, &owner, "SELECT owner_id FROM seat_maps WHERE id = $1", *id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	userID, ok := middleware.UserIDFromContext(ctx)
+	if owner == nil || !ok || *owner != userID {
+		return ErrForeignMap
+	}
+	return nil
+}
+
+// invalidRef translates foreign key violations into ErrInvalidRef
+func invalidRef(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+		return fmt.Errorf("%w: %s", ErrInvalidRef, pqErr.Constraint)
+	}
+	return err
+}
+
+// namedGet runs a named query and scans its single result row into dest
+func namedGet(ctx context.Context, e sqlx.ExtContext, dest interface{}, query string, arg interface{}) error {
+	rows, err := sqlx.NamedQueryContext(ctx, e, query, arg)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Printf("Failed to close rows: %v", err)
+		}
+	}()
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		return sql.ErrNoRows
+	}
+	return rows.StructScan(dest)
+}
+
+// Availability returns how many places are taken and, for capped events, how
+// many can still be sold, counting the overbooking allowance
+func (s *DBStore) Availability(ctx context.Context, e Event) (used int, remaining *int, err error) {
+	used, err = usedPlaces(ctx, s.db, e.Name)
+	if err != nil || e.Capacity == nil {
+		return used, nil, err
+	}
+	left := *e.Capacity + e.Allowance(*e.Capacity) - used
+	if left < 0 {
+		left = 0
+	}
+	return used, &left, nil
+}
@@ -0,0 +1,93 @@
+package events
+
+import (
+	"errors"
+	"time"
+
+	"booking-app/internal/jsonschema"
+	"booking-app/internal/overbooking"
+	"booking-app/internal/rules"
+	"booking-app/internal/timezone"
+
+	"github.com/jmoiron/sqlx/types"
+)
+
+// Event holds the settings of a bookable event. Bookings and holds refer to
+// events by name; events without a row here have no capacity limit.
+type Event struct {
+	ID          int       `json:"id" db:"id"`
+	Name        string    `json:"name" db:"name"`
+	OrganizerID *int      `json:"organizer_id,omitempty" db:"organizer_id"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+	// SeriesID and OccurrenceAt are set for occurrences of a recurring
+	// event. OccurrenceAt is when the rule scheduled it; changing StartsAt
+	// overrides that for this occurrence only.
+	SeriesID     *int       `json:"series_id,omitempty" db:"series_id"`
+	OccurrenceAt *time.Time `json:"occurrence_at,omitempty" db:"occurrence_at"`
+	// StartsAtLocal is StartsAt in the venue's time zone, set by Localize
+	StartsAtLocal *time.Time `json:"starts_at_local,omitempty" db:"-"`
+	Settings
+}
+
+// Localize sets the venue-local times of an event loaded from the database
+func (e *Event) Localize() {
+	e.StartsAtLocal = timezone.In(e.StartsAt, e.TimeZone)
+}
+
+// Settings are the organizer-controlled options of an event.
+//
+// The cancellation policy gives a full refund until FullRefundHours before
+// StartsAt, then keeps CancellationFeePercent of the price until the event
+// starts, after which nothing is refunded. Without a cut-off the full refund
+// lasts until the start; a cut-off without a fee means no refund after it.
+//
+// The booking rules count from StartsAt, so they only apply to events with
+// a start time. MaxPerUser caps the places one user can book or hold. The
+// overbooking policy allows selling places beyond Capacity. Bookings of
+// events that RequiresApproval wait for the organizer to approve them, for
+// ApprovalHours or 72 hours if not given. AttendeeSchema is a JSON Schema
+// that the answers given with each booking must satisfy.
+type Settings struct {
+	Capacity               *int       `json:"capacity" db:"capacity" validate:"omitempty,min=0"`
+	WaitlistClaimMinutes   *int       `json:"waitlist_claim_minutes" db:"waitlist_claim_minutes" validate:"omitempty,min=1,max=1440"`
+	SeatMapID              *int       `json:"seat_map_id" db:"seat_map_id"`
+	StartsAt               *time.Time `json:"starts_at" db:"starts_at"`
+	EndsAt                 *time.Time `json:"ends_at" db:"ends_at"`
+	MaxPerUser             *int       `json:"max_per_user" db:"max_per_user" validate:"omitempty,min=1"`
+	FullRefundHours        *int       `json:"full_refund_hours" db:"full_refund_hours" validate:"omitempty,min=0"`
+	CancellationFeePercent *int       `json:"cancellation_fee_percent" db:"cancellation_fee_percent" validate:"omitempty,min=0,max=100"`
+	// TimeZone is the IANA time zone of the venue, UTC if not given
+	TimeZone string `json:"time_zone" db:"time_zone" validate:"omitempty,timezone"`
+
+	RequiresApproval bool `json:"requires_approval" db:"requires_approval"`
+	ApprovalHours    *int `json:"approval_hours" db:"approval_hours" validate:"omitempty,min=1,max=720"`
+
+	AttendeeSchema *types.JSONText `json:"attendee_schema" db:"attendee_schema"`
+	rules.Rules
+	overbooking.Policy
+}
+
+// ErrInvalidTimes is returned for an event that ends before it starts
+var ErrInvalidTimes = errors.New("ends_at must be after starts_at")
+
+// CheckTimes rejects an event that ends before it starts. The times are only
+// compared when both are set, since either can be left out.
+func (s Settings) CheckTimes() error {
+	if s.StartsAt != nil && s.EndsAt != nil && !s.EndsAt.After(*s.StartsAt) {
+		return ErrInvalidTimes
+	}
+	return nil
+}
+
+// Questions compiles the attendee schema, returning nil if the event asks
+// no questions
+func (s Settings) Questions() (*jsonschema.Schema, error) {
+	if s.AttendeeSchema == nil {
+		return nil, nil
+	}
+	return jsonschema.Compile(*s.AttendeeSchema)
+}
+
+// EventUpdated is written to the outbox when an event's settings change
+const EventUpdated = "event.updated"
@@ -0,0 +1,32 @@
+package events
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSettingsCheckTimes(t *testing.T) {
+	start := time.Date(2026, 5, 1, 18, 0, 0, 0, time.UTC)
+	end := start.Add(2 * time.Hour)
+	tests := []struct {
+		name       string
+		start, end *time.Time
+		wantErr    bool
+	}{
+		{"no times", nil, nil, false},
+		{"only start", &start, nil, false},
+		{"only end", nil, &end, false},
+		{"ends after start", &start, &end, false},
+		{"ends before start", &end, &start, true},
+		{"ends at start", &start, &start, true},
+	}
+	for _, tt := range tests {
+		settings := Settings{StartsAt: tt.start, EndsAt: tt.end}
+		if err := validate.Struct(&settings); err != nil {
+			t.Errorf("%s: validate: %v", tt.name, err)
+		}
+		if err := settings.CheckTimes(); (err != nil) != tt.wantErr {
+			t.Errorf("%s: CheckTimes = %v, want error %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,420 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"booking-app/internal/middleware"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+)
+
+var validate = validator.New()
+
+type Handler struct {
+	store *DBStore
+}
+
+func NewHandler(store *DBStore) *Handler {
+	return &Handler{store: store}
+}
+
+// CanManage reports whether the authenticated user may change an event's
+// settings: admins and the event's organizer.
+func CanManage(ctx context.Context, e Event) bool {
+	if middleware.IsAdmin(ctx) {
+		return true
+	}
+	userID, ok := middleware.UserIDFromContext(ctx)
+	return ok && e.OrganizerID != nil && *e.OrganizerID == userID
+}
+
+// CanCreate reports whether the authenticated user may create events:
+// admins and organizers. Bookings refer to events by name, so whoever
+// creates an event controls every booking made under that name.
+func CanCreate(ctx context.Context) bool {
+	role := middleware.RoleFromContext(ctx)
+	return role == middleware.RoleAdmin || role == middleware.RoleOrganizer
+}
+
+// CreateEvent creates an event organized by the authenticated user
+func (h *Handler) CreateEvent(w http.ResponseWriter, r *http.Request) {
+	if !CanCreate(r.Context()) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	var input struct {
+		Name string `json:"name" validate:"required,max=255"`
+		Settings
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := validate.Struct(&input); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := input.CheckTimes(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, err := input.Questions(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := h.store.CheckSeatMap(r.Context(), input.SeatMapID); err != nil {
+		writeSeatMapError(w, err)
+		return
+	}
+	e := Event{Name: input.Name, Settings: input.Settings}
+	if userID, ok := middleware.UserIDFromContext(r.Context()); ok {
+		e.OrganizerID = &userID
+	}
+	e, err := h.store.CreateEvent(r.Context(), e)
+	if errors.Is(err, ErrEventExists) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if errors.Is(err, ErrInvalidRef) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to create event", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusCreated, e)
+}
+
+func (h *Handler) ListEvents(w http.ResponseWriter, r *http.Request) {
+	list, err := h.store.ListEvents(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to fetch events", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// GetEvent returns an event together with its current availability
+func (h *Handler) GetEvent(w http.ResponseWriter, r *http.Request) {
+	e, ok := h.loadEvent(w, r)
+	if !ok {
+		return
+	}
+	used, remaining, err := h.store.Availability(r.Context(), e)
+	if err != nil {
+		http.Error(w, "Failed to compute availability", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, struct {
+		Event
+		Used      int  `json:"used"`
+		Remaining *int `json:"remaining"`
+	}{e, used, remaining})
+}
+
+// UpdateEvent changes an event's settings. Fields missing from the body keep
+// their current value and explicit nulls clear them, e.g. a null capacity
+// removes the limit.
+func (h *Handler) UpdateEvent(w http.ResponseWriter, r *http.Request) {
+	e, ok := h.loadEvent(w, r)
+	if !ok {
+		return
+	}
+	if !CanManage(r.Context(), e) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	settings := e.Settings
+	if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := validate.Struct(&settings); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := settings.CheckTimes(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, err := settings.Questions(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if settings.SeatMapID != nil && (e.SeatMapID == nil || *e.SeatMapID != *settings.SeatMapID) {
+		if err := h.store.CheckSeatMap(r.Context(), settings.SeatMapID); err != nil {
+			writeSeatMapError(w, err)
+			return
+		}
+	}
+	e, err := h.store.UpdateSettings(r.Context(), e.ID, settings)
+	if errors.Is(err, ErrInvalidRef) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to update event", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, e)
+}
+
+// CreateTicketType adds a ticket type to an event the caller manages
+func (h *Handler) CreateTicketType(w http.ResponseWriter, r *http.Request) {
+	e, ok := h.loadEvent(w, r)
+	if !ok {
+		return
+	}
+	if !CanManage(r.Context(), e) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	var input struct {
+		Name string `json:"name" validate:"required,max=64"`
+		TicketTypeSettings
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := validate.Struct(&input); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := input.CheckWindow(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	t, err := h.store.CreateTicketType(r.Context(), TicketType{EventID: e.ID, Name: input.Name, TicketTypeSettings: input.TicketTypeSettings})
+	if errors.Is(err, ErrTicketTypeExists) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to create ticket type", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusCreated, t)
+}
+
+func (h *Handler) ListTicketTypes(w http.ResponseWriter, r *http.Request) {
+	e, ok := h.loadEvent(w, r)
+	if !ok {
+		return
+	}
+	list, err := h.store.ListTicketTypes(r.Context(), e.ID)
+	if err != nil {
+		http.Error(w, "Failed to fetch ticket types", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// UpdateTicketType changes a ticket type's price, quantity or sales window.
+// Like UpdateEvent, fields missing from the body keep their current value.
+func (h *Handler) UpdateTicketType(w http.ResponseWriter, r *http.Request) {
+	e, ok := h.loadEvent(w, r)
+	if !ok {
+		return
+	}
+	if !CanManage(r.Context(), e) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	typeID, err := strconv.Atoi(mux.Vars(r)["ticketTypeId"])
+	if err != nil {
+		http.Error(w, "Invalid ticket type ID", http.StatusBadRequest)
+		return
+	}
+	t, err := h.store.GetTicketType(r.Context(), e.ID, typeID)
+	if errors.Is(err, ErrTicketTypeNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to fetch ticket type", http.StatusInternalServerError)
+		return
+	}
+	if err := json.NewDecoder(r.Body).Decode(&t.TicketTypeSettings); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := validate.Struct(&t.TicketTypeSettings); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := t.CheckWindow(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	t, err = h.store.UpdateTicketType(r.Context(), t)
+	if err != nil {
+		http.Error(w, "Failed to update ticket type", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, t)
+}
+
+// CreateBlackout stops bookings of an event that starts within a period
+func (h *Handler) CreateBlackout(w http.ResponseWriter, r *http.Request) {
+	e, ok := h.loadEvent(w, r)
+	if !ok {
+		return
+	}
+	if !CanManage(r.Context(), e) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	var b Blackout
+	if err := json.NewDecoder(r.Body).Decode(&b); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := validate.Struct(&b); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	b.EventID = e.ID
+	b, err := h.store.CreateBlackout(r.Context(), b)
+	if err != nil {
+		http.Error(w, "Failed to create blackout", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusCreated, b)
+}
+
+func (h *Handler) ListBlackouts(w http.ResponseWriter, r *http.Request) {
+	e, ok := h.loadEvent(w, r)
+	if !ok {
+		return
+	}
+	list, err := h.store.ListBlackouts(r.Context(), e.ID)
+	if err != nil {
+		http.Error(w, "Failed to fetch blackouts", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// OverbookingReport shows an event's organizer how far it was oversold and
+// whether check-ins went beyond its capacity
+func (h *Handler) OverbookingReport(w http.ResponseWriter, r *http.Request) {
+	e, ok := h.loadEvent(w, r)
+	if !ok {
+		return
+	}
+	if !CanManage(r.Context(), e) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	report, err := h.store.OverbookingReport(r.Context(), e)
+	if err != nil {
+		http.Error(w, "Failed to compute overbooking report", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+// ExportAnswers gives an event's organizer the answers its attendees gave
+// to its questions, as JSON or, with format=csv, as a spreadsheet
+func (h *Handler) ExportAnswers(w http.ResponseWriter, r *http.Request) {
+	e, ok := h.loadEvent(w, r)
+	if !ok {
+		return
+	}
+	if !CanManage(r.Context(), e) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "csv" {
+		http.Error(w, "format must be json or csv", http.StatusBadRequest)
+		return
+	}
+	list, err := h.store.Answers(r.Context(), e)
+	if err != nil {
+		http.Error(w, "Failed to fetch attendee answers", http.StatusInternalServerError)
+		return
+	}
+	if format != "csv" {
+		writeJSON(w, http.StatusOK, list)
+		return
+	}
+	var questions []string
+	if schema, err := e.Questions(); err == nil && schema != nil {
+		questions = schema.Properties()
+	}
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": e.Name + "-answers.csv"}))
+	if err := writeAnswersCSV(w, questions, list); err != nil {
+		log.Printf("Failed to write attendee answers of event %d: %v", e.ID, err)
+	}
+}
+
+func (h *Handler) DeleteBlackout(w http.ResponseWriter, r *http.Request) {
+	e, ok := h.loadEvent(w, r)
+	if !ok {
+		return
+	}
+	if !CanManage(r.Context(), e) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	id, err := strconv.Atoi(mux.Vars(r)["blackoutId"])
+	if err != nil {
+		http.Error(w, "Invalid blackout ID", http.StatusBadRequest)
+		return
+	}
+	err = h.store.DeleteBlackout(r.Context(), e.ID, id)
+	if errors.Is(err, ErrBlackoutNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to delete blackout", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeSeatMapError(w http.ResponseWriter, err error) {
+	if errors.Is(err, ErrForeignMap) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	http.Error(w, "Failed to check seat map", http.StatusInternalServerError)
+}
+
+func (h *Handler) loadEvent(w http.ResponseWriter, r *http.Request) (Event, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return Event{}, false
+	}
+	e, err := h.store.GetEvent(r.Context(), id)
+	if errors.Is(err, ErrEventNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return Event{}, false
+	}
+	if err != nil {
+		http.Error(w, "Failed to fetch event", http.StatusInternalServerError)
+		return Event{}, false
+	}
+	return e, true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
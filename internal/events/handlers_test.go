@@ -0,0 +1,26 @@
+package events
+
+import (
+	"context"
+	"testing"
+
+	"booking-app/internal/middleware"
+)
+
+func TestCanCreate(t *testing.T) {
+	tests := []struct {
+		role string
+		want bool
+	}{
+		{middleware.RoleAdmin, true},
+		{middleware.RoleOrganizer, true},
+		{"user", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		ctx := context.WithValue(context.Background(), middleware.RoleKey, tt.role)
+		if got := CanCreate(ctx); got != tt.want {
+			t.Errorf("CanCreate as %q = %v, want %v", tt.role, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package events
+
+import (
+	"context"
+	"time"
+
+	"booking-app/internal/overbooking"
+)
+
+// OverbookingReport compares what an event sold with its capacity and with
+// who showed up. Oversold counts active bookings beyond capacity, whatever
+// they were flagged as when sold, since cancellations free places;
+// OverCapacity counts check-ins beyond capacity.
+// NoShows is set once the event has started.
+type OverbookingReport struct {
+	Capacity     *int `json:"capacity"`
+	Allowance    int  `json:"allowance"`
+	Booked       int  `json:"booked"`
+	Oversold     int  `json:"oversold"`
+	CheckedIn    int  `json:"checked_in"`
+	OverCapacity int  `json:"over_capacity"`
+	NoShows      *int `json:"no_shows,omitempty"`
+}
+
+func (s *DBStore) OverbookingReport(ctx context.Context, e Event) (OverbookingReport, error) {
+	var counts struct {
+		Booked    int `db:"booked"`
+		CheckedIn int `db:"checked_in"`
+	}
+	err := s.db.GetContext(ctx, &counts, `SELECT count(*) AS booked,
+              count(checked_in_at) AS checked_in
+              FROM bookings WHERE event = $1 AND is_active`, e.Name)
+	if err != nil {
+		return OverbookingReport{}, err
+	}
+	report := OverbookingReport{Capacity: e.Capacity, Booked: counts.Booked, CheckedIn: counts.CheckedIn}
+	if e.Capacity != nil {
+		report.Allowance = e.Allowance(*e.Capacity)
+		report.Oversold = overbooking.Oversold(*e.Capacity, 0, report.Booked)
+		if over := report.CheckedIn - *e.Capacity; over > 0 {
+			report.OverCapacity = over
+		}
+	}
+	if e.StartsAt != nil && e.StartsAt.Before(time.Now()) {
+		noShows := report.Booked - report.CheckedIn
+		report.NoShows = &noShows
+	}
+	return report, nil
+}
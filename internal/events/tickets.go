@@ -0,0 +1,144 @@
+package events
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	ErrTicketTypeNotFound = errors.New("ticket type not found")
+	ErrTicketTypeExists   = errors.New("event already has a ticket type with this name")
+	ErrTicketTypeRequired = errors.New("a ticket type is required for this event")
+	ErrNotOnSale          = errors.New("ticket type is not on sale")
+	ErrSoldOut            = errors.New("ticket type is sold out")
+	ErrInvalidSalesWindow = errors.New("sales_end must be after sales_start")
+)
+
+// TicketType is a kind of ticket an event sells, such as adult or VIP.
+// Prices are in minor currency units (cents). Quantity caps how many tickets
+// of the type can be sold; nil means only the event capacity applies.
+type TicketType struct {
+	ID      int    `json:"id" db:"id"`
+	EventID int    `json:"event_id" db:"event_id"`
+	Name    string `json:"name" db:"name"`
+	TicketTypeSettings
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// TicketTypeSettings are the fields of a ticket type an organizer can change
+type TicketTypeSettings struct {
+	Price      int64      `json:"price" db:"price" validate:"min=0"`
+	Currency   string     `json:"currency" db:"currency" validate:"required,len=3,uppercase"`
+	Quantity   *int       `json:"quantity" db:"quantity" validate:"omitempty,min=0"`
+	SalesStart *time.Time `json:"sales_start" db:"sales_start"`
+	SalesEnd   *time.Time `json:"sales_end" db:"sales_end"`
+}
+
+// CheckWindow rejects a sales window that ends before it starts. Either
+// end may be left open, so the two are only compared when both are set.
+func (t TicketTypeSettings) CheckWindow() error {
+	if t.SalesStart != nil && t.SalesEnd != nil && !t.SalesEnd.After(*t.SalesStart) {
+		return ErrInvalidSalesWindow
+	}
+	return nil
+}
+
+// OnSale reports whether the ticket type can be sold at the given time
+func (t TicketType) OnSale(now time.Time) bool {
+	if t.SalesStart != nil && now.Before(*t.SalesStart) {
+		return false
+	}
+	return t.SalesEnd == nil || now.Before(*t.SalesEnd)
+}
+
+func (s *DBStore) CreateTicketType(ctx context.Context, t TicketType) (TicketType, error) {
+	var created TicketType
+	err := namedGet(ctx, s.db, &created, `INSERT INTO ticket_types (event_id, name, price, currency, quantity, sales_start, sales_end)
+              VALUES (:event_id, :name, :price, :currency, :quantity, :sales_start, :sales_end)
+              ON CONFLICT (event_id, name) DO NOTHING RETURNING *`, t)
+	if errors.Is(err, sql.ErrNoRows) {
+		return TicketType{}, ErrTicketTypeExists
+	}
+	return created, err
+}
+
+func (s *DBStore) GetTicketType(ctx context.Context, eventID, id int) (TicketType, error) {
+	var t TicketType
+	err := s.db.GetContext(ctx, &t, "SELECT * FROM ticket_types WHERE id = $1 AND event_id = $2", id, eventID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return TicketType{}, ErrTicketTypeNotFound
+	}
+	return t, err
+}
+
+func (s *DBStore) ListTicketTypes(ctx context.Context, eventID int) ([]TicketType, error) {
+	list := []TicketType{}
+	err := s.db.SelectContext(ctx, &list, "SELECT * FROM ticket_types WHERE event_id = $1 ORDER BY id", eventID)
+	return list, err
+}
+
+// UpdateTicketType changes the price, quantity and sales window of a ticket
+// type. Bookings already made keep the price they were sold at.
+func (s *DBStore) UpdateTicketType(ctx context.Context, t TicketType) (TicketType, error) {
+	var updated TicketType
+	err := namedGet(ctx, s.db, &updated, `UPDATE ticket_types
+              SET price = :price, currency = :currency, quantity = :quantity,
+                  sales_start = :sales_start, sales_end = :sales_end, updated_at = now()
+              WHERE id = :id RETURNING *`, t)
+	if errors.Is(err, sql.ErrNoRows) {
+		return TicketType{}, ErrTicketTypeNotFound
+	}
+	return updated, err
+}
+
+// ReserveTicket checks that quantity tickets of a type can be sold for the
+// named event and returns the type so the caller can record its price. The
+// ticket type row is locked until tx ends, serializing sales of the type.
+// When id is nil it returns nil, unless the event sells ticket types, in
+// which case choosing one is required. Active bookings and unexpired active
+// holds of the type count against its quantity.
+func ReserveTicket(ctx context.Context, tx *sqlx.Tx, event string, id *int, quantity int) (*TicketType, error) {
+	if id == nil {
+		var required bool
+		err := tx.GetContext(ctx, &required, `SELECT EXISTS (
+              SELECT 1 FROM ticket_types t JOIN events e ON e.id = t.event_id WHERE e.name = $1)`, event)
+		if err != nil {
+			return nil, err
+		}
+		if required {
+			return nil, ErrTicketTypeRequired
+		}
+		return nil, nil
+	}
+	var t TicketType
+	err := tx.GetContext(ctx, &t, `SELECT t.* FROM ticket_types t JOIN events e ON e.id = t.event_id
+              WHERE t.id = $1 AND e.name = $2 FOR UPDATE OF t`, *id, event)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrTicketTypeNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !t.OnSale(time.Now()) {
+		return nil, ErrNotOnSale
+	}
+	if t.Quantity == nil {
+		return &t, nil
+	}
+	var sold int
+	err = tx.GetContext(ctx, &sold, `SELECT
+              (SELECT count(*) FROM bookings WHERE ticket_type_id = $1 AND is_active) +
+              (SELECT coalesce(sum(quantity), 0) FROM holds WHERE ticket_type_id = $1 AND status = 'active' AND expires_at > now())`, t.ID)
+	if err != nil {
+		return nil, err
+	}
+	if sold+quantity > *t.Quantity {
+		return nil, ErrSoldOut
+	}
+	return &t, nil
+}
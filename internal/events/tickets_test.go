@@ -0,0 +1,57 @@
+package events
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTicketTypeOnSale(t *testing.T) {
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	before := now.Add(-time.Hour)
+	after := now.Add(time.Hour)
+	tests := []struct {
+		name       string
+		start, end *time.Time
+		want       bool
+	}{
+		{"no window", nil, nil, true},
+		{"started", &before, nil, true},
+		{"not started", &after, nil, false},
+		{"ended", nil, &before, false},
+		{"within window", &before, &after, true},
+		{"ends now", nil, &now, false},
+		{"starts now", &now, nil, true},
+	}
+	for _, tt := range tests {
+		ticket := TicketType{TicketTypeSettings: TicketTypeSettings{SalesStart: tt.start, SalesEnd: tt.end}}
+		if got := ticket.OnSale(now); got != tt.want {
+			t.Errorf("%s: OnSale = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTicketTypeCheckWindow(t *testing.T) {
+	start := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+	tests := []struct {
+		name       string
+		start, end *time.Time
+		wantErr    bool
+	}{
+		{"no window", nil, nil, false},
+		{"only start", &start, nil, false},
+		{"only end", nil, &end, false},
+		{"end after start", &start, &end, false},
+		{"end before start", &end, &start, true},
+		{"empty window", &start, &start, true},
+	}
+	for _, tt := range tests {
+		settings := TicketTypeSettings{Price: 100, Currency: "EUR", SalesStart: tt.start, SalesEnd: tt.end}
+		if err := validate.Struct(&settings); err != nil {
+			t.Errorf("%s: validate: %v", tt.name, err)
+		}
+		if err := settings.CheckWindow(); (err != nil) != tt.wantErr {
+			t.Errorf("%s: CheckWindow = %v, want error %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,261 @@
+package holds
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"booking-app/internal/bookings"
+	"booking-app/internal/database"
+	"booking-app/internal/events"
+	"booking-app/internal/middleware"
+	"booking-app/internal/outbox"
+	"booking-app/internal/waitingroom"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	ErrHoldNotFound  = errors.New("hold not found")
+	ErrHoldNotActive = errors.New("hold is no longer active")
+	ErrForbidden     = errors.New("not allowed to use this hold")
+)
+
+// DBStore manages holds in PostgreSQL
+type DBStore struct {
+	db       *sqlx.DB
+	bookings *bookings.DBStore
+}
+
+func NewDBStore(db *sqlx.DB, bookingStore *bookings.DBStore) *DBStore {
+	return &DBStore{db: db, bookings: bookingStore}
+}
+
+// CreateHold reserves places for the authenticated user, using up their
+// admission if the event has a waiting room. Converting the hold later
+// needs no further admission.
+func (s *DBStore) CreateHold(ctx context.Context, nh NewHold) (Hold, error) {
+	if userID, ok := middleware.UserIDFromContext(ctx); ok {
+		nh.UserID = &userID
+	}
+	var h Hold
+	err := database.WithTx(ctx, s.db, func(tx *sqlx.Tx) error {
+		if nh.Slot == nil {
+			if err := waitingroom.UseAdmissionTx(ctx, tx, nh.Event, nh.UserID); err != nil {
+				return err
+			}
+		}
+		var err error
+		h, err = CreateHoldTx(ctx, tx, nh)
+		return err
+	})
+	return h, err
+}
+
+// CreateHoldTx reserves places inside the caller's transaction, failing with
+// rules.ErrNotBookable if the booking rules do not allow it,
+// bookings.ErrLimitReached if the user may not hold that many, with
+// events.ErrEventFull or events.ErrSoldOut if they are not available, or
+// bookings.ErrSlotTaken if its appointment slot is.
+func CreateHoldTx(ctx context.Context, tx *sqlx.Tx, nh NewHold) (Hold, error) {
+	if nh.Event == "" || nh.UserName == "" || nh.Quantity <= 0 {
+		return Hold{}, errors.New("event, user name and a positive quantity are required")
+	}
+	if err := bookings.CheckRulesTx(ctx, tx, nh.Event, nh.Slot, time.Now()); err != nil {
+		return Hold{}, err
+	}
+	// Held places count towards the event's per-user limit; the platform
+	// limits are checked when the hold is converted.
+	if err := bookings.CheckLimitsTx(ctx, tx, bookings.Limits{}, nh.UserID, nh.Event, nh.Quantity, nh.Slot); err != nil {
+		return Hold{}, err
+	}
+	// Holds on appointment slots are keyed by their resource, not the name
+	if nh.Slot == nil {
+		if _, err := events.ReserveCapacity(ctx, tx, nh.Event, nh.Quantity); err != nil {
+			return Hold{}, err
+		}
+		if _, err := events.ReserveTicket(ctx, tx, nh.Event, nh.TicketTypeID, nh.Quantity); err != nil {
+			return Hold{}, err
+		}
+	}
+	h := Hold{Event: nh.Event, UserID: nh.UserID, UserName: nh.UserName, Quantity: nh.Quantity, TicketTypeID: nh.TicketTypeID,
+		ExpiresAt: time.Now().Add(nh.TTL)}
+	if nh.Slot != nil {
+		if nh.Quantity != 1 {
+			return Hold{}, errors.New("a hold on an appointment slot holds a single place")
+		}
+		if _, err := bookings.LockSlotTx(ctx, tx, *nh.Slot); err != nil {
+			return Hold{}, err
+		}
+		h.ResourceID = &nh.Slot.ResourceID
+		h.StartsAt = &nh.Slot.StartsAt
+		h.EndsAt = &nh.Slot.EndsAt
+		h.BlockedUntil = &nh.Slot.BlockedUntil
+		h.TimeZone = &nh.Slot.TimeZone
+	}
+	rows, err := sqlx.NamedQueryContext(ctx, tx, `INSERT INTO holds (event, user_id, user_name, quantity, ticket_type_id, expires_at,
+              resource_id, starts_at, ends_at, blocked_until, time_zone)
+              VALUES (:event, :user_id, :user_name, :quantity, :ticket_type_id, :expires_at,
+              :resource_id, :starts_at, :ends_at, :blocked_until, :time_zone) RETURNING *`, h)
+	if err != nil {
+		return Hold{}, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Printf("Failed to close rows: %v", err)
+		}
+	}()
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return Hold{}, err
+		}
+		return Hold{}, errors.New("failed to retrieve inserted hold")
+	}
+	err = rows.StructScan(&h)
+	return h, err
+}
+
+func (s *DBStore) GetHold(ctx context.Context, id int) (Hold, error) {
+	var h Hold
+	err := s.db.GetContext(ctx, &h, "SELECT * FROM holds WHERE id = $1", id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Hold{}, ErrHoldNotFound
+	}
+	return h, err
+}
+
+// ConvertHold turns an active hold into confirmed bookings, one per place,
+// each with the given attendee answers
+func (s *DBStore) ConvertHold(ctx context.Context, id int, answers json.RawMessage) ([]bookings.Booking, error) {
+	var created []bookings.Booking
+	err := database.WithTx(ctx, s.db, func(tx *sqlx.Tx) error {
+		var err error
+		created, err = s.ConvertHoldTx(ctx, tx, id, answers)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// ConvertHoldTx converts a hold inside the caller's transaction
+func (s *DBStore) ConvertHoldTx(ctx context.Context, tx *sqlx.Tx, id int, answers json.RawMessage) ([]bookings.Booking, error) {
+	h, err := lockActiveHold(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	// Mark the hold converted first so its places stop counting against
+	// capacity before the bookings that replace them are checked.
+	if h, err = setStatus(ctx, tx, h.ID, StatusConverted); err != nil {
+		return nil, err
+	}
+	var created []bookings.Booking
+	for i := 0; i < h.Quantity; i++ {
+		b, err := s.bookings.CreateBookingTx(ctx, tx, bookings.NewBooking{
+			UserName:     h.UserName,
+			Event:        h.Event,
+			UserID:       h.UserID,
+			TicketTypeID: h.TicketTypeID,
+			Slot:         h.slot(),
+			Answers:      answers,
+			Held:         true,
+		})
+		if err != nil {
+			return nil, err
+		}
+		created = append(created, b)
+	}
+	return created, publish(ctx, tx, EventConverted, h)
+}
+
+// ReleaseHold gives an active hold's places back before it expires
+func (s *DBStore) ReleaseHold(ctx context.Context, id int) error {
+	return database.WithTx(ctx, s.db, func(tx *sqlx.Tx) error {
+		h, err := lockActiveHold(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		return ReleaseHoldTx(ctx, tx, h.ID)
+	})
+}
+
+// ReleaseHoldTx releases a hold inside the caller's transaction without
+// checking who owns it. Holds that are no longer active are left alone.
+func ReleaseHoldTx(ctx context.Context, tx *sqlx.Tx, id int) error {
+	var h Hold
+	err := tx.GetContext(ctx, &h, `UPDATE holds SET status = 'released', updated_at = now()
+              WHERE id = $1 AND status = 'active' RETURNING *`, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return publish(ctx, tx, EventReleased, h)
+}
+
+// ExpireHolds marks every active hold past its expiry as expired and returns them
+func (s *DBStore) ExpireHolds(ctx context.Context) ([]Hold, error) {
+	var expired []Hold
+	err := database.WithTx(ctx, s.db, func(tx *sqlx.Tx) error {
+		err := tx.SelectContext(ctx, &expired, `UPDATE holds SET status = 'expired', updated_at = now()
+              WHERE status = 'active' AND expires_at <= now() RETURNING *`)
+		if err != nil {
+			return err
+		}
+		for _, h := range expired {
+			if err := publish(ctx, tx, EventExpired, h); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return expired, nil
+}
+
+// lockActiveHold locks a hold owned by the caller that is still active
+func lockActiveHold(ctx context.Context, tx *sqlx.Tx, id int) (Hold, error) {
+	var h Hold
+	err := tx.GetContext(ctx, &h, "SELECT * FROM holds WHERE id = $1 FOR UPDATE", id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Hold{}, ErrHoldNotFound
+	}
+	if err != nil {
+		return Hold{}, err
+	}
+	if !canUse(ctx, h) {
+		return Hold{}, ErrForbidden
+	}
+	if h.Status != StatusActive || !h.ExpiresAt.After(time.Now()) {
+		return Hold{}, ErrHoldNotActive
+	}
+	return h, nil
+}
+
+// canUse allows admins and the hold's owner to use it. Holds without an
+// owner can only be used by admins.
+func canUse(ctx context.Context, h Hold) bool {
+	if middleware.IsAdmin(ctx) {
+		return true
+	}
+	userID, ok := middleware.UserIDFromContext(ctx)
+	return ok && h.UserID != nil && *h.UserID == userID
+}
+
+func setStatus(ctx context.Context, tx *sqlx.Tx, id int, status string) (Hold, error) {
+	var h Hold
+	err := tx.GetContext(ctx, &h, "UPDATE holds SET status = $1, updated_at = now() WHERE id = $2 RETURNING *", status, id)
+	return h, err
+}
+
+// publish records that a hold gave up its places, so waitlists can react
+func publish(ctx context.Context, tx *sqlx.Tx, eventType string, h Hold) error {
+	return outbox.Enqueue(ctx, tx, outbox.Message{Type: eventType, AggregateType: "hold", AggregateID: int64(h.ID), Data: h})
+}
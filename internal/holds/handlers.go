@@ -0,0 +1,142 @@
+package holds
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"booking-app/internal/bookings"
+	"booking-app/internal/events"
+	"booking-app/internal/rules"
+	"booking-app/internal/waitingroom"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+)
+
+var validate = validator.New()
+
+type Handler struct {
+	store *DBStore
+}
+
+func NewHandler(store *DBStore) *Handler {
+	return &Handler{store: store}
+}
+
+func (h *Handler) CreateHoldHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		UserName     string `json:"user_name" validate:"required"`
+		Event        string `json:"event" validate:"required"`
+		Quantity     int    `json:"quantity" validate:"required,min=1,max=50"`
+		TicketTypeID *int   `json:"ticket_type_id"`
+		TTLSeconds   int    `json:"ttl_seconds" validate:"omitempty,min=30,max=3600"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := validate.Struct(&input); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ttl := DefaultTTL
+	if input.TTLSeconds > 0 {
+		ttl = time.Duration(input.TTLSeconds) * time.Second
+	}
+	hold, err := h.store.CreateHold(r.Context(), NewHold{
+		Event:        input.Event,
+		UserName:     input.UserName,
+		Quantity:     input.Quantity,
+		TicketTypeID: input.TicketTypeID,
+		TTL:          ttl,
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, hold)
+}
+
+func (h *Handler) GetHoldHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	hold, err := h.store.GetHold(r.Context(), id)
+	if err == nil && !canUse(r.Context(), hold) {
+		err = ErrForbidden
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, hold)
+}
+
+func (h *Handler) ConvertHoldHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	var input struct {
+		// Answers respond to the event's attendee questions for every place
+		Answers json.RawMessage `json:"answers"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+	created, err := h.store.ConvertHold(r.Context(), id, input.Answers)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, created)
+}
+
+func (h *Handler) ReleaseHoldHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	if err := h.store.ReleaseHold(r.Context(), id); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrHoldNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrForbidden), errors.Is(err, waitingroom.ErrNotAdmitted):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, ErrHoldNotActive):
+		http.Error(w, err.Error(), http.StatusGone)
+	case errors.Is(err, events.ErrEventFull), errors.Is(err, events.ErrSoldOut), errors.Is(err, events.ErrNotOnSale),
+		errors.Is(err, bookings.ErrSlotTaken), errors.Is(err, rules.ErrNotBookable),
+		errors.Is(err, bookings.ErrLimitReached), errors.Is(err, bookings.ErrOverlap):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, bookings.ErrInvalidAnswers):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	default:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
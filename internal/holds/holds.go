@@ -0,0 +1,87 @@
+package holds
+
+import (
+	"encoding/json"
+	"time"
+
+	"booking-app/internal/bookings"
+	"booking-app/internal/timezone"
+)
+
+// Hold statuses
+const (
+	StatusActive    = "active"
+	StatusConverted = "converted"
+	StatusReleased  = "released"
+	StatusExpired   = "expired"
+)
+
+// DefaultTTL is how long a hold lasts when the client does not choose
+const DefaultTTL = 10 * time.Minute
+
+// Hold reserves places on an event during checkout. An active hold counts
+// against capacity until it expires, is released, or becomes bookings.
+type Hold struct {
+	ID           int       `json:"id" db:"id"`
+	Event        string    `json:"event" db:"event"`
+	UserID       *int      `json:"user_id,omitempty" db:"user_id"`
+	UserName     string    `json:"user_name" db:"user_name"`
+	Quantity     int       `json:"quantity" db:"quantity"`
+	TicketTypeID *int      `json:"ticket_type_id,omitempty" db:"ticket_type_id"`
+	Status       string    `json:"status" db:"status"`
+	ExpiresAt    time.Time `json:"expires_at" db:"expires_at"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
+	// ResourceID, StartsAt and EndsAt are set for holds on an appointment
+	// slot, which hold a single place, in the resource's TimeZone.
+	ResourceID   *int       `json:"resource_id,omitempty" db:"resource_id"`
+	StartsAt     *time.Time `json:"starts_at,omitempty" db:"starts_at"`
+	EndsAt       *time.Time `json:"ends_at,omitempty" db:"ends_at"`
+	BlockedUntil *time.Time `json:"-" db:"blocked_until"`
+	TimeZone     *string    `json:"time_zone,omitempty" db:"time_zone"`
+}
+
+// MarshalJSON adds the venue-local times of a held slot
+func (h Hold) MarshalJSON() ([]byte, error) {
+	type hold Hold
+	out := struct {
+		hold
+		StartsAtLocal *time.Time `json:"starts_at_local,omitempty"`
+		EndsAtLocal   *time.Time `json:"ends_at_local,omitempty"`
+	}{hold: hold(h)}
+	if h.TimeZone != nil {
+		out.StartsAtLocal = timezone.In(h.StartsAt, *h.TimeZone)
+		out.EndsAtLocal = timezone.In(h.EndsAt, *h.TimeZone)
+	}
+	return json.Marshal(out)
+}
+
+// NewHold describes places to reserve for a user
+type NewHold struct {
+	Event        string
+	UserID       *int
+	UserName     string
+	Quantity     int
+	TicketTypeID *int
+	TTL          time.Duration
+	Slot         *bookings.Slot
+}
+
+// Event types written to the outbox when a hold gives up its places
+const (
+	EventConverted = "hold.converted"
+	EventReleased  = "hold.released"
+	EventExpired   = "hold.expired"
+)
+
+// slot returns the appointment slot a hold is for, if any
+func (h Hold) slot() *bookings.Slot {
+	if h.ResourceID == nil {
+		return nil
+	}
+	slot := &bookings.Slot{ResourceID: *h.ResourceID, StartsAt: *h.StartsAt, EndsAt: *h.EndsAt, BlockedUntil: *h.BlockedUntil}
+	if h.TimeZone != nil {
+		slot.TimeZone = *h.TimeZone
+	}
+	return slot
+}
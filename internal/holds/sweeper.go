@@ -0,0 +1,30 @@
+package holds
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// RunSweeper marks expired holds every interval until ctx is cancelled.
+// Capacity checks already ignore holds past expires_at, so the sweeper only
+// moves them to their final status; places are never held longer than the TTL.
+func (s *DBStore) RunSweeper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			expired, err := s.ExpireHolds(ctx)
+			if err != nil {
+				log.Printf("Failed to expire holds: %v", err)
+				continue
+			}
+			if len(expired) > 0 {
+				log.Printf("Expired %d holds", len(expired))
+			}
+		}
+	}
+}
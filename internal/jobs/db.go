@@ -0,0 +1,202 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"booking-app/internal/database"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	ErrJobNotFound   = errors.New("job not found")
+	ErrInvalidStatus = errors.New("job cannot be changed in its current status")
+)
+
+// Store is a durable job queue backed by PostgreSQL
+type Store struct {
+	db *sqlx.DB
+}
+
+func NewStore(db *sqlx.DB) *Store {
+	return &Store{db: db}
+}
+
+func (s *Store) Enqueue(ctx context.Context, job NewJob) (Job, error) {
+	return EnqueueTx(ctx, s.db, job)
+}
+
+// EnqueueTx enqueues a job through q, which may be a transaction so the job
+// only becomes visible if the surrounding change commits.
+func EnqueueTx(ctx context.Context, q sqlx.QueryerContext, job NewJob) (Job, error) {
+	if job.Type == "" {
+		return Job{}, errors.New("job type cannot be empty")
+	}
+	payload, err := json.Marshal(job.Payload)
+	if err != nil {
+		return Job{}, err
+	}
+	runAt := job.RunAt
+	if runAt.IsZero() {
+		runAt = time.Now()
+	}
+	maxAttempts := job.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 5
+	}
+	var created Job
+	err = sqlx.GetContext(ctx, q, &created, `INSERT INTO jobs (type, payload, run_at, max_attempts)
+              VALUES ($1, $2, $3, $4) RETURNING *`, job.Type, string(payload), runAt, maxAttempts)
+	return created, err
+}
+
+func (s *Store) Get(ctx context.Context, id int64) (Job, error) {
+	var job Job
+	err := s.db.GetContext(ctx, &job, "SELECT * FROM jobs WHERE id = $1", id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Job{}, ErrJobNotFound
+	}
+	return job, err
+}
+
+// Filter narrows a job listing; zero values are ignored
+type Filter struct {
+	Status string
+	Type   string
+	Limit  int
+}
+
+func (s *Store) List(ctx context.Context, f Filter) ([]Job, error) {
+	var conds []string
+	var args []interface{}
+	if f.Status != "" {
+		args = append(args, f.Status)
+		conds = append(conds, fmt.Sprintf("status = $%d", len(args)))
+	}
+	if f.Type != "" {
+		args = append(args, f.Type)
+		conds = append(conds, fmt.Sprintf("type = $%d", len(args)))
+	}
+	query := "SELECT * FROM jobs"
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+	limit := f.Limit
+	if limit <= 0 || limit > 1000 {
+		limit = 100
+	}
+	args = append(args, limit)
+	query += fmt.Sprintf(" ORDER BY id DESC LIMIT $%d", len(args))
+	var jobs []Job
+	err := s.db.SelectContext(ctx, &jobs, query, args...)
+	return jobs, err
+}
+
+// Retry requeues a dead or cancelled job with a fresh set of attempts
+func (s *Store) Retry(ctx context.Context, id int64) (Job, error) {
+	return s.transition(ctx, id, `UPDATE jobs SET status = 'queued', attempts = 0, run_at = now(), last_error = NULL,
+              finished_at = NULL, updated_at = now()
+              WHERE id = $1 AND status IN ('dead', 'cancelled') RETURNING *`)
+}
+
+// Cancel stops a queued job from running. Running jobs cannot be cancelled.
+func (s *Store) Cancel(ctx context.Context, id int64) (Job, error) {
+	return s.transition(ctx, id, `UPDATE jobs SET status = 'cancelled', finished_at = now(), updated_at = now()
+              WHERE id = $1 AND status = 'queued' RETURNING *`)
+}
+
+func (s *Store) transition(ctx context.Context, id int64, query string) (Job, error) {
+	var job Job
+	err := s.db.GetContext(ctx, &job, query, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		if _, err := s.Get(ctx, id); err != nil {
+			return Job{}, err
+		}
+		return Job{}, ErrInvalidStatus
+	}
+	return job, err
+}
+
+// typeLockClass namespaces the advisory locks taken on job types
+const typeLockClass = 4603
+
+// claim locks the next due job of the given type for workerID, unless limit
+// jobs of that type are already running. Claims of one type are serialized
+// by a transaction-scoped advisory lock, so the limit holds across every
+// worker sharing the queue.
+func (s *Store) claim(ctx context.Context, jobType, workerID string, limit int) (Job, bool, error) {
+	var job Job
+	claimed := false
+	err := database.WithTx(ctx, s.db, func(tx *sqlx.Tx) error {
+		if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1, hashtext($2))", typeLockClass, jobType); err != nil {
+			return err
+		}
+		var running int
+		if err := tx.GetContext(ctx, &running, "SELECT count(*) FROM jobs WHERE type = $1 AND status = 'running'", jobType); err != nil {
+			return err
+		}
+		if running >= limit {
+			return nil
+		}
+		err := tx.GetContext(ctx, &job, `UPDATE jobs
+                  SET status = 'running', attempts = attempts + 1, locked_by = $2, locked_at = now(), updated_at = now()
+                  WHERE id = (
+                      SELECT id FROM jobs
+                      WHERE type = $1 AND status = 'queued' AND run_at <= now()
+                      ORDER BY run_at, id
+                      FOR UPDATE SKIP LOCKED
+                      LIMIT 1
+                  ) RETURNING *`, jobType, workerID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		claimed = true
+		return nil
+	})
+	if err != nil {
+		return Job{}, false, err
+	}
+	return job, claimed, nil
+}
+
+func (s *Store) complete(ctx context.Context, id int64) error {
+	_, err := s.db.ExecContext(ctx, `UPDATE jobs SET status = 'succeeded', finished_at = now(), locked_by = NULL,
+              locked_at = NULL, updated_at = now() WHERE id = $1`, id)
+	return err
+}
+
+// fail schedules a retry, or dead-letters the job once it is out of attempts
+func (s *Store) fail(ctx context.Context, job Job, jobErr error) error {
+	if job.Attempts >= job.MaxAttempts {
+		_, err := s.db.ExecContext(ctx, `UPDATE jobs SET status = 'dead', last_error = $1, finished_at = now(),
+                  locked_by = NULL, locked_at = NULL, updated_at = now() WHERE id = $2`, jobErr.Error(), job.ID)
+		return err
+	}
+	_, err := s.db.ExecContext(ctx, `UPDATE jobs SET status = 'queued', last_error = $1, run_at = $2,
+              locked_by = NULL, locked_at = NULL, updated_at = now() WHERE id = $3`,
+		jobErr.Error(), time.Now().Add(Backoff(job.Attempts, retryBase, retryMax)), job.ID)
+	return err
+}
+
+// requeueStale returns running jobs whose lease has expired to the queue,
+// e.g. because the worker process crashed mid-job, and dead-letters those
+// out of attempts.
+func (s *Store) requeueStale(ctx context.Context, lease time.Duration) (int64, error) {
+	result, err := s.db.ExecContext(ctx, `UPDATE jobs SET status = CASE WHEN attempts >= max_attempts THEN 'dead' ELSE 'queued' END,
+              finished_at = CASE WHEN attempts >= max_attempts THEN now() END,
+              last_error = 'worker lease expired', locked_by = NULL, locked_at = NULL, updated_at = now()
+              WHERE status = 'running' AND locked_at < $1`, time.Now().Add(-lease))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
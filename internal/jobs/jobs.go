@@ -0,0 +1,78 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/jmoiron/sqlx/types"
+)
+
+// Job statuses
+const (
+	StatusQueued    = "queued"
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusDead      = "dead"
+	StatusCancelled = "cancelled"
+)
+
+type Job struct {
+	ID          int64          `json:"id" db:"id"`
+	Type        string         `json:"type" db:"type"`
+	Payload     types.JSONText `json:"payload" db:"payload"`
+	Status      string         `json:"status" db:"status"`
+	RunAt       time.Time      `json:"run_at" db:"run_at"`
+	Attempts    int            `json:"attempts" db:"attempts"`
+	MaxAttempts int            `json:"max_attempts" db:"max_attempts"`
+	LastError   *string        `json:"last_error,omitempty" db:"last_error"`
+	LockedBy    *string        `json:"locked_by,omitempty" db:"locked_by"`
+	LockedAt    *time.Time     `json:"locked_at,omitempty" db:"locked_at"`
+	FinishedAt  *time.Time     `json:"finished_at,omitempty" db:"finished_at"`
+	CreatedAt   time.Time      `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at" db:"updated_at"`
+}
+
+// NewJob describes a job to enqueue. A zero RunAt runs the job as soon as a
+// worker is free and a zero MaxAttempts uses the default of 5.
+type NewJob struct {
+	Type        string
+	Payload     interface{}
+	RunAt       time.Time
+	MaxAttempts int
+}
+
+// HandlerFunc processes one job. Returning an error schedules a retry until
+// the job runs out of attempts and is dead-lettered.
+type HandlerFunc func(ctx context.Context, job Job) error
+
+// Handle adapts a function taking a typed payload into a HandlerFunc
+func Handle[T any](fn func(ctx context.Context, payload T) error) HandlerFunc {
+	return func(ctx context.Context, job Job) error {
+		var payload T
+		if err := json.Unmarshal(job.Payload, &payload); err != nil {
+			return err
+		}
+		return fn(ctx, payload)
+	}
+}
+
+// Retry delays of failed jobs: 10s, 20s, 40s... capped at one hour
+const (
+	retryBase = 10 * time.Second
+	retryMax  = time.Hour
+)
+
+// Backoff returns the delay before retry number n (starting at 1), doubling
+// from base and capped at max. Jobs, webhook deliveries and the outbox relay
+// all back off this way.
+func Backoff(n int, base, max time.Duration) time.Duration {
+	delay := base
+	for i := 1; i < n; i++ {
+		delay *= 2
+		if delay >= max {
+			return max
+		}
+	}
+	return delay
+}
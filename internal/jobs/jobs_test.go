@@ -0,0 +1,48 @@
+package jobs
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestHandleDecodesPayload(t *testing.T) {
+	type reminder struct {
+		BookingID int `json:"booking_id"`
+	}
+	var got reminder
+	handler := Handle(func(ctx context.Context, p reminder) error {
+		got = p
+		return nil
+	})
+	if err := handler(context.Background(), Job{Payload: []byte(`{"booking_id":12}`)}); err != nil {
+		t.Fatalf("Handler failed: %v", err)
+	}
+	if got.BookingID != 12 {
+		t.Errorf("Expected booking_id=12, got %+v", got)
+	}
+	if err := handler(context.Background(), Job{Payload: []byte(`[]`)}); err == nil {
+		t.Error("Expected error for payload of the wrong shape, got nil")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		n         int
+		base, max time.Duration
+		want      time.Duration
+	}{
+		{1, retryBase, retryMax, 10 * time.Second},
+		{2, retryBase, retryMax, 20 * time.Second},
+		{5, retryBase, retryMax, 160 * time.Second},
+		{20, retryBase, retryMax, time.Hour},
+		{1, time.Second, time.Minute, time.Second},
+		{4, time.Second, time.Minute, 8 * time.Second},
+		{30, time.Second, time.Minute, time.Minute},
+	}
+	for _, tt := range tests {
+		if got := Backoff(tt.n, tt.base, tt.max); got != tt.want {
+			t.Errorf("Backoff(%d, %v, %v) = %v, want %v", tt.n, tt.base, tt.max, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// Worker runs registered handlers against the queue
+type Worker struct {
+	store        *Store
+	id           string
+	pollInterval time.Duration
+	lease        time.Duration
+	handlers     map[string]registration
+}
+
+type registration struct {
+	handler     HandlerFunc
+	concurrency int
+}
+
+func NewWorker(store *Store) *Worker {
+	host, _ := os.Hostname()
+	return &Worker{
+		store:        store,
+		id:           fmt.Sprintf("%s-%d", host, os.Getpid()),
+		pollInterval: time.Second,
+		lease:        10 * time.Minute,
+		handlers:     make(map[string]registration),
+	}
+}
+
+// Register sets the handler for a job type and how many jobs of that type
+// may run at once. The limit is enforced when claiming, so it holds across
+// every worker process sharing the queue. It must be called before Run.
+func (w *Worker) Register(jobType string, concurrency int, handler HandlerFunc) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	w.handlers[jobType] = registration{handler: handler, concurrency: concurrency}
+}
+
+// Run processes jobs until ctx is cancelled, then waits for running jobs to finish
+func (w *Worker) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for jobType, reg := range w.handlers {
+		for i := 0; i < reg.concurrency; i++ {
+			wg.Add(1)
+			go func(jobType string, reg registration) {
+				defer wg.Done()
+				w.poll(ctx, jobType, reg)
+			}(jobType, reg)
+		}
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		w.reap(ctx)
+	}()
+	wg.Wait()
+}
+
+func (w *Worker) poll(ctx context.Context, jobType string, reg registration) {
+	for {
+		job, ok, err := w.store.claim(ctx, jobType, w.id, reg.concurrency)
+		if err != nil {
+			log.Printf("Failed to claim %s job: %v", jobType, err)
+		}
+		if ok {
+			w.execute(ctx, job, reg.handler)
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(w.pollInterval):
+		}
+	}
+}
+
+func (w *Worker) execute(ctx context.Context, job Job, handler HandlerFunc) {
+	jobCtx, cancel := context.WithTimeout(ctx, w.lease)
+	defer cancel()
+	err := func() (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("job panicked: %v", r)
+			}
+		}()
+		return handler(jobCtx, job)
+	}()
+	// Record the outcome even if ctx was cancelled while the job ran.
+	ctx = context.WithoutCancel(ctx)
+	if err != nil {
+		log.Printf("Job %d (%s) attempt %d failed: %v", job.ID, job.Type, job.Attempts, err)
+		if err := w.store.fail(ctx, job, err); err != nil {
+			log.Printf("Failed to record failure of job %d: %v", job.ID, err)
+		}
+		return
+	}
+	if err := w.store.complete(ctx, job.ID); err != nil {
+		log.Printf("Failed to complete job %d: %v", job.ID, err)
+	}
+}
+
+func (w *Worker) reap(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := w.store.requeueStale(ctx, w.lease+time.Minute)
+			if err != nil {
+				log.Printf("Failed to requeue stale jobs: %v", err)
+			} else if n > 0 {
+				log.Printf("Requeued %d stale jobs", n)
+			}
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package jsonpatch
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func assertJSONEqual(t *testing.T, got []byte, want string) {
+	t.Helper()
+	var g, w interface{}
+	if err := json.Unmarshal(got, &g); err != nil {
+		t.Fatalf("Failed to decode result %s: %v", got, err)
+	}
+	if err := json.Unmarshal([]byte(want), &w); err != nil {
+		t.Fatalf("Failed to decode expectation %s: %v", want, err)
+	}
+	if !reflect.DeepEqual(g, w) {
+		t.Errorf("Expected %s, got %s", want, got)
+	}
+}
+
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		doc, patch, want string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+		{`["a","b"]`, `{"a":"b"}`, `{"a":"b"}`},
+	}
+	for _, tt := range tests {
+		got, err := MergePatch([]byte(tt.doc), []byte(tt.patch))
+		if err != nil {
+			t.Fatalf("MergePatch(%s, %s) failed: %v", tt.doc, tt.patch, err)
+		}
+		assertJSONEqual(t, got, tt.want)
+	}
+}
+
+func TestMergePatchInvalid(t *testing.T) {
+	if _, err := MergePatch([]byte(`{}`), []byte(`{`)); !errors.Is(err, ErrInvalidPatch) {
+		t.Fatalf("Expected ErrInvalidPatch, got %v", err)
+	}
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		doc, patch, want string
+	}{
+		{`{"a":1}`, `[{"op":"replace","path":"/a","value":2}]`, `{"a":2}`},
+		{`{"a":1}`, `[{"op":"add","path":"/b","value":"x"}]`, `{"a":1,"b":"x"}`},
+		{`{"a":1,"b":2}`, `[{"op":"remove","path":"/b"}]`, `{"a":1}`},
+		{`{"a":[1,3]}`, `[{"op":"add","path":"/a/1","value":2}]`, `{"a":[1,2,3]}`},
+		{`{"a":[1]}`, `[{"op":"add","path":"/a/-","value":2}]`, `{"a":[1,2]}`},
+		{`{"a":1}`, `[{"op":"move","from":"/a","path":"/b"}]`, `{"b":1}`},
+		{`{"a":{"x":1}}`, `[{"op":"copy","from":"/a","path":"/b"}]`, `{"a":{"x":1},"b":{"x":1}}`},
+		{`{"a~b":1}`, `[{"op":"test","path":"/a~0b","value":1}]`, `{"a~b":1}`},
+	}
+	for _, tt := range tests {
+		got, err := Apply([]byte(tt.doc), []byte(tt.patch))
+		if err != nil {
+			t.Fatalf("Apply(%s, %s) failed: %v", tt.doc, tt.patch, err)
+		}
+		assertJSONEqual(t, got, tt.want)
+	}
+}
+
+func TestApplyErrors(t *testing.T) {
+	if _, err := Apply([]byte(`{"a":1}`), []byte(`[{"op":"test","path":"/a","value":2}]`)); !errors.Is(err, ErrTestFailed) {
+		t.Errorf("Expected ErrTestFailed, got %v", err)
+	}
+	if _, err := Apply([]byte(`{"a":1}`), []byte(`[{"op":"replace","path":"/missing","value":2}]`)); !errors.Is(err, ErrInvalidPatch) {
+		t.Errorf("Expected ErrInvalidPatch for missing path, got %v", err)
+	}
+	if _, err := Apply([]byte(`{"a":1}`), []byte(`[{"op":"frobnicate","path":"/a"}]`)); !errors.Is(err, ErrInvalidPatch) {
+		t.Errorf("Expected ErrInvalidPatch for unknown op, got %v", err)
+	}
+}
@@ -0,0 +1,43 @@
+package jsonpatch
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// ErrInvalidPatch is returned when a patch document cannot be parsed
+var ErrInvalidPatch = errors.New("invalid patch document")
+
+// MergePatch applies an RFC 7396 JSON Merge Patch to doc and returns the result
+func MergePatch(doc, patch []byte) ([]byte, error) {
+	var target interface{}
+	if len(doc) > 0 {
+		if err := json.Unmarshal(doc, &target); err != nil {
+			return nil, err
+		}
+	}
+	var p interface{}
+	if err := json.Unmarshal(patch, &p); err != nil {
+		return nil, ErrInvalidPatch
+	}
+	return json.Marshal(mergeValue(target, p))
+}
+
+func mergeValue(target, patch interface{}) interface{} {
+	patchObj, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	targetObj, ok := target.(map[string]interface{})
+	if !ok {
+		targetObj = map[string]interface{}{}
+	}
+	for key, value := range patchObj {
+		if value == nil {
+			delete(targetObj, key)
+			continue
+		}
+		targetObj[key] = mergeValue(targetObj[key], value)
+	}
+	return targetObj
+}
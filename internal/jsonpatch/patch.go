@@ -0,0 +1,255 @@
+package jsonpatch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ErrTestFailed is returned when a "test" operation does not match
+var ErrTestFailed = errors.New("test operation failed")
+
+// Operation is a single RFC 6902 JSON Patch operation
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// Apply applies an RFC 6902 JSON Patch to doc and returns the result
+func Apply(doc, patch []byte) ([]byte, error) {
+	var ops []Operation
+	if err := json.Unmarshal(patch, &ops); err != nil {
+		return nil, ErrInvalidPatch
+	}
+	var target interface{}
+	if err := json.Unmarshal(doc, &target); err != nil {
+		return nil, err
+	}
+	for i, op := range ops {
+		var err error
+		target, err = applyOp(target, op)
+		if err != nil {
+			return nil, fmt.Errorf("operation %d (%s %s): %w", i, op.Op, op.Path, err)
+		}
+	}
+	return json.Marshal(target)
+}
+
+func applyOp(doc interface{}, op Operation) (interface{}, error) {
+	switch op.Op {
+	case "add", "replace", "test":
+		if op.Value == nil {
+			return nil, fmt.Errorf("%w: missing value", ErrInvalidPatch)
+		}
+		var value interface{}
+		if err := json.Unmarshal(op.Value, &value); err != nil {
+			return nil, ErrInvalidPatch
+		}
+		switch op.Op {
+		case "add":
+			return add(doc, op.Path, value)
+		case "replace":
+			if _, err := get(doc, op.Path); err != nil {
+				return nil, err
+			}
+			doc, err := remove(doc, op.Path)
+			if err != nil {
+				return nil, err
+			}
+			return add(doc, op.Path, value)
+		default:
+			current, err := get(doc, op.Path)
+			if err != nil {
+				return nil, err
+			}
+			if !reflect.DeepEqual(current, value) {
+				return nil, ErrTestFailed
+			}
+			return doc, nil
+		}
+	case "remove":
+		return remove(doc, op.Path)
+	case "move", "copy":
+		value, err := get(doc, op.From)
+		if err != nil {
+			return nil, err
+		}
+		if op.Op == "move" {
+			if strings.HasPrefix(op.Path, op.From+"/") {
+				return nil, fmt.Errorf("%w: cannot move a value into itself", ErrInvalidPatch)
+			}
+			if doc, err = remove(doc, op.From); err != nil {
+				return nil, err
+			}
+		} else {
+			value = deepCopy(value)
+		}
+		return add(doc, op.Path, value)
+	default:
+		return nil, fmt.Errorf("%w: unknown op %q", ErrInvalidPatch, op.Op)
+	}
+}
+
+func parsePointer(path string) ([]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(path, "/") {
+		return nil, fmt.Errorf("%w: bad pointer %q", ErrInvalidPatch, path)
+	}
+	tokens := strings.Split(path[1:], "/")
+	for i, t := range tokens {
+		t = strings.ReplaceAll(t, "~1", "/")
+		tokens[i] = strings.ReplaceAll(t, "~0", "~")
+	}
+	return tokens, nil
+}
+
+func get(doc interface{}, path string) (interface{}, error) {
+	tokens, err := parsePointer(path)
+	if err != nil {
+		return nil, err
+	}
+	current := doc
+	for _, t := range tokens {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			v, ok := node[t]
+			if !ok {
+				return nil, fmt.Errorf("%w: path %q not found", ErrInvalidPatch, path)
+			}
+			current = v
+		case []interface{}:
+			idx, err := arrayIndex(t, len(node)-1)
+			if err != nil {
+				return nil, err
+			}
+			current = node[idx]
+		default:
+			return nil, fmt.Errorf("%w: path %q not found", ErrInvalidPatch, path)
+		}
+	}
+	return current, nil
+}
+
+func add(doc interface{}, path string, value interface{}) (interface{}, error) {
+	tokens, err := parsePointer(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return value, nil
+	}
+	return setIn(doc, tokens, value, true)
+}
+
+func remove(doc interface{}, path string) (interface{}, error) {
+	tokens, err := parsePointer(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+	return setIn(doc, tokens, nil, false)
+}
+
+// setIn inserts value at tokens when insert is true, and removes the
+// addressed member otherwise. Containers are modified in place where
+// possible and the (possibly new) root is returned.
+func setIn(node interface{}, tokens []string, value interface{}, insert bool) (interface{}, error) {
+	t := tokens[0]
+	last := len(tokens) == 1
+	switch n := node.(type) {
+	case map[string]interface{}:
+		if last {
+			if insert {
+				n[t] = value
+				return n, nil
+			}
+			if _, ok := n[t]; !ok {
+				return nil, fmt.Errorf("%w: member %q not found", ErrInvalidPatch, t)
+			}
+			delete(n, t)
+			return n, nil
+		}
+		child, ok := n[t]
+		if !ok {
+			return nil, fmt.Errorf("%w: member %q not found", ErrInvalidPatch, t)
+		}
+		updated, err := setIn(child, tokens[1:], value, insert)
+		if err != nil {
+			return nil, err
+		}
+		n[t] = updated
+		return n, nil
+	case []interface{}:
+		if last {
+			if insert {
+				idx := len(n)
+				if t != "-" {
+					var err error
+					if idx, err = arrayIndex(t, len(n)); err != nil {
+						return nil, err
+					}
+				}
+				n = append(n, nil)
+				copy(n[idx+1:], n[idx:])
+				n[idx] = value
+				return n, nil
+			}
+			idx, err := arrayIndex(t, len(n)-1)
+			if err != nil {
+				return nil, err
+			}
+			return append(n[:idx], n[idx+1:]...), nil
+		}
+		idx, err := arrayIndex(t, len(n)-1)
+		if err != nil {
+			return nil, err
+		}
+		updated, err := setIn(n[idx], tokens[1:], value, insert)
+		if err != nil {
+			return nil, err
+		}
+		n[idx] = updated
+		return n, nil
+	default:
+		return nil, fmt.Errorf("%w: cannot traverse into %q", ErrInvalidPatch, t)
+	}
+}
+
+func arrayIndex(token string, max int) (int, error) {
+	if token == "" || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("%w: bad array index %q", ErrInvalidPatch, token)
+	}
+	idx, err := strconv.Atoi(token)
+	if err != nil || idx < 0 || idx > max {
+		return 0, fmt.Errorf("%w: array index %q out of range", ErrInvalidPatch, token)
+	}
+	return idx, nil
+}
+
+func deepCopy(v interface{}) interface{} {
+	switch n := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(n))
+		for k, child := range n {
+			out[k] = deepCopy(child)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(n))
+		for i, child := range n {
+			out[i] = deepCopy(child)
+		}
+		return out
+	default:
+		return v
+	}
+}
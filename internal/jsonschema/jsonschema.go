@@ -0,0 +1,576 @@
+// Package jsonschema validates JSON documents against the subset of JSON
+// Schema (draft 2020-12) that booking forms need:
+//
+//   - type, enum and const
+//   - properties, required and additionalProperties for objects
+//   - items, minItems, maxItems and uniqueItems for arrays
+//   - minLength, maxLength, pattern and format (email, date, date-time,
+//     time) for strings
+//   - minimum, maximum, exclusiveMinimum, exclusiveMaximum and multipleOf
+//     for numbers
+//
+// Annotations such as title and description are accepted and ignored.
+// Any other keyword, including references and combinators, is rejected
+// when the schema is compiled, so a form never silently checks less than
+// its author intended.
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/mail"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+var (
+	ErrInvalidSchema = errors.New("invalid JSON Schema")
+	ErrInvalid       = errors.New("document does not match the schema")
+)
+
+// maxDepth bounds how deeply schemas may nest
+const maxDepth = 32
+
+// annotations are keywords that do not affect validation
+var annotations = map[string]bool{
+	"$schema": true, "$id": true, "$comment": true, "title": true, "description": true,
+	"default": true, "examples": true, "readOnly": true, "writeOnly": true, "deprecated": true,
+}
+
+// formats are the values of format that are checked; others are annotations
+var formats = map[string]func(string) bool{
+	"email": func(s string) bool {
+		a, err := mail.ParseAddress(s)
+		return err == nil && a.Address == s
+	},
+	"date":      func(s string) bool { _, err := time.Parse("2006-01-02", s); return err == nil },
+	"date-time": func(s string) bool { _, err := time.Parse(time.RFC3339, s); return err == nil },
+	"time":      func(s string) bool { _, err := time.Parse("15:04:05Z07:00", s); return err == nil },
+}
+
+var typeNames = map[string]bool{
+	"null": true, "boolean": true, "object": true, "array": true, "number": true, "integer": true, "string": true,
+}
+
+// Schema is a compiled schema. The zero value accepts every document.
+type Schema struct {
+	reject bool
+
+	types    []string
+	enum     []interface{}
+	hasEnum  bool
+	constant interface{}
+	hasConst bool
+
+	properties   map[string]*Schema
+	order        []string
+	required     []string
+	additional   *Schema
+	items        *Schema
+	minItems     *int
+	maxItems     *int
+	uniqueItems  bool
+	minLength    *int
+	maxLength    *int
+	pattern      *regexp.Regexp
+	format       string
+	minimum      *float64
+	maximum      *float64
+	exclusiveMin *float64
+	exclusiveMax *float64
+	multipleOf   *float64
+}
+
+// FieldError is one way a document fails a schema. Path is a JSON Pointer
+// to the offending value, empty for the document itself.
+type FieldError struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists every way a document fails a schema. It matches
+// ErrInvalid with errors.Is.
+type ValidationError struct {
+	Errors []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, fe := range e.Errors {
+		if fe.Path == "" {
+			msgs[i] = fe.Message
+		} else {
+			msgs[i] = fe.Path + ": " + fe.Message
+		}
+	}
+	return ErrInvalid.Error() + ": " + strings.Join(msgs, "; ")
+}
+
+func (e *ValidationError) Unwrap() error {
+	return ErrInvalid
+}
+
+// Compile parses a schema, failing with ErrInvalidSchema if it is not one
+// or uses keywords this package does not support
+func Compile(data []byte) (*Schema, error) {
+	v, err := decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSchema, err)
+	}
+	return compile(v, "", 0)
+}
+
+// Properties returns the names of the top-level properties, sorted
+func (s *Schema) Properties() []string {
+	return append([]string(nil), s.order...)
+}
+
+// Validate checks a document against the schema, returning a
+// *ValidationError if it does not match
+func (s *Schema) Validate(doc []byte) error {
+	v, err := decode(doc)
+	if err != nil {
+		return &ValidationError{Errors: []FieldError{{Message: "is not valid JSON"}}}
+	}
+	var errs []FieldError
+	s.validate("", v, &errs)
+	if len(errs) > 0 {
+		return &ValidationError{Errors: errs}
+	}
+	return nil
+}
+
+// decode parses a single JSON value, keeping numbers exact
+func decode(data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("unexpected data after the JSON value")
+	}
+	return v, nil
+}
+
+func compile(v interface{}, path string, depth int) (*Schema, error) {
+	if depth > maxDepth {
+		return nil, fmt.Errorf("%w: nested more than %d levels deep", ErrInvalidSchema, maxDepth)
+	}
+	invalid := func(format string, args ...interface{}) error {
+		return fmt.Errorf("%w: %s: %s", ErrInvalidSchema, pointer(path), fmt.Sprintf(format, args...))
+	}
+	switch v := v.(type) {
+	case bool:
+		return &Schema{reject: !v}, nil
+	case map[string]interface{}:
+		s := &Schema{}
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if err := s.keyword(k, v[k], path, depth); err != nil {
+				return nil, err
+			}
+		}
+		if s.hasEnum && len(s.enum) == 0 {
+			return nil, invalid("enum must not be empty")
+		}
+		for _, name := range s.required {
+			if _, ok := s.properties[name]; !ok && s.additional != nil && s.additional.reject {
+				return nil, invalid("required property %q is not allowed", name)
+			}
+		}
+		return s, nil
+	default:
+		return nil, invalid("a schema must be an object or a boolean")
+	}
+}
+
+// keyword compiles one keyword of a schema object
+func (s *Schema) keyword(k string, v interface{}, path string, depth int) error {
+	invalid := func(format string, args ...interface{}) error {
+		return fmt.Errorf("%w: %s: %s", ErrInvalidSchema, pointer(path+"/"+escape(k)), fmt.Sprintf(format, args...))
+	}
+	var err error
+	switch k {
+	case "type":
+		switch t := v.(type) {
+		case string:
+			s.types = []string{t}
+		case []interface{}:
+			for _, item := range t {
+				name, ok := item.(string)
+				if !ok {
+					return invalid("must list type names")
+				}
+				s.types = append(s.types, name)
+			}
+		default:
+			return invalid("must be a type name or a list of them")
+		}
+		for _, t := range s.types {
+			if !typeNames[t] {
+				return invalid("unknown type %q", t)
+			}
+		}
+	case "enum":
+		list, ok := v.([]interface{})
+		if !ok {
+			return invalid("must be an array")
+		}
+		s.enum, s.hasEnum = list, true
+	case "const":
+		s.constant, s.hasConst = v, true
+	case "properties":
+		props, ok := v.(map[string]interface{})
+		if !ok {
+			return invalid("must be an object")
+		}
+		s.properties = make(map[string]*Schema, len(props))
+		for name, sub := range props {
+			if s.properties[name], err = compile(sub, path+"/properties/"+escape(name), depth+1); err != nil {
+				return err
+			}
+		}
+		s.order = propertyOrder(props)
+	case "required":
+		list, ok := v.([]interface{})
+		if !ok {
+			return invalid("must be an array of property names")
+		}
+		for _, item := range list {
+			name, ok := item.(string)
+			if !ok {
+				return invalid("must be an array of property names")
+			}
+			s.required = append(s.required, name)
+		}
+	case "additionalProperties":
+		s.additional, err = compile(v, path+"/additionalProperties", depth+1)
+	case "items":
+		s.items, err = compile(v, path+"/items", depth+1)
+	case "minItems":
+		s.minItems, err = count(v)
+	case "maxItems":
+		s.maxItems, err = count(v)
+	case "uniqueItems":
+		b, ok := v.(bool)
+		if !ok {
+			return invalid("must be a boolean")
+		}
+		s.uniqueItems = b
+	case "minLength":
+		s.minLength, err = count(v)
+	case "maxLength":
+		s.maxLength, err = count(v)
+	case "pattern":
+		p, ok := v.(string)
+		if !ok {
+			return invalid("must be a string")
+		}
+		if s.pattern, err = regexp.Compile(p); err != nil {
+			return invalid("%v", err)
+		}
+	case "format":
+		f, ok := v.(string)
+		if !ok {
+			return invalid("must be a string")
+		}
+		s.format = f
+	case "minimum":
+		s.minimum, err = number(v)
+	case "maximum":
+		s.maximum, err = number(v)
+	case "exclusiveMinimum":
+		s.exclusiveMin, err = number(v)
+	case "exclusiveMaximum":
+		s.exclusiveMax, err = number(v)
+	case "multipleOf":
+		if s.multipleOf, err = number(v); err == nil && *s.multipleOf <= 0 {
+			return invalid("must be greater than 0")
+		}
+	default:
+		if !annotations[k] {
+			return invalid("unsupported keyword")
+		}
+	}
+	if err != nil {
+		if errors.Is(err, ErrInvalidSchema) {
+			return err
+		}
+		return invalid("%v", err)
+	}
+	return nil
+}
+
+// propertyOrder returns property names in a stable order. Go maps do not
+// keep the order of the document, so names are sorted.
+func propertyOrder(props map[string]interface{}) []string {
+	names := make([]string, 0, len(props))
+	for name := range props {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func count(v interface{}) (*int, error) {
+	n, ok := v.(json.Number)
+	if !ok {
+		return nil, errors.New("must be a non-negative integer")
+	}
+	i, err := strconv.Atoi(n.String())
+	if err != nil || i < 0 {
+		return nil, errors.New("must be a non-negative integer")
+	}
+	return &i, nil
+}
+
+func number(v interface{}) (*float64, error) {
+	n, ok := v.(json.Number)
+	if !ok {
+		return nil, errors.New("must be a number")
+	}
+	f, err := n.Float64()
+	if err != nil {
+		return nil, errors.New("must be a number")
+	}
+	return &f, nil
+}
+
+func (s *Schema) validate(path string, v interface{}, errs *[]FieldError) {
+	fail := func(format string, args ...interface{}) {
+		*errs = append(*errs, FieldError{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+	if s.reject {
+		fail("is not allowed")
+		return
+	}
+	if len(s.types) > 0 && !s.hasType(v) {
+		fail("must be of type %s", strings.Join(s.types, " or "))
+		return
+	}
+	if s.hasEnum && !contains(s.enum, v) {
+		fail("must be one of %s", list(s.enum))
+	}
+	if s.hasConst && !equal(s.constant, v) {
+		fail("must be %s", show(s.constant))
+	}
+	switch v := v.(type) {
+	case map[string]interface{}:
+		s.validateObject(path, v, errs)
+	case []interface{}:
+		s.validateArray(path, v, errs)
+	case string:
+		n := utf8.RuneCountInString(v)
+		if s.minLength != nil && n < *s.minLength {
+			fail("must be at least %d characters long", *s.minLength)
+		}
+		if s.maxLength != nil && n > *s.maxLength {
+			fail("must be at most %d characters long", *s.maxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(v) {
+			fail("must match the pattern %s", s.pattern)
+		}
+		if check, ok := formats[s.format]; ok && !check(v) {
+			fail("must be a valid %s", s.format)
+		}
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			fail("is not a valid number")
+			return
+		}
+		if s.minimum != nil && f < *s.minimum {
+			fail("must be at least %s", formatFloat(*s.minimum))
+		}
+		if s.maximum != nil && f > *s.maximum {
+			fail("must be at most %s", formatFloat(*s.maximum))
+		}
+		if s.exclusiveMin != nil && f <= *s.exclusiveMin {
+			fail("must be greater than %s", formatFloat(*s.exclusiveMin))
+		}
+		if s.exclusiveMax != nil && f >= *s.exclusiveMax {
+			fail("must be less than %s", formatFloat(*s.exclusiveMax))
+		}
+		if s.multipleOf != nil {
+			if q := f / *s.multipleOf; math.Abs(q-math.Round(q)) > 1e-9 {
+				fail("must be a multiple of %s", formatFloat(*s.multipleOf))
+			}
+		}
+	}
+}
+
+func (s *Schema) validateObject(path string, obj map[string]interface{}, errs *[]FieldError) {
+	for _, name := range s.required {
+		if _, ok := obj[name]; !ok {
+			*errs = append(*errs, FieldError{Path: path + "/" + escape(name), Message: "is required"})
+		}
+	}
+	names := make([]string, 0, len(obj))
+	for name := range obj {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		sub, ok := s.properties[name]
+		if !ok {
+			sub = s.additional
+		}
+		if sub != nil {
+			sub.validate(path+"/"+escape(name), obj[name], errs)
+		}
+	}
+}
+
+func (s *Schema) validateArray(path string, arr []interface{}, errs *[]FieldError) {
+	if s.minItems != nil && len(arr) < *s.minItems {
+		*errs = append(*errs, FieldError{Path: path, Message: fmt.Sprintf("must have at least %d items", *s.minItems)})
+	}
+	if s.maxItems != nil && len(arr) > *s.maxItems {
+		*errs = append(*errs, FieldError{Path: path, Message: fmt.Sprintf("must have at most %d items", *s.maxItems)})
+	}
+	if s.uniqueItems {
+		for i := range arr {
+			for j := 0; j < i; j++ {
+				if equal(arr[i], arr[j]) {
+					*errs = append(*errs, FieldError{Path: path, Message: "must not contain duplicate items"})
+					i = len(arr)
+					break
+				}
+			}
+		}
+	}
+	if s.items != nil {
+		for i, item := range arr {
+			s.items.validate(path+"/"+strconv.Itoa(i), item, errs)
+		}
+	}
+}
+
+func (s *Schema) hasType(v interface{}) bool {
+	for _, t := range s.types {
+		switch v := v.(type) {
+		case nil:
+			if t == "null" {
+				return true
+			}
+		case bool:
+			if t == "boolean" {
+				return true
+			}
+		case map[string]interface{}:
+			if t == "object" {
+				return true
+			}
+		case []interface{}:
+			if t == "array" {
+				return true
+			}
+		case string:
+			if t == "string" {
+				return true
+			}
+		case json.Number:
+			if t == "number" {
+				return true
+			}
+			if f, err := v.Float64(); t == "integer" && err == nil && f == math.Trunc(f) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func contains(list []interface{}, v interface{}) bool {
+	for _, item := range list {
+		if equal(item, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// equal compares decoded JSON values, numbers by value
+func equal(a, b interface{}) bool {
+	switch a := a.(type) {
+	case json.Number:
+		bn, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		af, aerr := a.Float64()
+		bf, berr := bn.Float64()
+		return aerr == nil && berr == nil && af == bf
+	case map[string]interface{}:
+		bm, ok := b.(map[string]interface{})
+		if !ok || len(a) != len(bm) {
+			return false
+		}
+		for k, av := range a {
+			bv, ok := bm[k]
+			if !ok || !equal(av, bv) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		bl, ok := b.([]interface{})
+		if !ok || len(a) != len(bl) {
+			return false
+		}
+		for i := range a {
+			if !equal(a[i], bl[i]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return a == b
+	}
+}
+
+func list(values []interface{}) string {
+	shown := make([]string, len(values))
+	for i, v := range values {
+		shown[i] = show(v)
+	}
+	return strings.Join(shown, ", ")
+}
+
+func show(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// escape encodes a property name as a JSON Pointer reference token
+func escape(name string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(name, "~", "~0"), "/", "~1")
+}
+
+func pointer(path string) string {
+	if path == "" {
+		return "schema root"
+	}
+	return path
+}
@@ -0,0 +1,174 @@
+package jsonschema
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+const form = `{
+	"$schema": "https://json-schema.org/draft/2020-12/schema",
+	"title": "Attendee questions",
+	"type": "object",
+	"properties": {
+		"diet": {"enum": ["none", "vegetarian", "vegan"]},
+		"shirt": {"type": "string", "enum": ["S", "M", "L", "XL"]},
+		"age": {"type": "integer", "minimum": 18, "maximum": 120},
+		"email": {"type": "string", "format": "email"},
+		"phone": {"type": "string", "pattern": "^\\+?[0-9 ]{6,20}$"},
+		"contact": {
+			"type": "object",
+			"properties": {"name": {"type": "string", "minLength": 1, "maxLength": 5}},
+			"required": ["name"]
+		},
+		"tags": {"type": "array", "items": {"type": "string"}, "maxItems": 2, "uniqueItems": true}
+	},
+	"required": ["diet", "shirt"],
+	"additionalProperties": false
+}`
+
+func TestValidate(t *testing.T) {
+	schema, err := Compile([]byte(form))
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+	tests := []struct {
+		name string
+		doc  string
+		want []FieldError
+	}{
+		{"minimal", `{"diet": "vegan", "shirt": "M"}`, nil},
+		{"complete", `{"diet": "none", "shirt": "XL", "age": 30, "email": "a@example.com", "phone": "+44 20 7946 0000",
+			"contact": {"name": "Bob"}, "tags": ["a", "b"]}`, nil},
+		{"integer written as float", `{"diet": "none", "shirt": "S", "age": 30.0}`, nil},
+		{"missing", `{}`, []FieldError{{"/diet", "is required"}, {"/shirt", "is required"}}},
+		{"not an object", `[]`, []FieldError{{"", "must be of type object"}}},
+		{"enum", `{"diet": "keto", "shirt": "M"}`, []FieldError{{"/diet", `must be one of "none", "vegetarian", "vegan"`}}},
+		{"type", `{"diet": "none", "shirt": 3}`, []FieldError{{"/shirt", "must be of type string"}}},
+		{"not an integer", `{"diet": "none", "shirt": "M", "age": 30.5}`, []FieldError{{"/age", "must be of type integer"}}},
+		{"minimum", `{"diet": "none", "shirt": "M", "age": 17}`, []FieldError{{"/age", "must be at least 18"}}},
+		{"maximum", `{"diet": "none", "shirt": "M", "age": 121}`, []FieldError{{"/age", "must be at most 120"}}},
+		{"format", `{"diet": "none", "shirt": "M", "email": "Bob <bob@example.com>"}`, []FieldError{{"/email", "must be a valid email"}}},
+		{"pattern", `{"diet": "none", "shirt": "M", "phone": "call me"}`, []FieldError{{"/phone", `must match the pattern ^\+?[0-9 ]{6,20}$`}}},
+		{"nested", `{"diet": "none", "shirt": "M", "contact": {}}`, []FieldError{{"/contact/name", "is required"}}},
+		{"min length", `{"diet": "none", "shirt": "M", "contact": {"name": ""}}`, []FieldError{{"/contact/name", "must be at least 1 characters long"}}},
+		{"max length counts characters", `{"diet": "none", "shirt": "M", "contact": {"name": "Zoë B"}}`, nil},
+		{"max length", `{"diet": "none", "shirt": "M", "contact": {"name": "Robert"}}`, []FieldError{{"/contact/name", "must be at most 5 characters long"}}},
+		{"items", `{"diet": "none", "shirt": "M", "tags": ["a", 1]}`, []FieldError{{"/tags/1", "must be of type string"}}},
+		{"max items", `{"diet": "none", "shirt": "M", "tags": ["a", "b", "c"]}`, []FieldError{{"/tags", "must have at most 2 items"}}},
+		{"unique items", `{"diet": "none", "shirt": "M", "tags": ["a", "a"]}`, []FieldError{{"/tags", "must not contain duplicate items"}}},
+		{"additional", `{"diet": "none", "shirt": "M", "a/b": 1}`, []FieldError{{"/a~1b", "is not allowed"}}},
+		{"several", `{"shirt": "XXL", "age": "old"}`, []FieldError{
+			{"/diet", "is required"}, {"/age", "must be of type integer"}, {"/shirt", `must be one of "S", "M", "L", "XL"`}}},
+		{"not JSON", `{"diet":`, []FieldError{{"", "is not valid JSON"}}},
+		{"trailing data", `{} {}`, []FieldError{{"", "is not valid JSON"}}},
+	}
+	for _, tt := range tests {
+		err := schema.Validate([]byte(tt.doc))
+		if tt.want == nil {
+			if err != nil {
+				t.Errorf("%s: Validate failed: %v", tt.name, err)
+			}
+			continue
+		}
+		var verr *ValidationError
+		if !errors.As(err, &verr) || !errors.Is(err, ErrInvalid) {
+			t.Errorf("%s: Expected a validation error, got %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(verr.Errors, tt.want) {
+			t.Errorf("%s: Expected %v, got %v", tt.name, tt.want, verr.Errors)
+		}
+	}
+}
+
+func TestValidateKeywords(t *testing.T) {
+	tests := []struct {
+		schema, doc string
+		valid       bool
+	}{
+		{`{}`, `"anything"`, true},
+		{`true`, `1`, true},
+		{`false`, `1`, false},
+		{`{"type": ["string", "null"]}`, `null`, true},
+		{`{"type": ["string", "null"]}`, `1`, false},
+		{`{"type": "boolean"}`, `false`, true},
+		{`{"type": "number"}`, `1.5`, true},
+		{`{"const": 2}`, `2.0`, true},
+		{`{"const": {"a": [1]}}`, `{"a": [1]}`, true},
+		{`{"const": {"a": [1]}}`, `{"a": [2]}`, false},
+		{`{"enum": [1, "1"]}`, `"1"`, true},
+		{`{"enum": [1]}`, `true`, false},
+		{`{"exclusiveMinimum": 0}`, `0`, false},
+		{`{"exclusiveMaximum": 10}`, `9.99`, true},
+		{`{"multipleOf": 0.5}`, `2.5`, true},
+		{`{"multipleOf": 0.5}`, `2.4`, false},
+		{`{"minimum": 5}`, `"3"`, true},
+		{`{"minItems": 1}`, `[]`, false},
+		{`{"uniqueItems": true}`, `[1, 1.0]`, false},
+		{`{"uniqueItems": true}`, `[{"a": 1}, {"a": 2}]`, true},
+		{`{"format": "date"}`, `"2026-02-30"`, false},
+		{`{"format": "date"}`, `"2026-02-28"`, true},
+		{`{"format": "date-time"}`, `"2026-02-28T09:30:00+01:00"`, true},
+		{`{"format": "time"}`, `"09:30:00Z"`, true},
+		{`{"format": "hostname"}`, `"not checked"`, true},
+		{`{"additionalProperties": {"type": "integer"}}`, `{"a": 1, "b": 2}`, true},
+		{`{"additionalProperties": {"type": "integer"}}`, `{"a": "1"}`, false},
+	}
+	for _, tt := range tests {
+		schema, err := Compile([]byte(tt.schema))
+		if err != nil {
+			t.Fatalf("Compile(%s) failed: %v", tt.schema, err)
+		}
+		if err := schema.Validate([]byte(tt.doc)); (err == nil) != tt.valid {
+			t.Errorf("Validate(%s) against %s: expected valid=%v, got %v", tt.doc, tt.schema, tt.valid, err)
+		}
+	}
+}
+
+func TestCompileRejects(t *testing.T) {
+	tests := []string{
+		`"object"`,
+		`{"type": "text"}`,
+		`{"type": 1}`,
+		`{"$ref": "#/$defs/a"}`,
+		`{"oneOf": [{}, {}]}`,
+		`{"properties": {"a": {"allOf": []}}}`,
+		`{"properties": []}`,
+		`{"required": "a"}`,
+		`{"enum": []}`,
+		`{"minLength": -1}`,
+		`{"maxItems": 1.5}`,
+		`{"minimum": "1"}`,
+		`{"multipleOf": 0}`,
+		`{"pattern": "("}`,
+		`{"required": ["a"], "additionalProperties": false}`,
+		`{"type": "object"`,
+	}
+	for _, tt := range tests {
+		if _, err := Compile([]byte(tt)); !errors.Is(err, ErrInvalidSchema) {
+			t.Errorf("Compile(%s): expected ErrInvalidSchema, got %v", tt, err)
+		}
+	}
+}
+
+func TestCompileDepth(t *testing.T) {
+	schema := `{}`
+	for i := 0; i <= maxDepth; i++ {
+		schema = `{"items": ` + schema + `}`
+	}
+	if _, err := Compile([]byte(schema)); !errors.Is(err, ErrInvalidSchema) {
+		t.Errorf("Expected ErrInvalidSchema for a deeply nested schema, got %v", err)
+	}
+}
+
+func TestProperties(t *testing.T) {
+	schema, err := Compile([]byte(form))
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+	want := []string{"age", "contact", "diet", "email", "phone", "shirt", "tags"}
+	if got := schema.Properties(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,123 @@
+package ledger
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// PostTx writes a balanced transaction inside the caller's transaction, so
+// it is recorded if and only if the operation it describes commits.
+// Accounts are created the first time they are used.
+func PostTx(ctx context.Context, tx *sqlx.Tx, t Transaction) error {
+	if err := t.Validate(); err != nil {
+		return err
+	}
+	var txID int64
+	err := tx.GetContext(ctx, &txID, `INSERT INTO ledger_transactions (kind, reference_type, reference_id)
+              VALUES ($1, $2, $3) RETURNING id`, t.Kind, t.ReferenceType, t.ReferenceID)
+	if err != nil {
+		return err
+	}
+	for _, e := range t.Entries {
+		accountID, err := accountID(ctx, tx, e.Account)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `INSERT INTO ledger_entries (transaction_id, account_id, amount, currency)
+              VALUES ($1, $2, $3, $4)`, txID, accountID, e.Amount, e.Currency)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func accountID(ctx context.Context, tx *sqlx.Tx, code string) (int, error) {
+	kind, err := KindOf(code)
+	if err != nil {
+		return 0, err
+	}
+	var id int
+	err = tx.GetContext(ctx, &id, `INSERT INTO ledger_accounts (code, kind) VALUES ($1, $2)
+              ON CONFLICT (code) DO UPDATE SET code = EXCLUDED.code RETURNING id`, code, kind)
+	return id, err
+}
+
+// Store queries the ledger
+type Store struct {
+	db *sqlx.DB
+}
+
+func NewStore(db *sqlx.DB) *Store {
+	return &Store{db: db}
+}
+
+// Balances returns the balance of every account in every currency it uses.
+// An empty code returns all accounts.
+func (s *Store) Balances(ctx context.Context, code string) ([]Balance, error) {
+	balances := []Balance{}
+	err := s.db.SelectContext(ctx, &balances, `SELECT a.code AS account, a.kind, e.currency,
+              coalesce(sum(e.amount) FILTER (WHERE e.amount > 0), 0) AS debits,
+              coalesce(-sum(e.amount) FILTER (WHERE e.amount < 0), 0) AS credits
+              FROM ledger_accounts a JOIN ledger_entries e ON e.account_id = a.id
+              WHERE $1 = '' OR a.code = $1
+              GROUP BY a.code, a.kind, e.currency
+              ORDER BY a.code, e.currency`, code)
+	if err != nil {
+		return nil, err
+	}
+	for i := range balances {
+		balances[i].compute()
+	}
+	return balances, nil
+}
+
+// Entries returns an account's most recent entries, newest first
+func (s *Store) Entries(ctx context.Context, code string, limit int) ([]AccountEntry, error) {
+	if limit <= 0 || limit > 1000 {
+		limit = 100
+	}
+	entries := []AccountEntry{}
+	err := s.db.SelectContext(ctx, &entries, `SELECT e.id, e.transaction_id, t.kind, t.reference_type, t.reference_id,
+              e.amount, e.currency, e.created_at
+              FROM ledger_entries e
+              JOIN ledger_accounts a ON a.id = e.account_id
+              JOIN ledger_transactions t ON t.id = e.transaction_id
+              WHERE a.code = $1
+              ORDER BY e.id DESC LIMIT $2`, code, limit)
+	return entries, err
+}
+
+// Check verifies that total debits equal total credits in each currency,
+// that every transaction balances and that none is empty.
+func (s *Store) Check(ctx context.Context) (Report, error) {
+	r := Report{Totals: []CurrencyTotal{}, Unbalanced: []int64{}, CheckedAt: time.Now()}
+	err := s.db.SelectContext(ctx, &r.Totals, `SELECT currency,
+              coalesce(sum(amount) FILTER (WHERE amount > 0), 0) AS debits,
+              coalesce(-sum(amount) FILTER (WHERE amount < 0), 0) AS credits
+              FROM ledger_entries GROUP BY currency ORDER BY currency`)
+	if err != nil {
+		return Report{}, err
+	}
+	err = s.db.SelectContext(ctx, &r.Unbalanced, `SELECT DISTINCT transaction_id FROM (
+              SELECT transaction_id FROM ledger_entries
+              GROUP BY transaction_id, currency HAVING sum(amount) <> 0) u
+              ORDER BY transaction_id`)
+	if err != nil {
+		return Report{}, err
+	}
+	err = s.db.GetContext(ctx, &r.EmptyCount, `SELECT count(*) FROM ledger_transactions t
+              WHERE NOT EXISTS (SELECT 1 FROM ledger_entries e WHERE e.transaction_id = t.id)`)
+	if err != nil {
+		return Report{}, err
+	}
+	r.Consistent = len(r.Unbalanced) == 0 && r.EmptyCount == 0
+	for _, t := range r.Totals {
+		if t.Debits != t.Credits {
+			r.Consistent = false
+		}
+	}
+	return r, nil
+}
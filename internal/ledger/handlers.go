@@ -0,0 +1,64 @@
+package ledger
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+type Handler struct {
+	store *Store
+}
+
+func NewHandler(store *Store) *Handler {
+	return &Handler{store: store}
+}
+
+func (h *Handler) ListBalances(w http.ResponseWriter, r *http.Request) {
+	balances, err := h.store.Balances(r.Context(), "")
+	if err != nil {
+		http.Error(w, "Failed to fetch balances", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, balances)
+}
+
+func (h *Handler) ListEntries(w http.ResponseWriter, r *http.Request) {
+	code := mux.Vars(r)["code"]
+	if _, err := KindOf(code); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	var limit int
+	if v := r.URL.Query().Get("limit"); v != "" {
+		var err error
+		if limit, err = strconv.Atoi(v); err != nil {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+	entries, err := h.store.Entries(r.Context(), code, limit)
+	if err != nil {
+		http.Error(w, "Failed to fetch entries", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, entries)
+}
+
+func (h *Handler) Check(w http.ResponseWriter, r *http.Request) {
+	report, err := h.store.Check(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to check ledger", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, report)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
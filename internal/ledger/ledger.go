@@ -0,0 +1,173 @@
+package ledger
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Account kinds
+const (
+	KindAsset     = "asset"
+	KindLiability = "liability"
+	KindRevenue   = "revenue"
+	KindExpense   = "expense"
+)
+
+// Platform-wide accounts. Each event also has an organizer payables account,
+// named by OrganizerPayables.
+const (
+	// CustomerPayments holds money collected from customers through the
+	// payment provider.
+	CustomerPayments = "customer_payments"
+	// PlatformFees is the platform's share of ticket sales
+	PlatformFees = "platform_fees"
+	// RefundsPayable is money owed back to customers for cancelled bookings
+	// that the payment provider has not refunded yet.
+	RefundsPayable = "refunds_payable"
+	// Discounts is the cost of promo code discounts, borne by the platform
+	Discounts = "discounts"
+	// UnallocatedPayments is money captured for bookings that were no
+	// longer awaiting payment, owed back to the customer
+	UnallocatedPayments = "unallocated_payments"
+)
+
+const organizerPayablesPrefix = "organizer_payables:event:"
+
+// Transaction kinds
+const (
+	TxPaymentCaptured = "payment.captured"
+	TxRefundIssued    = "refund.issued"
+	TxRefundPaid      = "refund.paid"
+)
+
+var ErrUnbalanced = errors.New("ledger transaction is not balanced")
+
+// OrganizerPayables names the account of money owed to an event's organizer
+func OrganizerPayables(eventID int) string {
+	return organizerPayablesPrefix + strconv.Itoa(eventID)
+}
+
+// KindOf returns the kind of an account from its code
+func KindOf(code string) (string, error) {
+	switch {
+	case code == CustomerPayments:
+		return KindAsset, nil
+	case code == PlatformFees:
+		return KindRevenue, nil
+	case code == RefundsPayable, code == UnallocatedPayments:
+		return KindLiability, nil
+	case code == Discounts:
+		return KindExpense, nil
+	case strings.HasPrefix(code, organizerPayablesPrefix):
+		return KindLiability, nil
+	}
+	return "", fmt.Errorf("unknown ledger account %q", code)
+}
+
+// Entry moves Amount minor units of Currency into or out of an account.
+// Debits are positive and credits negative.
+type Entry struct {
+	Account  string `json:"account" db:"account"`
+	Amount   int64  `json:"amount" db:"amount"`
+	Currency string `json:"currency" db:"currency"`
+}
+
+func Debit(account string, amount int64, currency string) Entry {
+	return Entry{Account: account, Amount: amount, Currency: currency}
+}
+
+func Credit(account string, amount int64, currency string) Entry {
+	return Entry{Account: account, Amount: -amount, Currency: currency}
+}
+
+// Transaction is a set of entries recorded together for one operation,
+// such as capturing a payment, identified by the record it refers to.
+type Transaction struct {
+	Kind          string
+	ReferenceType string
+	ReferenceID   int64
+	Entries       []Entry
+}
+
+// Validate checks that a transaction balances in every currency. Zero-amount
+// entries are dropped first, since they move nothing.
+func (t *Transaction) Validate() error {
+	kept := t.Entries[:0]
+	sums := make(map[string]int64)
+	for _, e := range t.Entries {
+		if e.Amount == 0 {
+			continue
+		}
+		if _, err := KindOf(e.Account); err != nil {
+			return err
+		}
+		sums[e.Currency] += e.Amount
+		kept = append(kept, e)
+	}
+	t.Entries = kept
+	if len(kept) == 0 {
+		return fmt.Errorf("%w: no entries", ErrUnbalanced)
+	}
+	for currency, sum := range sums {
+		if sum != 0 {
+			return fmt.Errorf("%w: %s entries sum to %d", ErrUnbalanced, currency, sum)
+		}
+	}
+	return nil
+}
+
+// Balance is an account's total in one currency. For assets and expenses
+// it is debits minus credits; for liabilities and revenue, credits minus
+// debits, so that balances are normally positive.
+type Balance struct {
+	Account  string `json:"account" db:"account"`
+	Kind     string `json:"kind" db:"kind"`
+	Currency string `json:"currency" db:"currency"`
+	Debits   int64  `json:"debits" db:"debits"`
+	Credits  int64  `json:"credits" db:"credits"`
+	Balance  int64  `json:"balance" db:"-"`
+}
+
+func (b *Balance) compute() {
+	b.Balance = b.Debits - b.Credits
+	if b.Kind == KindLiability || b.Kind == KindRevenue {
+		b.Balance = -b.Balance
+	}
+}
+
+// AccountEntry is an entry as listed for one account
+type AccountEntry struct {
+	ID            int64     `json:"id" db:"id"`
+	TransactionID int64     `json:"transaction_id" db:"transaction_id"`
+	Kind          string    `json:"kind" db:"kind"`
+	ReferenceType string    `json:"reference_type" db:"reference_type"`
+	ReferenceID   int64     `json:"reference_id" db:"reference_id"`
+	Amount        int64     `json:"amount" db:"amount"`
+	Currency      string    `json:"currency" db:"currency"`
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+}
+
+// Report is the result of a consistency check. The ledger is consistent when
+// debits equal credits in every currency and every transaction balances.
+type Report struct {
+	Totals     []CurrencyTotal `json:"totals"`
+	Unbalanced []int64         `json:"unbalanced_transactions"`
+	EmptyCount int             `json:"empty_transactions"`
+	CheckedAt  time.Time       `json:"checked_at"`
+	Consistent bool            `json:"consistent"`
+}
+
+type CurrencyTotal struct {
+	Currency string `json:"currency" db:"currency"`
+	Debits   int64  `json:"debits" db:"debits"`
+	Credits  int64  `json:"credits" db:"credits"`
+}
+
+// PlatformFee is the platform's share, in basis points, of a ticket price,
+// rounded to the nearest minor unit
+func PlatformFee(price int64, basisPoints int) int64 {
+	return (price*int64(basisPoints) + 5000) / 10000
+}
@@ -0,0 +1,212 @@
+package ledger
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []Entry
+		want    error
+	}{
+		{"balanced", []Entry{Debit(CustomerPayments, 100, "EUR"), Credit(PlatformFees, 100, "EUR")}, nil},
+		{"unbalanced", []Entry{Debit(CustomerPayments, 100, "EUR"), Credit(PlatformFees, 90, "EUR")}, ErrUnbalanced},
+		{"balanced per currency", []Entry{
+			Debit(CustomerPayments, 100, "EUR"), Credit(PlatformFees, 100, "EUR"),
+			Debit(CustomerPayments, 50, "USD"), Credit(PlatformFees, 50, "USD"),
+		}, nil},
+		{"balanced only across currencies", []Entry{Debit(CustomerPayments, 100, "EUR"), Credit(PlatformFees, 100, "USD")}, ErrUnbalanced},
+		{"empty", nil, ErrUnbalanced},
+		{"only zero entries", []Entry{Debit(Discounts, 0, "EUR")}, ErrUnbalanced},
+	}
+	for _, tt := range tests {
+		tx := Transaction{Kind: TxPaymentCaptured, Entries: tt.entries}
+		if err := tx.Validate(); !errors.Is(err, tt.want) {
+			t.Errorf("%s: Validate = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+
+	tx := Transaction{Entries: []Entry{Debit("cash", 100, "EUR"), Credit(PlatformFees, 100, "EUR")}}
+	if err := tx.Validate(); err == nil {
+		t.Error("Expected an error for an unknown account")
+	}
+}
+
+func TestKindOf(t *testing.T) {
+	tests := map[string]string{
+		CustomerPayments:     KindAsset,
+		PlatformFees:         KindRevenue,
+		RefundsPayable:       KindLiability,
+		Discounts:            KindExpense,
+		OrganizerPayables(7): KindLiability,
+	}
+	for code, want := range tests {
+		if got, err := KindOf(code); err != nil || got != want {
+			t.Errorf("KindOf(%q) = %q, %v, want %q", code, got, err, want)
+		}
+	}
+	if OrganizerPayables(7) != "organizer_payables:event:7" {
+		t.Errorf("Unexpected organizer account code %q", OrganizerPayables(7))
+	}
+}
+
+func TestPlatformFee(t *testing.T) {
+	tests := []struct {
+		price int64
+		bps   int
+		want  int64
+	}{
+		{10000, 500, 500},
+		{999, 250, 25},
+		{1000, 0, 0},
+		{0, 500, 0},
+	}
+	for _, tt := range tests {
+		if got := PlatformFee(tt.price, tt.bps); got != tt.want {
+			t.Errorf("PlatformFee(%d, %d) = %d, want %d", tt.price, tt.bps, got, tt.want)
+		}
+	}
+}
+
+func TestPaymentCaptured(t *testing.T) {
+	sales := []Sale{
+		{EventID: 1, Price: 8000, Discount: 2000, Fee: 500, Currency: "EUR"},
+		{EventID: 2, Price: 5000, Fee: 250, Currency: "EUR"},
+	}
+	tx := PaymentCaptured(9, 13000, "EUR", sales)
+	if err := tx.Validate(); err != nil {
+		t.Fatalf("Expected a balanced transaction, got %v", err)
+	}
+	got := make(map[string]int64)
+	for _, e := range tx.Entries {
+		got[e.Account] += e.Amount
+	}
+	want := map[string]int64{
+		CustomerPayments:     13000,
+		Discounts:            2000,
+		OrganizerPayables(1): -9500,
+		OrganizerPayables(2): -4750,
+		PlatformFees:         -750,
+	}
+	for account, amount := range want {
+		if got[account] != amount {
+			t.Errorf("%s: got %d, want %d", account, got[account], amount)
+		}
+	}
+	// The second sale has no discount, so its zero entry is dropped
+	if len(tx.Entries) != 7 {
+		t.Errorf("Expected 7 entries, got %d", len(tx.Entries))
+	}
+}
+
+func TestRefundPostingsClearRefundsPayable(t *testing.T) {
+	issued := RefundIssued(3, Sale{EventID: 1, Price: 5000, Fee: 250, Currency: "EUR"}, 2500)
+	paid := RefundPaid(3, 2500, "EUR")
+	var payable int64
+	for _, tx := range []Transaction{issued, paid} {
+		if err := tx.Validate(); err != nil {
+			t.Fatalf("%s: %v", tx.Kind, err)
+		}
+		for _, e := range tx.Entries {
+			if e.Account == RefundsPayable {
+				payable += e.Amount
+			}
+		}
+	}
+	if payable != 0 {
+		t.Errorf("Expected refunds payable to be cleared, got %d", payable)
+	}
+}
+
+func TestPaymentCapturedWithoutSales(t *testing.T) {
+	tx := PaymentCaptured(9, 5000, "EUR", nil)
+	if err := tx.Validate(); err != nil {
+		t.Fatalf("Expected a balanced transaction, got %v", err)
+	}
+	got := make(map[string]int64)
+	for _, e := range tx.Entries {
+		got[e.Account] += e.Amount
+	}
+	if got[CustomerPayments] != 5000 || got[UnallocatedPayments] != -5000 {
+		t.Errorf("Expected the payment to be unallocated, got %v", got)
+	}
+}
+
+func TestUnallocatedRefundClearsPayment(t *testing.T) {
+	balances := make(map[string]int64)
+	for _, tx := range []Transaction{
+		PaymentCaptured(9, 5000, "EUR", nil),
+		UnallocatedRefunded(4, 5000, "EUR"),
+		RefundPaid(4, 5000, "EUR"),
+	} {
+		if err := tx.Validate(); err != nil {
+			t.Fatalf("%s: %v", tx.Kind, err)
+		}
+		for _, e := range tx.Entries {
+			balances[e.Account] += e.Amount
+		}
+	}
+	for account, balance := range balances {
+		if balance != 0 {
+			t.Errorf("Expected %s to be cleared, got %d", account, balance)
+		}
+	}
+}
+
+func TestRefundReversesSale(t *testing.T) {
+	sale := Sale{EventID: 1, Price: 8000, Discount: 2000, Fee: 500, Currency: "EUR"}
+	tests := []struct {
+		name   string
+		amount int64
+		// payable is what the organizer is still owed after the refund
+		payable int64
+		// fees is what the platform keeps of the fee taken at capture
+		fees int64
+	}{
+		{"full refund", 8000, 0, 0},
+		{"half refund", 4000, 4750, 250},
+	}
+	for _, tt := range tests {
+		capture := PaymentCaptured(9, sale.Price, sale.Currency, []Sale{sale})
+		issued := RefundIssued(3, sale, tt.amount)
+		sums := make(map[string]int64)
+		for _, tx := range []Transaction{capture, issued} {
+			if err := tx.Validate(); err != nil {
+				t.Fatalf("%s: %s: %v", tt.name, tx.Kind, err)
+			}
+			for _, e := range tx.Entries {
+				sums[e.Account] += e.Amount
+			}
+		}
+		if got := -sums[OrganizerPayables(1)]; got != tt.payable {
+			t.Errorf("%s: organizer payable = %d, want %d", tt.name, got, tt.payable)
+		}
+		if got := -sums[RefundsPayable]; got != tt.amount {
+			t.Errorf("%s: refunds payable = %d, want %d", tt.name, got, tt.amount)
+		}
+		if got := -sums[PlatformFees]; got != tt.fees {
+			t.Errorf("%s: platform fees = %d, want %d", tt.name, got, tt.fees)
+		}
+	}
+}
+
+func TestBalanceCompute(t *testing.T) {
+	tests := []struct {
+		kind string
+		want int64
+	}{
+		{KindAsset, 700},
+		{KindExpense, 700},
+		{KindLiability, -700},
+		{KindRevenue, -700},
+	}
+	for _, tt := range tests {
+		b := Balance{Kind: tt.kind, Debits: 1000, Credits: 300}
+		b.compute()
+		if b.Balance != tt.want {
+			t.Errorf("%s: balance = %d, want %d", tt.kind, b.Balance, tt.want)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package ledger
+
+// Sale is one booking paid for by a captured payment. Price is what the
+// customer paid, Discount what a promo code took off the list price and Fee
+// the platform's share of the list price, fixed when the payment is captured.
+type Sale struct {
+	EventID  int
+	Price    int64
+	Discount int64
+	Fee      int64
+	Currency string
+}
+
+// PaymentCaptured records amount collected for sales. The organizer of each
+// event is owed the list price less the platform fee, and the platform
+// bears any discount:
+//
+//	Dr customer_payments     price
+//	Dr discounts             discount
+//	Cr organizer_payables    price + discount - fee
+//	Cr platform_fees         fee
+//
+// Whatever the sales do not account for, such as a payment captured after
+// its bookings were cancelled, is still recorded as owed back:
+//
+//	Dr customer_payments     remainder
+//	Cr unallocated_payments  remainder
+func PaymentCaptured(paymentID int, amount int64, currency string, sales []Sale) Transaction {
+	t := Transaction{Kind: TxPaymentCaptured, ReferenceType: "payment", ReferenceID: int64(paymentID)}
+	remainder := amount
+	for _, s := range sales {
+		t.Entries = append(t.Entries,
+			Debit(CustomerPayments, s.Price, s.Currency),
+			Debit(Discounts, s.Discount, s.Currency),
+			Credit(OrganizerPayables(s.EventID), s.Price+s.Discount-s.Fee, s.Currency),
+			Credit(PlatformFees, s.Fee, s.Currency),
+		)
+		if s.Currency == currency {
+			remainder -= s.Price
+		}
+	}
+	if remainder > 0 {
+		t.Entries = append(t.Entries,
+			Debit(CustomerPayments, remainder, currency),
+			Credit(UnallocatedPayments, remainder, currency),
+		)
+	}
+	return t
+}
+
+// RefundIssued records that amount of a cancelled sale is owed back to the
+// customer. The refunded share of the sale is reversed part by part: the
+// organizer gives back their share net of the platform fee, the platform
+// the fee it took at capture, and the discount it bore is returned to it. For
+// a partial refund each part is reduced in proportion, so the organizer
+// keeps the rest.
+//
+//	Dr organizer_payables  amount + discount share - fee share
+//	Dr platform_fees       fee share
+//	Cr discounts           discount share
+//	Cr refunds_payable     amount
+func RefundIssued(refundID int, sale Sale, amount int64) Transaction {
+	share := func(part int64) int64 {
+		if sale.Price <= 0 {
+			return 0
+		}
+		return (part*amount + sale.Price/2) / sale.Price
+	}
+	fee := share(sale.Fee)
+	discount := share(sale.Discount)
+	return Transaction{
+		Kind:          TxRefundIssued,
+		ReferenceType: "refund",
+		ReferenceID:   int64(refundID),
+		Entries: []Entry{
+			Debit(OrganizerPayables(sale.EventID), amount+discount-fee, sale.Currency),
+			Debit(PlatformFees, fee, sale.Currency),
+			Credit(Discounts, discount, sale.Currency),
+			Credit(RefundsPayable, amount, sale.Currency),
+		},
+	}
+}
+
+// UnallocatedRefunded records that money captured for no booking, such as a
+// payment captured after it failed, is being refunded to the customer
+//
+//	Dr unallocated_payments  amount
+//	Cr refunds_payable       amount
+func UnallocatedRefunded(refundID int, amount int64, currency string) Transaction {
+	return Transaction{
+		Kind:          TxRefundIssued,
+		ReferenceType: "refund",
+		ReferenceID:   int64(refundID),
+		Entries: []Entry{
+			Debit(UnallocatedPayments, amount, currency),
+			Credit(RefundsPayable, amount, currency),
+		},
+	}
+}
+
+// RefundPaid records that the payment provider returned a refund to the customer
+func RefundPaid(refundID int, amount int64, currency string) Transaction {
+	return Transaction{
+		Kind:          TxRefundPaid,
+		ReferenceType: "refund",
+		ReferenceID:   int64(refundID),
+		Entries: []Entry{
+			Debit(RefundsPayable, amount, currency),
+			Credit(CustomerPayments, amount, currency),
+		},
+	}
+}
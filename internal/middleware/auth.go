@@ -14,6 +14,15 @@ type contextKey string
 // UserIDKey is the key for user ID in context
 const UserIDKey contextKey = "userID"
 
+// RoleKey is the key for the user's role in context
+const RoleKey contextKey = "role"
+
+// RoleAdmin is the role allowed to use administrative endpoints
+const RoleAdmin = "admin"
+
+// RoleOrganizer is the role allowed to create events
+const RoleOrganizer = "organizer"
+
 func Auth(jwtSecret string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -43,7 +52,12 @@ func Auth(jwtSecret string) func(http.Handler) http.Handler {
 					http.Error(w, "Invalid token claims", http.StatusUnauthorized)
 					return
 				}
+				role, _ := claims["role"].(string)
+				if role == "" {
+					role = "user"
+				}
 				ctx := context.WithValue(r.Context(), UserIDKey, int(userID))
+				ctx = context.WithValue(ctx, RoleKey, role)
 				next.ServeHTTP(w, r.WithContext(ctx))
 			} else {
 				http.Error(w, "Invalid token", http.StatusUnauthorized)
@@ -51,3 +65,34 @@ func Auth(jwtSecret string) func(http.Handler) http.Handler {
 		})
 	}
 }
+
+// UserIDFromContext returns the authenticated user ID stored by Auth
+func UserIDFromContext(ctx context.Context) (int, bool) {
+	userID, ok := ctx.Value(UserIDKey).(int)
+	return userID, ok
+}
+
+// RoleFromContext returns the authenticated user's role stored by Auth
+func RoleFromContext(ctx context.Context) string {
+	role, _ := ctx.Value(RoleKey).(string)
+	return role
+}
+
+// IsAdmin reports whether the authenticated user has the admin role
+func IsAdmin(ctx context.Context) bool {
+	return RoleFromContext(ctx) == RoleAdmin
+}
+
+// RequireRole rejects requests whose authenticated user lacks the given role.
+// It must run after Auth.
+func RequireRole(role string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if RoleFromContext(r.Context()) != role {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
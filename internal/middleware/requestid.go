@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+// RequestIDKey is the key for the request ID in context
+const RequestIDKey contextKey = "requestID"
+
+// RequestIDHeader carries the request ID in requests and responses
+const RequestIDHeader = "X-Request-ID"
+
+// RequestID reuses the caller's X-Request-ID or generates a new one, and
+// echoes it back on the response.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if id == "" || len(id) > 128 {
+			buf := make([]byte, 16)
+			if _, err := rand.Read(buf); err != nil {
+				http.Error(w, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+			id = hex.EncodeToString(buf)
+		}
+		w.Header().Set(RequestIDHeader, id)
+		ctx := context.WithValue(r.Context(), RequestIDKey, id)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// RequestIDFromContext returns the request ID stored by RequestID
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(RequestIDKey).(string)
+	return id
+}
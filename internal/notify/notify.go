@@ -0,0 +1,32 @@
+package notify
+
+import (
+	"context"
+	"log"
+)
+
+// Notification is a message for a single user
+type Notification struct {
+	UserID   *int
+	UserName string
+	Subject  string
+	Body     string
+}
+
+// Notifier delivers notifications to users, e.g. by email or push
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+// LogNotifier writes notifications to the standard logger. It is the default
+// until a real delivery channel is configured.
+type LogNotifier struct{}
+
+func (LogNotifier) Notify(ctx context.Context, n Notification) error {
+	userID := 0
+	if n.UserID != nil {
+		userID = *n.UserID
+	}
+	log.Printf("notify user %d (%s): %s: %s", userID, n.UserName, n.Subject, n.Body)
+	return nil
+}
@@ -0,0 +1,51 @@
+package outbox
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/jmoiron/sqlx/types"
+)
+
+// Event is a domain event waiting in, or already relayed from, the outbox
+type Event struct {
+	ID            int64          `json:"-" db:"id"`
+	EventID       string         `json:"id" db:"event_id"`
+	EventType     string         `json:"type" db:"event_type"`
+	AggregateType string         `json:"aggregate_type" db:"aggregate_type"`
+	AggregateID   int64          `json:"aggregate_id" db:"aggregate_id"`
+	Payload       types.JSONText `json:"data" db:"payload"`
+	Attempts      int            `json:"-" db:"attempts"`
+	LastError     *string        `json:"-" db:"last_error"`
+	NextAttemptAt time.Time      `json:"-" db:"next_attempt_at"`
+	PublishedAt   *time.Time     `json:"-" db:"published_at"`
+	CreatedAt     time.Time      `json:"created_at" db:"created_at"`
+}
+
+// Message describes an event to add to the outbox
+type Message struct {
+	Type          string
+	AggregateType string
+	AggregateID   int64
+	Data          interface{}
+}
+
+// Enqueue writes an event to the outbox using the caller's transaction, so
+// the event exists if and only if the change that produced it commits.
+func Enqueue(ctx context.Context, tx sqlx.ExecerContext, msg Message) error {
+	payload, err := json.Marshal(msg.Data)
+	if err != nil {
+		return err
+	}
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO outbox_events (event_id, event_type, aggregate_type, aggregate_id, payload)
+              VALUES ($1, $2, $3, $4, $5)`, hex.EncodeToString(buf), msg.Type, msg.AggregateType, msg.AggregateID, string(payload))
+	return err
+}
@@ -0,0 +1,46 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type fakeBroker struct {
+	topic, key string
+	body       []byte
+}
+
+func (b *fakeBroker) Send(ctx context.Context, topic, key string, body []byte) error {
+	b.topic, b.key, b.body = topic, key, body
+	return nil
+}
+
+type failingSink struct{}
+
+func (failingSink) Name() string                               { return "failing" }
+func (failingSink) Publish(ctx context.Context, e Event) error { return errors.New("unavailable") }
+
+func TestBrokerSinkRoutesByTypeAndAggregate(t *testing.T) {
+	broker := &fakeBroker{}
+	sink := NewBrokerSink(broker, "bookings.")
+	e := Event{EventID: "abc", EventType: "booking.created", AggregateType: "booking", AggregateID: 7, Payload: []byte(`{"id":7}`)}
+	if err := sink.Publish(context.Background(), e); err != nil {
+		t.Fatalf("Failed to publish: %v", err)
+	}
+	if broker.topic != "bookings.booking.created" || broker.key != "booking:7" {
+		t.Errorf("Expected topic bookings.booking.created and key booking:7, got %s and %s", broker.topic, broker.key)
+	}
+	if !strings.Contains(string(broker.body), `"id":"abc"`) {
+		t.Errorf("Expected body to carry the event ID, got %s", broker.body)
+	}
+}
+
+func TestRelayPublishReportsFailingSinks(t *testing.T) {
+	r := NewRelay(nil, LogSink{}, failingSink{})
+	err := r.publish(context.Background(), Event{EventID: "x", Payload: []byte(`{}`)})
+	if err == nil || !strings.Contains(err.Error(), "failing: unavailable") {
+		t.Fatalf("Expected failing sink to be reported, got %v", err)
+	}
+}
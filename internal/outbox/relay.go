@@ -0,0 +1,103 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"booking-app/internal/database"
+	"booking-app/internal/jobs"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Relay publishes outbox events to sinks. Rows are claimed with
+// FOR UPDATE SKIP LOCKED, so any number of instances can run a relay against
+// the same table without publishing an event concurrently.
+type Relay struct {
+	db        *sqlx.DB
+	sinks     []Sink
+	batchSize int
+	interval  time.Duration
+	maxDelay  time.Duration
+}
+
+func NewRelay(db *sqlx.DB, sinks ...Sink) *Relay {
+	return &Relay{
+		db:        db,
+		sinks:     sinks,
+		batchSize: 100,
+		interval:  time.Second,
+		maxDelay:  10 * time.Minute,
+	}
+}
+
+// Run relays events until ctx is cancelled
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		n, err := r.ProcessBatch(ctx)
+		if err != nil {
+			log.Printf("Outbox relay failed: %v", err)
+		}
+		// Keep draining while batches come back full.
+		if err == nil && n == r.batchSize {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessBatch publishes one batch of due events and returns how many it claimed
+func (r *Relay) ProcessBatch(ctx context.Context) (int, error) {
+	var claimed int
+	err := database.WithTx(ctx, r.db, func(tx *sqlx.Tx) error {
+		var events []Event
+		err := tx.SelectContext(ctx, &events, `SELECT * FROM outbox_events
+              WHERE published_at IS NULL AND next_attempt_at <= now()
+              ORDER BY id LIMIT $1 FOR UPDATE SKIP LOCKED`, r.batchSize)
+		if err != nil {
+			return err
+		}
+		claimed = len(events)
+		for _, e := range events {
+			if err := r.publish(ctx, e); err != nil {
+				delay := jobs.Backoff(e.Attempts+1, time.Second, r.maxDelay)
+				_, err = tx.ExecContext(ctx, `UPDATE outbox_events
+                      SET attempts = attempts + 1, last_error = $1, next_attempt_at = now() + $2 * interval '1 millisecond'
+                      WHERE id = $3`, err.Error(), delay.Milliseconds(), e.ID)
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			if _, err := tx.ExecContext(ctx, "UPDATE outbox_events SET published_at = now(), last_error = NULL WHERE id = $1", e.ID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return claimed, err
+}
+
+// publish sends an event to every sink. If any sink fails the whole event is
+// retried later, so sinks that already succeeded will see it again.
+func (r *Relay) publish(ctx context.Context, e Event) error {
+	var failures []string
+	for _, sink := range r.sinks {
+		if err := sink.Publish(ctx, e); err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", sink.Name(), err))
+		}
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("sink failures: %s", strings.Join(failures, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strconv"
+)
+
+// Sink receives relayed events. Delivery is at least once, so sinks must
+// tolerate seeing the same event ID more than once.
+type Sink interface {
+	Name() string
+	Publish(ctx context.Context, e Event) error
+}
+
+// LogSink writes every event to the standard logger
+type LogSink struct{}
+
+func (LogSink) Name() string { return "log" }
+
+func (LogSink) Publish(ctx context.Context, e Event) error {
+	log.Printf("outbox event %s %s %s/%d: %s", e.EventID, e.EventType, e.AggregateType, e.AggregateID, e.Payload)
+	return nil
+}
+
+// EventPublisher is implemented by webhooks.Dispatcher
+type EventPublisher interface {
+	PublishEvent(ctx context.Context, eventID, eventType string, data json.RawMessage) error
+}
+
+// WebhookSink fans events out to webhook subscriptions. Publish returns once
+// the deliveries and their first attempts are stored, so an event is only
+// marked published when no restart can lose its deliveries.
+type WebhookSink struct {
+	publisher EventPublisher
+}
+
+func NewWebhookSink(publisher EventPublisher) *WebhookSink {
+	return &WebhookSink{publisher: publisher}
+}
+
+func (s *WebhookSink) Name() string { return "webhooks" }
+
+func (s *WebhookSink) Publish(ctx context.Context, e Event) error {
+	return s.publisher.PublishEvent(ctx, e.EventID, e.EventType, json.RawMessage(e.Payload))
+}
+
+// Broker is the minimal interface a message broker client must provide
+type Broker interface {
+	Send(ctx context.Context, topic, key string, body []byte) error
+}
+
+// BrokerSink publishes events to a message broker, one topic per event type
+// and keyed by aggregate so brokers that partition by key keep per-aggregate order.
+type BrokerSink struct {
+	broker Broker
+	prefix string
+}
+
+func NewBrokerSink(broker Broker, topicPrefix string) *BrokerSink {
+	return &BrokerSink{broker: broker, prefix: topicPrefix}
+}
+
+func (s *BrokerSink) Name() string { return "broker" }
+
+func (s *BrokerSink) Publish(ctx context.Context, e Event) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	key := e.AggregateType + ":" + strconv.FormatInt(e.AggregateID, 10)
+	return s.broker.Send(ctx, s.prefix+e.EventType, key, body)
+}
@@ -0,0 +1,44 @@
+// Package overbooking lets organizers sell a few more places than exist,
+// expecting some customers not to show up. Events and resources carry the
+// same policy. Places sold beyond capacity are flagged as oversold on their
+// bookings when sold. Reports count active bookings beyond capacity
+// instead, since cancellations free places whatever their flags, and
+// compare them with check-ins.
+package overbooking
+
+// Policy is the overbooking allowance of an event or resource: Percent of
+// its capacity, rounded down, at most Limit places. Either may be given
+// alone; without both nothing is oversold. A resource slot holds a single
+// appointment, so on a resource only a Percent of 100 allows anything.
+type Policy struct {
+	OverbookPercent *int `json:"overbook_percent" db:"overbook_percent" validate:"omitempty,min=1,max=100"`
+	OverbookLimit   *int `json:"overbook_limit" db:"overbook_limit" validate:"omitempty,min=1"`
+}
+
+// Allowance is how many places may be sold beyond capacity
+func (p Policy) Allowance(capacity int) int {
+	if p.OverbookPercent == nil && p.OverbookLimit == nil {
+		return 0
+	}
+	n := -1
+	if p.OverbookPercent != nil {
+		n = capacity * *p.OverbookPercent / 100
+	}
+	if p.OverbookLimit != nil && (n < 0 || *p.OverbookLimit < n) {
+		n = *p.OverbookLimit
+	}
+	return n
+}
+
+// Oversold is how many of quantity places taken on top of used ones go
+// beyond capacity
+func Oversold(capacity, used, quantity int) int {
+	over := used + quantity - capacity
+	if over <= 0 {
+		return 0
+	}
+	if over > quantity {
+		return quantity
+	}
+	return over
+}
@@ -0,0 +1,44 @@
+package overbooking
+
+import "testing"
+
+func TestAllowance(t *testing.T) {
+	ten, five, hundred := 10, 5, 100
+	tests := []struct {
+		name     string
+		policy   Policy
+		capacity int
+		want     int
+	}{
+		{"none", Policy{}, 100, 0},
+		{"percent", Policy{OverbookPercent: &ten}, 100, 10},
+		{"percent rounds down", Policy{OverbookPercent: &ten}, 19, 1},
+		{"limit", Policy{OverbookLimit: &five}, 100, 5},
+		{"percent capped by limit", Policy{OverbookPercent: &ten, OverbookLimit: &five}, 200, 5},
+		{"limit above percent", Policy{OverbookPercent: &ten, OverbookLimit: &five}, 30, 3},
+		{"single slot", Policy{OverbookPercent: &hundred}, 1, 1},
+		{"single slot below 100 percent", Policy{OverbookPercent: &ten}, 1, 0},
+	}
+	for _, tt := range tests {
+		if got := tt.policy.Allowance(tt.capacity); got != tt.want {
+			t.Errorf("%s: Allowance(%d) = %d, want %d", tt.name, tt.capacity, got, tt.want)
+		}
+	}
+}
+
+func TestOversold(t *testing.T) {
+	tests := []struct {
+		capacity, used, quantity, want int
+	}{
+		{10, 5, 1, 0},
+		{10, 9, 1, 0},
+		{10, 10, 1, 1},
+		{10, 8, 4, 2},
+		{10, 12, 3, 3},
+	}
+	for _, tt := range tests {
+		if got := Oversold(tt.capacity, tt.used, tt.quantity); got != tt.want {
+			t.Errorf("Oversold(%d, %d, %d) = %d, want %d", tt.capacity, tt.used, tt.quantity, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,363 @@
+package payments
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"booking-app/internal/bookings"
+	"booking-app/internal/database"
+	"booking-app/internal/jobs"
+	"booking-app/internal/ledger"
+	"booking-app/internal/middleware"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	ErrPaymentNotFound   = errors.New("payment not found")
+	ErrPaymentNotPending = errors.New("payment is no longer pending")
+	ErrMixedCurrency     = errors.New("bookings paid together must share a currency")
+	ErrForbidden         = errors.New("not allowed to use this payment")
+	ErrNotCaptured       = errors.New("payment has not been captured")
+	ErrRefundTooLarge    = errors.New("refund exceeds the amount left on the payment")
+)
+
+// DBStore records payments in PostgreSQL and drives them through a provider
+type DBStore struct {
+	db       *sqlx.DB
+	provider PaymentProvider
+	// feeBasisPoints is the platform's share of each sale, taken and
+	// recorded on the booking when a payment is captured
+	feeBasisPoints int
+}
+
+func NewDBStore(db *sqlx.DB, provider PaymentProvider, feeBasisPoints int) *DBStore {
+	return &DBStore{db: db, provider: provider, feeBasisPoints: feeBasisPoints}
+}
+
+// Start creates a payment for bookings awaiting payment and the provider
+// intent the customer authorizes it with. The bookings are linked to the
+// payment before the provider is called, so they cannot be paid twice.
+func (s *DBStore) Start(ctx context.Context, bookingIDs []int) (Payment, error) {
+	var p Payment
+	err := database.WithTx(ctx, s.db, func(tx *sqlx.Tx) error {
+		locked, err := bookings.LockForPaymentTx(ctx, tx, bookingIDs)
+		if err != nil {
+			return err
+		}
+		if len(locked) == 0 {
+			return bookings.ErrNotPayable
+		}
+		var amount int64
+		currency := ""
+		for _, b := range locked {
+			if b.Price == nil || b.Currency == nil {
+				return bookings.ErrNotPayable
+			}
+			if currency != "" && *b.Currency != currency {
+				return ErrMixedCurrency
+			}
+			currency = *b.Currency
+			amount += *b.Price
+		}
+		var userID *int
+		if id, ok := middleware.UserIDFromContext(ctx); ok {
+			userID = &id
+		}
+		err = tx.GetContext(ctx, &p, `INSERT INTO payments (provider, user_id, amount, currency)
+              VALUES ($1, $2, $3, $4) RETURNING *`, s.provider.Name(), userID, amount, currency)
+		if err != nil {
+			return err
+		}
+		return bookings.AttachPaymentTx(ctx, tx, locked, p.ID)
+	})
+	if err != nil {
+		return Payment{}, err
+	}
+
+	intent, err := s.provider.CreateIntent(ctx, IntentRequest{
+		Amount:         p.Amount,
+		Currency:       p.Currency,
+		IdempotencyKey: "payment-" + strconv.Itoa(p.ID),
+		Metadata:       map[string]string{"payment_id": strconv.Itoa(p.ID)},
+	})
+	if err != nil {
+		// The provider never took the payment, so free the bookings to be
+		// paid for again rather than failing them.
+		reason := err.Error()
+		detachErr := database.WithTx(ctx, s.db, func(tx *sqlx.Tx) error {
+			if _, err := tx.ExecContext(ctx, `UPDATE payments SET status = 'failed', failure_reason = $1, updated_at = now()
+              WHERE id = $2`, reason, p.ID); err != nil {
+				return err
+			}
+			return bookings.DetachPaymentTx(ctx, tx, p.ID)
+		})
+		if detachErr != nil {
+			log.Printf("Failed to release bookings of payment %d: %v", p.ID, detachErr)
+		}
+		return Payment{}, err
+	}
+	err = s.db.GetContext(ctx, &p, `UPDATE payments SET provider_ref = $1, client_secret = $2, updated_at = now()
+              WHERE id = $3 RETURNING *`, intent.ID, intent.ClientSecret, p.ID)
+	return p, err
+}
+
+func (s *DBStore) GetPayment(ctx context.Context, id int) (Payment, error) {
+	var p Payment
+	err := s.db.GetContext(ctx, &p, "SELECT * FROM payments WHERE id = $1", id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Payment{}, ErrPaymentNotFound
+	}
+	return p, err
+}
+
+// Capture charges an authorized payment and confirms its bookings. If the
+// provider declines, the bookings are cancelled and ErrDeclined returned.
+func (s *DBStore) Capture(ctx context.Context, id int) (Payment, error) {
+	p, err := s.GetPayment(ctx, id)
+	if err != nil {
+		return Payment{}, err
+	}
+	if !canUse(ctx, p) {
+		return Payment{}, ErrForbidden
+	}
+	return s.capture(ctx, p)
+}
+
+func (s *DBStore) capture(ctx context.Context, p Payment) (Payment, error) {
+	if p.Status == StatusCaptured {
+		return p, nil
+	}
+	if p.Status != StatusPending || p.ProviderRef == nil {
+		return Payment{}, ErrPaymentNotPending
+	}
+	// Mark the payment authorized before capturing it, so Expire leaves it
+	// to be settled by the capture or the provider's webhooks.
+	res, err := s.db.ExecContext(ctx, `UPDATE payments SET authorized_at = coalesce(authorized_at, now()), updated_at = now()
+              WHERE id = $1 AND status = 'pending'`, p.ID)
+	if err != nil {
+		return Payment{}, err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return Payment{}, err
+	} else if n == 0 {
+		return Payment{}, ErrPaymentNotPending
+	}
+	_, err = s.provider.Capture(ctx, *p.ProviderRef, "capture-"+strconv.Itoa(p.ID))
+	if errors.Is(err, ErrDeclined) {
+		if _, settleErr := s.settle(ctx, p.ID, false, err.Error()); settleErr != nil {
+			return Payment{}, settleErr
+		}
+		return Payment{}, err
+	}
+	if err != nil {
+		return Payment{}, err
+	}
+	return s.settle(ctx, p.ID, true, "")
+}
+
+// HandleWebhook applies a provider notification about an intent. Intents
+// that are ready are captured here, so payments complete even if the client
+// never calls the capture endpoint. Unknown intents are ignored.
+func (s *DBStore) HandleWebhook(ctx context.Context, event WebhookEvent) error {
+	var p Payment
+	err := s.db.GetContext(ctx, &p, "SELECT * FROM payments WHERE provider = $1 AND provider_ref = $2",
+		s.provider.Name(), event.IntentID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	switch event.Type {
+	case WebhookCapturable:
+		_, err = s.capture(ctx, p)
+		if errors.Is(err, ErrDeclined) || errors.Is(err, ErrPaymentNotPending) {
+			return nil
+		}
+	case WebhookSucceeded:
+		_, err = s.settle(ctx, p.ID, true, "")
+	case WebhookFailed:
+		_, err = s.settle(ctx, p.ID, false, "payment failed")
+	}
+	return err
+}
+
+// settle records the outcome of a pending payment and confirms or cancels its
+// bookings in the same transaction. Payments already settled are returned
+// unchanged, except that a capture of a failed payment is refunded.
+func (s *DBStore) settle(ctx context.Context, id int, paid bool, reason string) (Payment, error) {
+	var p Payment
+	err := database.WithTx(ctx, s.db, func(tx *sqlx.Tx) error {
+		if err := tx.GetContext(ctx, &p, "SELECT * FROM payments WHERE id = $1 FOR UPDATE", id); err != nil {
+			return err
+		}
+		if p.Status != StatusPending {
+			if paid && p.Status == StatusFailed {
+				return s.refundLateCapture(ctx, tx, &p)
+			}
+			return nil
+		}
+		if reason == reasonExpired && p.AuthorizedAt != nil {
+			// Authorized after Expire picked it; the capture settles it
+			return nil
+		}
+		status := StatusCaptured
+		var failure *string
+		if !paid {
+			status = StatusFailed
+			failure = &reason
+		}
+		err := tx.GetContext(ctx, &p, `UPDATE payments SET status = $1, failure_reason = $2, updated_at = now()
+              WHERE id = $3 RETURNING *`, status, failure, id)
+		if err != nil {
+			return err
+		}
+		settled, err := bookings.SettlePaymentTx(ctx, tx, id, paid)
+		if err != nil || !paid {
+			return err
+		}
+		sales, err := s.sales(ctx, tx, settled)
+		if err != nil {
+			return err
+		}
+		return ledger.PostTx(ctx, tx, ledger.PaymentCaptured(p.ID, p.Amount, p.Currency, sales))
+	})
+	return p, err
+}
+
+// refundLateCapture handles a payment captured after it had already failed,
+// whose bookings are gone, so the customer was charged for nothing. The
+// payment is recorded as captured into unallocated_payments and a refund of
+// the full amount queued, in the caller's transaction.
+func (s *DBStore) refundLateCapture(ctx context.Context, tx *sqlx.Tx, p *Payment) error {
+	err := tx.GetContext(ctx, p, "UPDATE payments SET status = 'captured', updated_at = now() WHERE id = $1 RETURNING *", p.ID)
+	if err != nil {
+		return err
+	}
+	if err := ledger.PostTx(ctx, tx, ledger.PaymentCaptured(p.ID, p.Amount, p.Currency, nil)); err != nil {
+		return err
+	}
+	var refundID int
+	err = tx.GetContext(ctx, &refundID, `INSERT INTO refunds (payment_id, amount, currency)
+              VALUES ($1, $2, $3) RETURNING id`, p.ID, p.Amount, p.Currency)
+	if err != nil {
+		return err
+	}
+	if err := ledger.PostTx(ctx, tx, ledger.UnallocatedRefunded(refundID, p.Amount, p.Currency)); err != nil {
+		return err
+	}
+	_, err = jobs.EnqueueTx(ctx, tx, jobs.NewJob{Type: JobExecuteRefund, Payload: RefundJob{RefundID: refundID}})
+	return err
+}
+
+// reasonExpired is the failure reason of payments Expire gave up on
+const reasonExpired = "payment expired"
+
+// Expire fails payments not completed within bookings.PaymentWindow of being
+// started and expires bookings nobody started paying for in time, giving
+// their places back. Payments the customer has authorized are left to be
+// captured. It returns how many payments and bookings it expired.
+func (s *DBStore) Expire(ctx context.Context) (int, int, error) {
+	var stale []int
+	err := s.db.SelectContext(ctx, &stale, `SELECT id FROM payments
+              WHERE status = 'pending' AND authorized_at IS NULL AND created_at <= $1 ORDER BY id`,
+		time.Now().Add(-bookings.PaymentWindow))
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, id := range stale {
+		if _, err := s.settle(ctx, id, false, reasonExpired); err != nil {
+			return 0, 0, err
+		}
+	}
+	var expired []bookings.Booking
+	err = database.WithTx(ctx, s.db, func(tx *sqlx.Tx) error {
+		var err error
+		expired, err = bookings.ExpirePaymentsTx(ctx, tx)
+		return err
+	})
+	return len(stale), len(expired), err
+}
+
+// RunSweeper expires unpaid bookings and payments every interval until ctx
+// is cancelled
+func (s *DBStore) RunSweeper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			payments, unpaid, err := s.Expire(ctx)
+			if err != nil {
+				log.Printf("Failed to expire unpaid bookings: %v", err)
+				continue
+			}
+			if payments > 0 || unpaid > 0 {
+				log.Printf("Expired %d payments and %d unpaid bookings", payments, unpaid)
+			}
+		}
+	}
+}
+
+// sales describes paid bookings for the ledger and records on each the
+// platform fee taken, so refunds reverse that fee even if the rate changes
+func (s *DBStore) sales(ctx context.Context, tx *sqlx.Tx, paid []bookings.Booking) ([]ledger.Sale, error) {
+	var sales []ledger.Sale
+	for _, b := range paid {
+		if b.Price == nil || b.Currency == nil {
+			continue
+		}
+		sale := ledger.Sale{Price: *b.Price, Currency: *b.Currency}
+		if b.Discount != nil {
+			sale.Discount = *b.Discount
+		}
+		sale.Fee = ledger.PlatformFee(sale.Price+sale.Discount, s.feeBasisPoints)
+		if err := tx.GetContext(ctx, &sale.EventID, "SELECT id FROM events WHERE name = $1", b.Event); err != nil {
+			return nil, err
+		}
+		if _, err := tx.ExecContext(ctx, "UPDATE bookings SET platform_fee = $1 WHERE id = $2", sale.Fee, b.ID); err != nil {
+			return nil, err
+		}
+		sales = append(sales, sale)
+	}
+	return sales, nil
+}
+
+// Refund returns part or all of a captured payment through the provider.
+// Retrying with the same idempotency key never refunds twice. The caller
+// records the refund with RecordRefundTx once it succeeds.
+func (s *DBStore) Refund(ctx context.Context, paymentID int, amount int64, idempotencyKey string) (Refund, error) {
+	p, err := s.GetPayment(ctx, paymentID)
+	if err != nil {
+		return Refund{}, err
+	}
+	if p.Status != StatusCaptured || p.ProviderRef == nil {
+		return Refund{}, ErrNotCaptured
+	}
+	if amount > p.Amount-p.RefundedAmount {
+		return Refund{}, ErrRefundTooLarge
+	}
+	return s.provider.Refund(ctx, *p.ProviderRef, amount, idempotencyKey)
+}
+
+// RecordRefundTx adds a completed refund to the payment's refunded amount
+func RecordRefundTx(ctx context.Context, tx *sqlx.Tx, paymentID int, amount int64) error {
+	_, err := tx.ExecContext(ctx, `UPDATE payments SET refunded_amount = refunded_amount + $1, updated_at = now()
+              WHERE id = $2`, amount, paymentID)
+	return err
+}
+
+func canUse(ctx context.Context, p Payment) bool {
+	if p.UserID == nil || middleware.IsAdmin(ctx) {
+		return true
+	}
+	userID, ok := middleware.UserIDFromContext(ctx)
+	return !ok || userID == *p.UserID
+}
@@ -0,0 +1,139 @@
+package payments
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"booking-app/internal/webhooks"
+)
+
+// FakeSignatureHeader carries the signature of FakeProvider webhooks
+const FakeSignatureHeader = "Fake-Signature"
+
+// FakeProvider is an in-memory PaymentProvider for tests and local
+// development. Intents are authorized as soon as they are created, so they
+// can be captured straight away; it must never take real orders.
+type FakeProvider struct {
+	// DeclineCapture makes captures fail as if the card was declined
+	DeclineCapture bool
+
+	mu       sync.Mutex
+	secret   string
+	next     int
+	intents  map[string]*Intent
+	keys     map[string]string
+	refunds  map[string]Refund
+	refunded map[string]int64
+}
+
+func NewFakeProvider(webhookSecret string) *FakeProvider {
+	return &FakeProvider{
+		secret:   webhookSecret,
+		intents:  make(map[string]*Intent),
+		keys:     make(map[string]string),
+		refunds:  make(map[string]Refund),
+		refunded: make(map[string]int64),
+	}
+}
+
+func (p *FakeProvider) Name() string {
+	return "fake"
+}
+
+func (p *FakeProvider) CreateIntent(ctx context.Context, req IntentRequest) (Intent, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if id, ok := p.keys[req.IdempotencyKey]; ok && req.IdempotencyKey != "" {
+		return *p.intents[id], nil
+	}
+	if req.Amount <= 0 {
+		return Intent{}, errors.New("amount must be positive")
+	}
+	p.next++
+	id := fmt.Sprintf("pi_fake_%d", p.next)
+	intent := &Intent{
+		ID:           id,
+		Amount:       req.Amount,
+		Currency:     req.Currency,
+		Status:       IntentRequiresCapture,
+		ClientSecret: id + "_secret",
+	}
+	p.intents[id] = intent
+	if req.IdempotencyKey != "" {
+		p.keys[req.IdempotencyKey] = id
+	}
+	return *intent, nil
+}
+
+func (p *FakeProvider) Capture(ctx context.Context, intentID, idempotencyKey string) (Intent, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	intent, ok := p.intents[intentID]
+	if !ok {
+		return Intent{}, ErrIntentNotFound
+	}
+	switch intent.Status {
+	case IntentSucceeded:
+		return *intent, nil
+	case IntentRequiresCapture:
+	default:
+		return Intent{}, fmt.Errorf("intent %s cannot be captured in status %s", intentID, intent.Status)
+	}
+	if p.DeclineCapture {
+		intent.Status = IntentCanceled
+		return Intent{}, ErrDeclined
+	}
+	intent.Status = IntentSucceeded
+	return *intent, nil
+}
+
+func (p *FakeProvider) Refund(ctx context.Context, intentID string, amount int64, idempotencyKey string) (Refund, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if r, ok := p.refunds[idempotencyKey]; ok && idempotencyKey != "" {
+		return r, nil
+	}
+	intent, ok := p.intents[intentID]
+	if !ok {
+		return Refund{}, ErrIntentNotFound
+	}
+	if intent.Status != IntentSucceeded {
+		return Refund{}, fmt.Errorf("intent %s has not been captured", intentID)
+	}
+	if amount <= 0 || p.refunded[intentID]+amount > intent.Amount {
+		return Refund{}, fmt.Errorf("refund of %d exceeds the remaining amount of intent %s", amount, intentID)
+	}
+	p.refunded[intentID] += amount
+	p.next++
+	r := Refund{ID: fmt.Sprintf("re_fake_%d", p.next), IntentID: intentID, Amount: amount, Status: "succeeded"}
+	if idempotencyKey != "" {
+		p.refunds[idempotencyKey] = r
+	}
+	return r, nil
+}
+
+func (p *FakeProvider) VerifyWebhook(body []byte, header http.Header) (WebhookEvent, error) {
+	return parseWebhook(p.secret, header.Get(FakeSignatureHeader), body)
+}
+
+// SignedWebhook builds a webhook request body and headers about an intent,
+// as the provider would send them.
+func (p *FakeProvider) SignedWebhook(eventType, intentID string) ([]byte, http.Header) {
+	p.mu.Lock()
+	p.next++
+	id := fmt.Sprintf("evt_fake_%d", p.next)
+	p.mu.Unlock()
+	body, _ := json.Marshal(map[string]interface{}{
+		"id":   id,
+		"type": eventType,
+		"data": map[string]interface{}{"object": map[string]string{"id": intentID}},
+	})
+	header := http.Header{}
+	header.Set(FakeSignatureHeader, webhooks.Sign(p.secret, time.Now(), body))
+	return body, header
+}
@@ -0,0 +1,130 @@
+package payments
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"booking-app/internal/bookings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+)
+
+var validate = validator.New()
+
+// maxWebhookBody bounds the size of provider webhook requests
+const maxWebhookBody = 1 << 20
+
+type Handler struct {
+	store    *DBStore
+	provider PaymentProvider
+}
+
+func NewHandler(store *DBStore, provider PaymentProvider) *Handler {
+	return &Handler{store: store, provider: provider}
+}
+
+// StartPaymentHandler starts paying for bookings awaiting payment. The
+// response includes the client secret the customer authorizes it with.
+func (h *Handler) StartPaymentHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		BookingIDs []int `json:"booking_ids" validate:"required,min=1,max=50"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := validate.Struct(&input); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	p, err := h.store.Start(r.Context(), input.BookingIDs)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, p)
+}
+
+func (h *Handler) GetPaymentHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	p, err := h.store.GetPayment(r.Context(), id)
+	if err == nil && !canUse(r.Context(), p) {
+		err = ErrForbidden
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, p)
+}
+
+// CaptureHandler captures an authorized payment, confirming its bookings
+func (h *Handler) CaptureHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	p, err := h.store.Capture(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, p)
+}
+
+// WebhookHandler receives signed notifications from the payment provider.
+// It is not behind Auth; the signature authenticates the request.
+func (h *Handler) WebhookHandler(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBody))
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	event, err := h.provider.VerifyWebhook(body, r.Header)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := h.store.HandleWebhook(r.Context(), event); err != nil {
+		log.Printf("Failed to handle payment webhook %s: %v", event.ID, err)
+		http.Error(w, "Failed to handle webhook", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrPaymentNotFound), errors.Is(err, bookings.ErrBookingNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrForbidden), errors.Is(err, bookings.ErrForbidden):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, ErrPaymentNotPending), errors.Is(err, bookings.ErrNotPayable):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, ErrMixedCurrency):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, ErrDeclined):
+		http.Error(w, err.Error(), http.StatusPaymentRequired)
+	default:
+		log.Printf("Payment error: %v", err)
+		http.Error(w, "Payment provider error", http.StatusBadGateway)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
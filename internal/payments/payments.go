@@ -0,0 +1,40 @@
+package payments
+
+import "time"
+
+// Payment statuses
+const (
+	StatusPending  = "pending"
+	StatusCaptured = "captured"
+	StatusFailed   = "failed"
+)
+
+// JobExecuteRefund is the job type that sends a refund to the payment
+// provider. The refunds package handles it; payments queues it itself to
+// return money captured after a payment had failed.
+const JobExecuteRefund = "refund.execute"
+
+// RefundJob is the payload of a JobExecuteRefund job
+type RefundJob struct {
+	RefundID int `json:"refund_id"`
+}
+
+// Payment collects the price of one or more bookings through a provider.
+// ProviderRef is the provider's intent ID, set once the intent is created.
+// AuthorizedAt is set once the customer has authorized the intent and we
+// start capturing it.
+type Payment struct {
+	ID             int        `json:"id" db:"id"`
+	Provider       string     `json:"provider" db:"provider"`
+	ProviderRef    *string    `json:"provider_ref,omitempty" db:"provider_ref"`
+	UserID         *int       `json:"user_id,omitempty" db:"user_id"`
+	Amount         int64      `json:"amount" db:"amount"`
+	Currency       string     `json:"currency" db:"currency"`
+	RefundedAmount int64      `json:"refunded_amount" db:"refunded_amount"`
+	Status         string     `json:"status" db:"status"`
+	ClientSecret   *string    `json:"client_secret,omitempty" db:"client_secret"`
+	FailureReason  *string    `json:"failure_reason,omitempty" db:"failure_reason"`
+	AuthorizedAt   *time.Time `json:"authorized_at,omitempty" db:"authorized_at"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at" db:"updated_at"`
+}
@@ -0,0 +1,129 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestFakeProviderCaptureAndRefund(t *testing.T) {
+	ctx := context.Background()
+	p := NewFakeProvider("secret")
+	intent, err := p.CreateIntent(ctx, IntentRequest{Amount: 5000, Currency: "EUR", IdempotencyKey: "payment-1"})
+	if err != nil {
+		t.Fatalf("CreateIntent failed: %v", err)
+	}
+	again, err := p.CreateIntent(ctx, IntentRequest{Amount: 5000, Currency: "EUR", IdempotencyKey: "payment-1"})
+	if err != nil || again.ID != intent.ID {
+		t.Fatalf("Expected retried intent %s, got %s (err %v)", intent.ID, again.ID, err)
+	}
+	if _, err := p.Refund(ctx, intent.ID, 100, "refund-0"); err == nil {
+		t.Error("Expected error refunding an uncaptured intent, got nil")
+	}
+	captured, err := p.Capture(ctx, intent.ID, "capture-1")
+	if err != nil {
+		t.Fatalf("Capture failed: %v", err)
+	}
+	if captured.Status != IntentSucceeded {
+		t.Errorf("Expected status %s, got %s", IntentSucceeded, captured.Status)
+	}
+	if _, err := p.Refund(ctx, intent.ID, 3000, "refund-1"); err != nil {
+		t.Fatalf("Partial refund failed: %v", err)
+	}
+	if _, err := p.Refund(ctx, intent.ID, 3000, "refund-2"); err == nil {
+		t.Error("Expected error refunding more than was captured, got nil")
+	}
+	if _, err := p.Refund(ctx, intent.ID, 3000, "refund-1"); err != nil {
+		t.Errorf("Expected retried refund to succeed, got %v", err)
+	}
+}
+
+func TestFakeProviderDecline(t *testing.T) {
+	ctx := context.Background()
+	p := NewFakeProvider("secret")
+	p.DeclineCapture = true
+	intent, err := p.CreateIntent(ctx, IntentRequest{Amount: 100, Currency: "USD"})
+	if err != nil {
+		t.Fatalf("CreateIntent failed: %v", err)
+	}
+	if _, err := p.Capture(ctx, intent.ID, ""); !errors.Is(err, ErrDeclined) {
+		t.Errorf("Expected ErrDeclined, got %v", err)
+	}
+}
+
+func TestFakeProviderWebhook(t *testing.T) {
+	p := NewFakeProvider("secret")
+	body, header := p.SignedWebhook(WebhookCapturable, "pi_fake_1")
+	event, err := p.VerifyWebhook(body, header)
+	if err != nil {
+		t.Fatalf("VerifyWebhook failed: %v", err)
+	}
+	if event.Type != WebhookCapturable || event.IntentID != "pi_fake_1" {
+		t.Errorf("Unexpected event %+v", event)
+	}
+	if _, err := NewFakeProvider("other").VerifyWebhook(body, header); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Expected ErrInvalidSignature for the wrong secret, got %v", err)
+	}
+}
+
+func TestFakeProviderWebhookWithoutSecret(t *testing.T) {
+	p := NewFakeProvider("")
+	body, header := p.SignedWebhook(WebhookSucceeded, "pi_fake_1")
+	if _, err := p.VerifyWebhook(body, header); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Expected ErrInvalidSignature without a secret, got %v", err)
+	}
+}
+
+func TestStripeProvider(t *testing.T) {
+	var gotKey, gotIdempotency string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotKey, _, _ = r.BasicAuth()
+		gotIdempotency = r.Header.Get("Idempotency-Key")
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("Failed to parse form: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/v1/payment_intents":
+			if r.Form.Get("capture_method") != "manual" || r.Form.Get("currency") != "usd" || r.Form.Get("metadata[payment_id]") != "7" {
+				t.Errorf("Unexpected intent form %v", r.Form)
+			}
+			w.Write([]byte(`{"id":"pi_1","amount":2500,"currency":"usd","status":"requires_payment_method","client_secret":"pi_1_secret"}`))
+		case "/v1/payment_intents/pi_1/capture":
+			w.WriteHeader(http.StatusPaymentRequired)
+			w.Write([]byte(`{"error":{"type":"card_error","code":"card_declined","message":"Your card was declined."}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":{"type":"invalid_request_error","message":"No such resource"}}`))
+		}
+	}))
+	defer server.Close()
+
+	p := NewStripeProvider("sk_test", "whsec", time.Second)
+	p.baseURL = server.URL
+	ctx := context.Background()
+	intent, err := p.CreateIntent(ctx, IntentRequest{
+		Amount:         2500,
+		Currency:       "USD",
+		IdempotencyKey: "payment-7",
+		Metadata:       map[string]string{"payment_id": "7"},
+	})
+	if err != nil {
+		t.Fatalf("CreateIntent failed: %v", err)
+	}
+	if intent.ID != "pi_1" || intent.Currency != "USD" || intent.ClientSecret != "pi_1_secret" {
+		t.Errorf("Unexpected intent %+v", intent)
+	}
+	if gotKey != "sk_test" || gotIdempotency != "payment-7" {
+		t.Errorf("Expected API key and idempotency key to be sent, got %q and %q", gotKey, gotIdempotency)
+	}
+	if _, err := p.Capture(ctx, "pi_1", "capture-7"); !errors.Is(err, ErrDeclined) {
+		t.Errorf("Expected ErrDeclined, got %v", err)
+	}
+	if _, err := p.Refund(ctx, "pi_2", 100, ""); !errors.Is(err, ErrIntentNotFound) {
+		t.Errorf("Expected ErrIntentNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,110 @@
+package payments
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StripeSignatureHeader carries the signature of Stripe webhooks
+const StripeSignatureHeader = "Stripe-Signature"
+
+const stripeBaseURL = "https://api.stripe.com"
+
+// StripeProvider talks to the Stripe PaymentIntents API. Intents are created
+// with manual capture so the customer's card is only charged by Capture.
+type StripeProvider struct {
+	apiKey        string
+	webhookSecret string
+	baseURL       string
+	client        *http.Client
+}
+
+func NewStripeProvider(apiKey, webhookSecret string, timeout time.Duration) *StripeProvider {
+	return &StripeProvider{
+		apiKey:        apiKey,
+		webhookSecret: webhookSecret,
+		baseURL:       stripeBaseURL,
+		client:        &http.Client{Timeout: timeout},
+	}
+}
+
+func (p *StripeProvider) Name() string {
+	return "stripe"
+}
+
+func (p *StripeProvider) CreateIntent(ctx context.Context, req IntentRequest) (Intent, error) {
+	form := url.Values{}
+	form.Set("amount", strconv.FormatInt(req.Amount, 10))
+	form.Set("currency", strings.ToLower(req.Currency))
+	form.Set("capture_method", "manual")
+	for k, v := range req.Metadata {
+		form.Set("metadata["+k+"]", v)
+	}
+	var intent Intent
+	err := p.post(ctx, "/v1/payment_intents", form, req.IdempotencyKey, &intent)
+	intent.Currency = strings.ToUpper(intent.Currency)
+	return intent, err
+}
+
+func (p *StripeProvider) Capture(ctx context.Context, intentID, idempotencyKey string) (Intent, error) {
+	var intent Intent
+	err := p.post(ctx, "/v1/payment_intents/"+url.PathEscape(intentID)+"/capture", url.Values{}, idempotencyKey, &intent)
+	intent.Currency = strings.ToUpper(intent.Currency)
+	return intent, err
+}
+
+func (p *StripeProvider) Refund(ctx context.Context, intentID string, amount int64, idempotencyKey string) (Refund, error) {
+	form := url.Values{}
+	form.Set("payment_intent", intentID)
+	form.Set("amount", strconv.FormatInt(amount, 10))
+	var r Refund
+	err := p.post(ctx, "/v1/refunds", form, idempotencyKey, &r)
+	return r, err
+}
+
+func (p *StripeProvider) VerifyWebhook(body []byte, header http.Header) (WebhookEvent, error) {
+	return parseWebhook(p.webhookSecret, header.Get(StripeSignatureHeader), body)
+}
+
+// post sends a form-encoded API request and decodes the JSON response into
+// out. Card errors are reported as ErrDeclined.
+func (p *StripeProvider) post(ctx context.Context, path string, form url.Values, idempotencyKey string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+path, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(p.apiKey, "")
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if idempotencyKey != "" {
+		req.Header.Set("Idempotency-Key", idempotencyKey)
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		var body struct {
+			Error struct {
+				Type    string `json:"type"`
+				Code    string `json:"code"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&body)
+		if resp.StatusCode == http.StatusPaymentRequired || body.Error.Type == "card_error" {
+			return fmt.Errorf("%w: %s", ErrDeclined, body.Error.Message)
+		}
+		if resp.StatusCode == http.StatusNotFound {
+			return fmt.Errorf("%w: %s", ErrIntentNotFound, body.Error.Message)
+		}
+		return fmt.Errorf("stripe: %s %s: status %d: %s", http.MethodPost, path, resp.StatusCode, body.Error.Message)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,142 @@
+package promos
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// selectCodes selects promo codes together with their current use count
+const selectCodes = `SELECT p.*, (
+              SELECT count(*) FROM promo_redemptions r JOIN bookings b ON b.id = r.booking_id
+              WHERE r.promo_code_id = p.id AND b.is_active) AS uses
+              FROM promo_codes p`
+
+// DBStore manages promo codes in PostgreSQL
+type DBStore struct {
+	db *sqlx.DB
+}
+
+func NewDBStore(db *sqlx.DB) *DBStore {
+	return &DBStore{db: db}
+}
+
+func (s *DBStore) Create(ctx context.Context, p PromoCode) (PromoCode, error) {
+	if err := p.check(); err != nil {
+		return PromoCode{}, err
+	}
+	rows, err := sqlx.NamedQueryContext(ctx, s.db, `INSERT INTO promo_codes (code, kind, percent_off, amount_off, currency,
+              max_uses, max_uses_per_user, valid_from, valid_until, event_id, ticket_type_id, created_by)
+              VALUES (:code, :kind, :percent_off, :amount_off, :currency,
+              :max_uses, :max_uses_per_user, :valid_from, :valid_until, :event_id, :ticket_type_id, :created_by)
+              ON CONFLICT ((upper(code))) DO NOTHING RETURNING id`, p)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+		return PromoCode{}, fmt.Errorf("%w: event or ticket type does not exist", ErrInvalid)
+	}
+	if err != nil {
+		return PromoCode{}, err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return PromoCode{}, err
+		}
+		return PromoCode{}, ErrExists
+	}
+	var id int
+	if err := rows.Scan(&id); err != nil {
+		return PromoCode{}, err
+	}
+	return s.Get(ctx, id)
+}
+
+func (s *DBStore) Get(ctx context.Context, id int) (PromoCode, error) {
+	var p PromoCode
+	err := s.db.GetContext(ctx, &p, selectCodes+" WHERE p.id = $1", id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return PromoCode{}, ErrNotFound
+	}
+	return p, err
+}
+
+func (s *DBStore) List(ctx context.Context) ([]PromoCode, error) {
+	list := []PromoCode{}
+	err := s.db.SelectContext(ctx, &list, selectCodes+" ORDER BY p.id DESC")
+	return list, err
+}
+
+// Deactivate stops a code from being redeemed. Bookings that already used it
+// keep their discount.
+func (s *DBStore) Deactivate(ctx context.Context, id int) (PromoCode, error) {
+	res, err := s.db.ExecContext(ctx, "UPDATE promo_codes SET active = FALSE, updated_at = now() WHERE id = $1", id)
+	if err != nil {
+		return PromoCode{}, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return PromoCode{}, err
+	}
+	if n == 0 {
+		return PromoCode{}, ErrNotFound
+	}
+	return s.Get(ctx, id)
+}
+
+// ReserveTx checks that a code can be redeemed for the target by userID and
+// returns it with the discount it gives. The code's row stays locked until
+// tx ends, so concurrent redemptions are counted one at a time and a capped
+// code is never oversubscribed; the caller must call RedeemTx in tx.
+func ReserveTx(ctx context.Context, tx *sqlx.Tx, code string, t Target, userID *int) (PromoCode, int64, error) {
+	var p PromoCode
+	err := tx.GetContext(ctx, &p, "SELECT *, 0 AS uses FROM promo_codes WHERE upper(code) = upper($1) FOR UPDATE", code)
+	if errors.Is(err, sql.ErrNoRows) {
+		return PromoCode{}, 0, ErrNotFound
+	}
+	if err != nil {
+		return PromoCode{}, 0, err
+	}
+	if err := p.Check(t, time.Now()); err != nil {
+		return PromoCode{}, 0, err
+	}
+	if p.MaxUses != nil {
+		if err := countUses(ctx, tx, &p.Uses, p.ID, nil); err != nil {
+			return PromoCode{}, 0, err
+		}
+		if p.Uses >= *p.MaxUses {
+			return PromoCode{}, 0, ErrExhausted
+		}
+	}
+	if p.MaxUsesPerUser != nil {
+		if userID == nil {
+			return PromoCode{}, 0, ErrNotApplicable
+		}
+		var used int
+		if err := countUses(ctx, tx, &used, p.ID, userID); err != nil {
+			return PromoCode{}, 0, err
+		}
+		if used >= *p.MaxUsesPerUser {
+			return PromoCode{}, 0, ErrUserLimit
+		}
+	}
+	return p, p.Discount(t.Price), nil
+}
+
+// RedeemTx records that a booking used a code reserved with ReserveTx
+func RedeemTx(ctx context.Context, tx *sqlx.Tx, promoID, bookingID int, userID *int, discount int64) error {
+	_, err := tx.ExecContext(ctx, `INSERT INTO promo_redemptions (promo_code_id, booking_id, user_id, discount)
+              VALUES ($1, $2, $3, $4)`, promoID, bookingID, userID, discount)
+	return err
+}
+
+// countUses counts redemptions of a code by active bookings, optionally
+// only those of one user
+func countUses(ctx context.Context, tx *sqlx.Tx, dest *int, promoID int, userID *int) error {
+	return tx.GetContext(ctx, dest, `SELECT count(*) FROM promo_redemptions r JOIN bookings b ON b.id = r.booking_id
+              WHERE r.promo_code_id = $1 AND b.is_active AND ($2::int IS NULL OR r.user_id = $2)`, promoID, userID)
+}
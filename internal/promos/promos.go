@@ -0,0 +1,110 @@
+package promos
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Discount kinds
+const (
+	KindPercent = "percent"
+	KindFixed   = "fixed"
+)
+
+var (
+	ErrNotFound      = errors.New("promo code not found")
+	ErrExists        = errors.New("promo code already exists")
+	ErrInvalid       = errors.New("invalid promo code")
+	ErrNotValidNow   = errors.New("promo code is not valid at this time")
+	ErrNotApplicable = errors.New("promo code does not apply to this booking")
+	ErrExhausted     = errors.New("promo code has been fully redeemed")
+	ErrUserLimit     = errors.New("promo code has already been used the maximum number of times by this user")
+)
+
+// PromoCode discounts the price of bookings. Percent codes take PercentOff
+// percent off; fixed codes take AmountOff minor units off prices in
+// Currency. Usage caps count redemptions whose booking is still active, so
+// cancelled bookings give their use back.
+type PromoCode struct {
+	ID             int        `json:"id" db:"id"`
+	Code           string     `json:"code" db:"code" validate:"required,max=64,alphanumunicode"`
+	Kind           string     `json:"kind" db:"kind" validate:"required,oneof=percent fixed"`
+	PercentOff     *int       `json:"percent_off,omitempty" db:"percent_off" validate:"omitempty,min=1,max=100"`
+	AmountOff      *int64     `json:"amount_off,omitempty" db:"amount_off" validate:"omitempty,min=1"`
+	Currency       *string    `json:"currency,omitempty" db:"currency" validate:"omitempty,len=3,uppercase"`
+	MaxUses        *int       `json:"max_uses" db:"max_uses" validate:"omitempty,min=1"`
+	MaxUsesPerUser *int       `json:"max_uses_per_user" db:"max_uses_per_user" validate:"omitempty,min=1"`
+	ValidFrom      *time.Time `json:"valid_from" db:"valid_from"`
+	ValidUntil     *time.Time `json:"valid_until" db:"valid_until"`
+	EventID        *int       `json:"event_id" db:"event_id"`
+	TicketTypeID   *int       `json:"ticket_type_id" db:"ticket_type_id"`
+	Active         bool       `json:"active" db:"active"`
+	CreatedBy      *int       `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at" db:"updated_at"`
+	// Uses is the number of active bookings that redeemed the code
+	Uses int `json:"uses" db:"uses"`
+}
+
+// Target is the booking a code is being applied to
+type Target struct {
+	EventID      *int
+	TicketTypeID *int
+	Price        int64
+	Currency     string
+}
+
+// check verifies that the fields the code's kind needs are set and that
+// its validity period, when bounded at both ends, is not empty
+func (p PromoCode) check() error {
+	switch {
+	case p.ValidFrom != nil && p.ValidUntil != nil && !p.ValidUntil.After(*p.ValidFrom):
+		return fmt.Errorf("%w: valid_until must be after valid_from", ErrInvalid)
+	case p.Kind == KindPercent && p.PercentOff == nil:
+		return fmt.Errorf("%w: percent codes need percent_off", ErrInvalid)
+	case p.Kind == KindFixed && (p.AmountOff == nil || p.Currency == nil):
+		return fmt.Errorf("%w: fixed codes need amount_off and currency", ErrInvalid)
+	}
+	return nil
+}
+
+// Check returns why the code cannot discount the target at now, ignoring
+// usage caps, or nil if it can
+func (p PromoCode) Check(t Target, now time.Time) error {
+	if !p.Active {
+		return ErrNotFound
+	}
+	if (p.ValidFrom != nil && now.Before(*p.ValidFrom)) || (p.ValidUntil != nil && !now.Before(*p.ValidUntil)) {
+		return ErrNotValidNow
+	}
+	if t.Price <= 0 {
+		return ErrNotApplicable
+	}
+	if p.EventID != nil && (t.EventID == nil || *t.EventID != *p.EventID) {
+		return ErrNotApplicable
+	}
+	if p.TicketTypeID != nil && (t.TicketTypeID == nil || *t.TicketTypeID != *p.TicketTypeID) {
+		return ErrNotApplicable
+	}
+	if p.Kind == KindFixed && (p.Currency == nil || *p.Currency != t.Currency) {
+		return ErrNotApplicable
+	}
+	return nil
+}
+
+// Discount returns how much the code takes off price. Percentages are
+// rounded to the nearest minor unit and no discount exceeds the price.
+func (p PromoCode) Discount(price int64) int64 {
+	var d int64
+	switch {
+	case p.Kind == KindPercent && p.PercentOff != nil:
+		d = (price*int64(*p.PercentOff) + 50) / 100
+	case p.Kind == KindFixed && p.AmountOff != nil:
+		d = *p.AmountOff
+	}
+	if d > price {
+		return price
+	}
+	return d
+}
@@ -0,0 +1,90 @@
+package promos
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDiscount(t *testing.T) {
+	percent := func(n int) PromoCode { return PromoCode{Kind: KindPercent, PercentOff: &n} }
+	fixed := func(n int64) PromoCode { return PromoCode{Kind: KindFixed, AmountOff: &n} }
+	tests := []struct {
+		name  string
+		code  PromoCode
+		price int64
+		want  int64
+	}{
+		{"percent", percent(20), 5000, 1000},
+		{"percent rounds half up", percent(15), 999, 150},
+		{"full percent", percent(100), 2500, 2500},
+		{"fixed", fixed(500), 2500, 500},
+		{"fixed capped at price", fixed(5000), 2500, 2500},
+	}
+	for _, tt := range tests {
+		if got := tt.code.Discount(tt.price); got != tt.want {
+			t.Errorf("%s: Discount(%d) = %d, want %d", tt.name, tt.price, got, tt.want)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	past, future := now.Add(-time.Hour), now.Add(time.Hour)
+	eventID, otherEvent, typeID := 1, 2, 10
+	eur, usd := "EUR", "USD"
+	amount := int64(100)
+	target := Target{EventID: &eventID, TicketTypeID: &typeID, Price: 2000, Currency: "EUR"}
+	tests := []struct {
+		name string
+		code PromoCode
+		want error
+	}{
+		{"active", PromoCode{Active: true}, nil},
+		{"inactive", PromoCode{}, ErrNotFound},
+		{"not started", PromoCode{Active: true, ValidFrom: &future}, ErrNotValidNow},
+		{"expired", PromoCode{Active: true, ValidUntil: &past}, ErrNotValidNow},
+		{"within window", PromoCode{Active: true, ValidFrom: &past, ValidUntil: &future}, nil},
+		{"same event", PromoCode{Active: true, EventID: &eventID}, nil},
+		{"other event", PromoCode{Active: true, EventID: &otherEvent}, ErrNotApplicable},
+		{"same ticket type", PromoCode{Active: true, TicketTypeID: &typeID}, nil},
+		{"fixed in same currency", PromoCode{Active: true, Kind: KindFixed, AmountOff: &amount, Currency: &eur}, nil},
+		{"fixed in other currency", PromoCode{Active: true, Kind: KindFixed, AmountOff: &amount, Currency: &usd}, ErrNotApplicable},
+	}
+	for _, tt := range tests {
+		if err := tt.code.Check(target, now); !errors.Is(err, tt.want) {
+			t.Errorf("%s: Check = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+	if err := (PromoCode{Active: true}).Check(Target{}, now); !errors.Is(err, ErrNotApplicable) {
+		t.Errorf("Expected ErrNotApplicable for a free booking, got %v", err)
+	}
+}
+
+func TestCheckValidity(t *testing.T) {
+	from := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+	until := from.Add(24 * time.Hour)
+	percent := 10
+	tests := []struct {
+		name        string
+		from, until *time.Time
+		wantErr     bool
+	}{
+		{"open", nil, nil, false},
+		{"only from", &from, nil, false},
+		{"only until", nil, &until, false},
+		{"until after from", &from, &until, false},
+		{"until before from", &until, &from, true},
+		{"empty period", &from, &from, true},
+	}
+	for _, tt := range tests {
+		p := PromoCode{Code: "SPRING", Kind: KindPercent, PercentOff: &percent, ValidFrom: tt.from, ValidUntil: tt.until}
+		if err := validate.Struct(&p); err != nil {
+			t.Errorf("%s: validate: %v", tt.name, err)
+		}
+		err := p.check()
+		if (err != nil) != tt.wantErr || (err != nil && !errors.Is(err, ErrInvalid)) {
+			t.Errorf("%s: check = %v, want error %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
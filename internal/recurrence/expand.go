@@ -0,0 +1,240 @@
+package recurrence
+
+import (
+	"sort"
+	"time"
+)
+
+// MaxOccurrences bounds how many occurrences a single expansion returns
+const MaxOccurrences = 1000
+
+// maxPeriods bounds how many periods are scanned, so rules that can never
+// match, such as the 30th of February, end
+const maxPeriods = 10000
+
+// Set is a rule anchored at its first possible occurrence, minus exception
+// dates. Occurrences keep Start's wall-clock time in Start's location, so a
+// class at 18:00 stays at 18:00 across daylight saving changes. As in most
+// implementations, Start is only an occurrence if it matches the rule.
+type Set struct {
+	Start   time.Time
+	Rule    Rule
+	Exclude []time.Time
+}
+
+// Between returns the occurrences in [from, to), at most MaxOccurrences
+func (s Set) Between(from, to time.Time) []time.Time {
+	var out []time.Time
+	s.each(func(t time.Time) bool {
+		if !t.Before(to) {
+			return false
+		}
+		if !t.Before(from) && !s.excluded(t) {
+			out = append(out, t)
+		}
+		return len(out) < MaxOccurrences
+	})
+	return out
+}
+
+// All returns every occurrence of a rule with COUNT or UNTIL, at most
+// MaxOccurrences. Rules without an end are cut off at MaxOccurrences too.
+func (s Set) All() []time.Time {
+	var out []time.Time
+	s.each(func(t time.Time) bool {
+		if !s.excluded(t) {
+			out = append(out, t)
+		}
+		return len(out) < MaxOccurrences
+	})
+	return out
+}
+
+// Finite reports whether the rule ends by COUNT or UNTIL
+func (r Rule) Finite() bool {
+	return r.Count > 0 || r.Until != nil
+}
+
+func (s Set) excluded(t time.Time) bool {
+	for _, ex := range s.Exclude {
+		if ex.Equal(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// each calls fn with occurrences in order until fn returns false or the
+// rule ends. COUNT includes excluded occurrences, as RFC 5545 requires.
+func (s Set) each(fn func(time.Time) bool) {
+	r := s.Rule
+	if r.Interval <= 0 {
+		r.Interval = 1
+	}
+	loc := s.Start.Location()
+	start := dateOf(s.Start)
+	hour, min, sec := s.Start.Clock()
+	count := 0
+	for k := 0; k < maxPeriods; k++ {
+		for _, d := range r.period(start, k) {
+			t := time.Date(d.Year(), d.Month(), d.Day(), hour, min, sec, s.Start.Nanosecond(), loc)
+			if t.Before(s.Start) {
+				continue
+			}
+			if r.Until != nil && t.After(*r.Until) {
+				return
+			}
+			count++
+			if !fn(t) || (r.Count > 0 && count >= r.Count) {
+				return
+			}
+		}
+	}
+}
+
+// period returns the dates matching the rule in the k-th period after the
+// one containing start, in order
+func (r Rule) period(start time.Time, k int) []time.Time {
+	var days []time.Time
+	switch r.Freq {
+	case Daily:
+		d := start.AddDate(0, 0, k*r.Interval)
+		if r.matchWeekday(d) && r.matchMonthDay(d) {
+			days = []time.Time{d}
+		}
+	case Weekly:
+		offset := (int(start.Weekday()) - int(r.WeekStart) + 7) % 7
+		first := start.AddDate(0, 0, -offset+7*k*r.Interval)
+		for i := 0; i < 7; i++ {
+			d := first.AddDate(0, 0, i)
+			if r.matchWeekday(d) && (len(r.ByDay) > 0 || d.Weekday() == start.Weekday()) {
+				days = append(days, d)
+			}
+		}
+	case Monthly:
+		month := time.Date(start.Year(), start.Month()+time.Month(k*r.Interval), 1, 0, 0, 0, 0, time.UTC)
+		days = r.inSpan(month, month.AddDate(0, 1, 0), start)
+	case Yearly:
+		year := time.Date(start.Year()+k*r.Interval, 1, 1, 0, 0, 0, 0, time.UTC)
+		if len(r.ByMonth) == 0 && len(r.ByDay) > 0 && len(r.ByMonthDay) == 0 {
+			days = r.inSpan(year, year.AddDate(1, 0, 0), start)
+			break
+		}
+		months := r.ByMonth
+		if len(months) == 0 && len(r.ByMonthDay) == 0 {
+			months = []time.Month{start.Month()}
+		}
+		for m := time.January; m <= time.December; m++ {
+			if len(months) > 0 && !containsMonth(months, m) {
+				continue
+			}
+			month := time.Date(year.Year(), m, 1, 0, 0, 0, 0, time.UTC)
+			days = append(days, r.inSpan(month, month.AddDate(0, 1, 0), start)...)
+		}
+	}
+	if len(r.ByMonth) > 0 {
+		kept := days[:0]
+		for _, d := range days {
+			if containsMonth(r.ByMonth, d.Month()) {
+				kept = append(kept, d)
+			}
+		}
+		days = kept
+	}
+	return days
+}
+
+// inSpan returns the days in [from, to) matching BYDAY and BYMONTHDAY, or
+// start's day of the month if neither is given. BYDAY ordinals count within
+// the span and BYMONTHDAY within each month.
+func (r Rule) inSpan(from, to, start time.Time) []time.Time {
+	var all []time.Time
+	for d := from; d.Before(to); d = d.AddDate(0, 0, 1) {
+		all = append(all, d)
+	}
+	var days []time.Time
+	switch {
+	case len(r.ByDay) == 0 && len(r.ByMonthDay) == 0:
+		for _, d := range all {
+			if d.Day() == start.Day() {
+				days = append(days, d)
+			}
+		}
+	case len(r.ByDay) == 0:
+		for _, d := range all {
+			if r.matchMonthDay(d) {
+				days = append(days, d)
+			}
+		}
+	default:
+		picked := make(map[time.Time]bool)
+		for _, wd := range r.ByDay {
+			var same []time.Time
+			for _, d := range all {
+				if d.Weekday() == wd.Weekday {
+					same = append(same, d)
+				}
+			}
+			switch {
+			case wd.N == 0:
+				for _, d := range same {
+					picked[d] = true
+				}
+			case wd.N > 0 && wd.N <= len(same):
+				picked[same[wd.N-1]] = true
+			case wd.N < 0 && -wd.N <= len(same):
+				picked[same[len(same)+wd.N]] = true
+			}
+		}
+		for _, d := range all {
+			if picked[d] && r.matchMonthDay(d) {
+				days = append(days, d)
+			}
+		}
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].Before(days[j]) })
+	return days
+}
+
+// matchWeekday reports whether d's weekday is in BYDAY, ignoring ordinals
+func (r Rule) matchWeekday(d time.Time) bool {
+	if len(r.ByDay) == 0 {
+		return true
+	}
+	for _, wd := range r.ByDay {
+		if wd.Weekday == d.Weekday() {
+			return true
+		}
+	}
+	return false
+}
+
+// matchMonthDay reports whether d is one of the BYMONTHDAY days, which
+// count back from the end of the month when negative
+func (r Rule) matchMonthDay(d time.Time) bool {
+	if len(r.ByMonthDay) == 0 {
+		return true
+	}
+	last := time.Date(d.Year(), d.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	for _, md := range r.ByMonthDay {
+		if md == d.Day() || (md < 0 && last+md+1 == d.Day()) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsMonth(months []time.Month, m time.Month) bool {
+	for _, x := range months {
+		if x == m {
+			return true
+		}
+	}
+	return false
+}
+
+// dateOf returns t's local calendar date as midnight UTC, so date arithmetic
+// is not affected by daylight saving changes
+func dateOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
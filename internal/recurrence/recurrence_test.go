@@ -0,0 +1,155 @@
+package recurrence
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	// Written in the order String formats them, so they round-trip
+	valid := []string{
+		"FREQ=WEEKLY;COUNT=10;BYDAY=MO,WE",
+		"FREQ=MONTHLY;BYDAY=-1FR",
+		"FREQ=YEARLY;BYDAY=2SU;BYMONTH=3",
+		"FREQ=DAILY;INTERVAL=2;UNTIL=20260401T000000Z",
+		"FREQ=MONTHLY;BYMONTHDAY=1,-1",
+		"FREQ=WEEKLY;BYDAY=SA;WKST=SU",
+	}
+	for _, s := range valid {
+		r, err := Parse(s)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", s, err)
+			continue
+		}
+		if r.String() != s {
+			t.Errorf("Parse(%q).String() = %q", s, r.String())
+		}
+	}
+	if r, err := Parse("RRULE:freq=daily"); err != nil || r.Freq != Daily {
+		t.Errorf("Expected a prefixed lower-case rule to parse, got %+v, %v", r, err)
+	}
+
+	invalid := []string{
+		"",
+		"BYDAY=MO",
+		"FREQ=HOURLY",
+		"FREQ=WEEKLY;COUNT=0",
+		"FREQ=WEEKLY;COUNT=3;UNTIL=20260101",
+		"FREQ=WEEKLY;BYDAY=1MO",
+		"FREQ=WEEKLY;BYMONTHDAY=3",
+		"FREQ=DAILY;BYDAY=XX",
+		"FREQ=MONTHLY;BYMONTHDAY=32",
+		"FREQ=DAILY;BYSETPOS=1",
+		"FREQ=DAILY;FREQ=WEEKLY",
+		"FREQ=DAILY;COUNT",
+	}
+	for _, s := range invalid {
+		if _, err := Parse(s); !errors.Is(err, ErrInvalidRule) {
+			t.Errorf("Parse(%q): expected ErrInvalidRule, got %v", s, err)
+		}
+	}
+}
+
+func TestExpand(t *testing.T) {
+	// 2026-01-05 is a Monday
+	start := time.Date(2026, 1, 5, 18, 0, 0, 0, time.UTC)
+	tests := []struct {
+		rule  string
+		start time.Time
+		want  []string
+	}{
+		{"FREQ=DAILY;COUNT=3", start, []string{"2026-01-05", "2026-01-06", "2026-01-07"}},
+		{"FREQ=DAILY;INTERVAL=10;COUNT=3", start, []string{"2026-01-05", "2026-01-15", "2026-01-25"}},
+		{"FREQ=WEEKLY;COUNT=3", start, []string{"2026-01-05", "2026-01-12", "2026-01-19"}},
+		{"FREQ=WEEKLY;BYDAY=MO,TH;COUNT=4", start, []string{"2026-01-05", "2026-01-08", "2026-01-12", "2026-01-15"}},
+		{"FREQ=WEEKLY;INTERVAL=2;BYDAY=TU;COUNT=3", start, []string{"2026-01-06", "2026-01-20", "2026-02-03"}},
+		{"FREQ=WEEKLY;BYDAY=MO;UNTIL=20260119T180000Z", start, []string{"2026-01-05", "2026-01-12", "2026-01-19"}},
+		{"FREQ=MONTHLY;COUNT=3", start, []string{"2026-01-05", "2026-02-05", "2026-03-05"}},
+		{"FREQ=MONTHLY;BYDAY=-1FR;COUNT=3", start, []string{"2026-01-30", "2026-02-27", "2026-03-27"}},
+		{"FREQ=MONTHLY;BYDAY=2MO;COUNT=2", start, []string{"2026-01-12", "2026-02-09"}},
+		{"FREQ=MONTHLY;BYMONTHDAY=-1;COUNT=3", start, []string{"2026-01-31", "2026-02-28", "2026-03-31"}},
+		// Friday the 13th
+		{"FREQ=MONTHLY;BYDAY=FR;BYMONTHDAY=13;COUNT=2", start, []string{"2026-02-13", "2026-03-13"}},
+		// Months without a 31st are skipped
+		{"FREQ=MONTHLY;COUNT=3", time.Date(2026, 1, 31, 9, 0, 0, 0, time.UTC), []string{"2026-01-31", "2026-03-31", "2026-05-31"}},
+		{"FREQ=YEARLY;COUNT=2", start, []string{"2026-01-05", "2027-01-05"}},
+		{"FREQ=YEARLY;BYMONTH=3;BYDAY=-1SU;COUNT=2", start, []string{"2026-03-29", "2027-03-28"}},
+		{"FREQ=YEARLY;BYDAY=1MO;COUNT=2", start, []string{"2026-01-05", "2027-01-04"}},
+		{"FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=29;COUNT=2", start, []string{"2028-02-29", "2032-02-29"}},
+	}
+	for _, tt := range tests {
+		r, err := Parse(tt.rule)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tt.rule, err)
+		}
+		got := dates(Set{Start: tt.start, Rule: r}.All())
+		if !equal(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.rule, got, tt.want)
+		}
+	}
+}
+
+func TestExclude(t *testing.T) {
+	start := time.Date(2026, 1, 5, 18, 0, 0, 0, time.UTC)
+	r, _ := Parse("FREQ=WEEKLY;COUNT=4")
+	s := Set{Start: start, Rule: r, Exclude: []time.Time{start.AddDate(0, 0, 7)}}
+	// COUNT includes the excluded occurrence
+	want := []string{"2026-01-05", "2026-01-19", "2026-01-26"}
+	if got := dates(s.All()); !equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestBetween(t *testing.T) {
+	start := time.Date(2026, 1, 5, 18, 0, 0, 0, time.UTC)
+	r, _ := Parse("FREQ=WEEKLY")
+	s := Set{Start: start, Rule: r}
+	got := dates(s.Between(time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 3, 17, 0, 0, 0, 0, time.UTC)))
+	want := []string{"2026-03-02", "2026-03-09", "2026-03-16"}
+	if !equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if n := len(s.All()); n != MaxOccurrences {
+		t.Errorf("Expected an endless rule to stop at %d occurrences, got %d", MaxOccurrences, n)
+	}
+}
+
+func TestWallClockAcrossDaylightSaving(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("time zone data not available: %v", err)
+	}
+	r, _ := Parse("FREQ=WEEKLY;COUNT=3")
+	// Clocks go forward on 2026-03-29
+	s := Set{Start: time.Date(2026, 3, 22, 18, 0, 0, 0, berlin), Rule: r}
+	for _, occ := range s.All() {
+		if occ.Hour() != 18 {
+			t.Errorf("Expected 18:00 local, got %v", occ)
+		}
+	}
+	all := s.All()
+	if diff := all[1].Sub(all[0]); diff != 7*24*time.Hour-time.Hour {
+		t.Errorf("Expected a 167 hour gap across the change, got %v", diff)
+	}
+}
+
+func dates(ts []time.Time) []string {
+	out := make([]string, len(ts))
+	for i, t := range ts {
+		out[i] = t.Format("2006-01-02")
+	}
+	return out
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,241 @@
+// Package recurrence parses RFC 5545 recurrence rules and expands them into
+// occurrences. It supports the parts of RRULE used for scheduling: FREQ of
+// DAILY, WEEKLY, MONTHLY or YEARLY with INTERVAL, COUNT, UNTIL, BYDAY
+// (with ordinals such as 2MO or -1FR for monthly and yearly rules),
+// BYMONTHDAY, BYMONTH and WKST.
+package recurrence
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Frequencies
+const (
+	Daily   = "DAILY"
+	Weekly  = "WEEKLY"
+	Monthly = "MONTHLY"
+	Yearly  = "YEARLY"
+)
+
+var ErrInvalidRule = errors.New("invalid recurrence rule")
+
+// WeekdayNum is a BYDAY entry: a weekday and, for monthly and yearly rules,
+// an optional ordinal counted from the start (positive) or end (negative)
+// of the period. N is zero for every such weekday.
+type WeekdayNum struct {
+	N       int
+	Weekday time.Weekday
+}
+
+// Rule is a parsed RRULE
+type Rule struct {
+	Freq       string
+	Interval   int
+	Count      int
+	Until      *time.Time
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+	ByMonth    []time.Month
+	WeekStart  time.Weekday
+}
+
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// Parse parses a rule such as "FREQ=WEEKLY;BYDAY=MO,WE;COUNT=10". A leading
+// "RRULE:" is allowed.
+func Parse(s string) (Rule, error) {
+	r := Rule{Interval: 1, WeekStart: time.Monday}
+	s = strings.TrimPrefix(strings.TrimSpace(s), "RRULE:")
+	if s == "" {
+		return Rule{}, fmt.Errorf("%w: empty rule", ErrInvalidRule)
+	}
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(s, ";") {
+		name, value, ok := strings.Cut(part, "=")
+		name = strings.ToUpper(name)
+		if !ok || value == "" {
+			return Rule{}, fmt.Errorf("%w: malformed part %q", ErrInvalidRule, part)
+		}
+		if seen[name] {
+			return Rule{}, fmt.Errorf("%w: %s given twice", ErrInvalidRule, name)
+		}
+		seen[name] = true
+		var err error
+		switch name {
+		case "FREQ":
+			r.Freq = strings.ToUpper(value)
+			if r.Freq != Daily && r.Freq != Weekly && r.Freq != Monthly && r.Freq != Yearly {
+				err = fmt.Errorf("unsupported frequency %s", value)
+			}
+		case "INTERVAL":
+			r.Interval, err = positive(value)
+		case "COUNT":
+			r.Count, err = positive(value)
+		case "UNTIL":
+			var until time.Time
+			until, err = parseUntil(value)
+			r.Until = &until
+		case "BYDAY":
+			r.ByDay, err = parseByDay(value)
+		case "BYMONTHDAY":
+			r.ByMonthDay, err = parseInts(value, 1, 31)
+		case "BYMONTH":
+			var months []int
+			months, err = parseInts(value, 1, 12)
+			for _, m := range months {
+				if m < 0 {
+					err = fmt.Errorf("negative month %d", m)
+				}
+				r.ByMonth = append(r.ByMonth, time.Month(m))
+			}
+		case "WKST":
+			day, ok := weekdays[strings.ToUpper(value)]
+			if !ok {
+				err = fmt.Errorf("unknown weekday %s", value)
+			}
+			r.WeekStart = day
+		default:
+			err = fmt.Errorf("unsupported part %s", name)
+		}
+		if err != nil {
+			return Rule{}, fmt.Errorf("%w: %v", ErrInvalidRule, err)
+		}
+	}
+	if r.Freq == "" {
+		return Rule{}, fmt.Errorf("%w: FREQ is required", ErrInvalidRule)
+	}
+	if r.Count > 0 && r.Until != nil {
+		return Rule{}, fmt.Errorf("%w: COUNT and UNTIL cannot both be given", ErrInvalidRule)
+	}
+	for _, d := range r.ByDay {
+		if d.N != 0 && r.Freq != Monthly && r.Freq != Yearly {
+			return Rule{}, fmt.Errorf("%w: BYDAY ordinals need a monthly or yearly rule", ErrInvalidRule)
+		}
+	}
+	if len(r.ByMonthDay) > 0 && r.Freq == Weekly {
+		return Rule{}, fmt.Errorf("%w: BYMONTHDAY cannot be used with a weekly rule", ErrInvalidRule)
+	}
+	return r, nil
+}
+
+// String formats the rule in RRULE syntax, without the "RRULE:" prefix
+func (r Rule) String() string {
+	parts := []string{"FREQ=" + r.Freq}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if r.Until != nil {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, d := range r.ByDay {
+			days[i] = dayName(d.Weekday)
+			if d.N != 0 {
+				days[i] = strconv.Itoa(d.N) + days[i]
+			}
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		parts = append(parts, "BYMONTHDAY="+joinInts(r.ByMonthDay))
+	}
+	if len(r.ByMonth) > 0 {
+		months := make([]int, len(r.ByMonth))
+		for i, m := range r.ByMonth {
+			months[i] = int(m)
+		}
+		parts = append(parts, "BYMONTH="+joinInts(months))
+	}
+	if r.WeekStart != time.Monday {
+		parts = append(parts, "WKST="+dayName(r.WeekStart))
+	}
+	return strings.Join(parts, ";")
+}
+
+func positive(s string) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("%q is not a positive number", s)
+	}
+	return n, nil
+}
+
+// parseUntil accepts a UTC date-time, a floating date-time, taken as UTC,
+// or a date, which ends the rule at the end of that day in UTC
+func parseUntil(s string) (time.Time, error) {
+	for _, layout := range []string{"20060102T150405Z", "20060102T150405"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	t, err := time.Parse("20060102", s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid UNTIL %q", s)
+	}
+	return t.Add(24*time.Hour - time.Second), nil
+}
+
+func parseByDay(s string) ([]WeekdayNum, error) {
+	var days []WeekdayNum
+	for _, item := range strings.Split(s, ",") {
+		item = strings.ToUpper(strings.TrimSpace(item))
+		if len(item) < 2 {
+			return nil, fmt.Errorf("invalid BYDAY %q", item)
+		}
+		day, ok := weekdays[item[len(item)-2:]]
+		if !ok {
+			return nil, fmt.Errorf("unknown weekday in %q", item)
+		}
+		n := 0
+		if prefix := item[:len(item)-2]; prefix != "" {
+			var err error
+			n, err = strconv.Atoi(prefix)
+			if err != nil || n == 0 || n < -53 || n > 53 {
+				return nil, fmt.Errorf("invalid ordinal in %q", item)
+			}
+		}
+		days = append(days, WeekdayNum{N: n, Weekday: day})
+	}
+	return days, nil
+}
+
+// parseInts parses a list of numbers between -max and max, excluding zero
+func parseInts(s string, min, max int) ([]int, error) {
+	var out []int
+	for _, item := range strings.Split(s, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(item))
+		if err != nil || n == 0 || n < -max || n > max || (n > 0 && n < min) {
+			return nil, fmt.Errorf("invalid value %q", item)
+		}
+		out = append(out, n)
+	}
+	return out, nil
+}
+
+func joinInts(ns []int) string {
+	s := make([]string, len(ns))
+	for i, n := range ns {
+		s[i] = strconv.Itoa(n)
+	}
+	return strings.Join(s, ",")
+}
+
+func dayName(d time.Weekday) string {
+	for name, day := range weekdays {
+		if day == d {
+			return name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,219 @@
+package refunds
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"time"
+
+	"booking-app/internal/bookings"
+	"booking-app/internal/database"
+	"booking-app/internal/events"
+	"booking-app/internal/jobs"
+	"booking-app/internal/ledger"
+	"booking-app/internal/middleware"
+	"booking-app/internal/payments"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	ErrNotCancellable    = errors.New("booking is already cancelled")
+	ErrPaymentInProgress = errors.New("booking has a payment in progress")
+	ErrInvalidAmount     = errors.New("refund amount must be between 0 and the amount paid")
+	ErrRefundNotFound    = errors.New("refund not found")
+)
+
+// DBStore cancels bookings and refunds them through the payment layer
+type DBStore struct {
+	db       *sqlx.DB
+	events   *events.DBStore
+	payments *payments.DBStore
+}
+
+func NewDBStore(db *sqlx.DB, eventStore *events.DBStore, paymentStore *payments.DBStore) *DBStore {
+	return &DBStore{db: db, events: eventStore, payments: paymentStore}
+}
+
+// Quote computes what cancelling a booking now would refund
+func (s *DBStore) Quote(ctx context.Context, bookingID int) (Quote, error) {
+	var q Quote
+	err := database.WithTx(ctx, s.db, func(tx *sqlx.Tx) error {
+		b, err := s.lockOwned(ctx, tx, bookingID)
+		if err != nil {
+			return err
+		}
+		q, err = s.quote(ctx, b)
+		return err
+	})
+	return q, err
+}
+
+// Cancel cancels a booking and, if anything is due back, records a refund
+// and queues it for the payment provider in the same transaction. Admins may
+// pass amount to refund a different amount than the policy gives, up to
+// what was paid.
+func (s *DBStore) Cancel(ctx context.Context, bookingID int, amount *int64) (Cancellation, error) {
+	var c Cancellation
+	err := database.WithTx(ctx, s.db, func(tx *sqlx.Tx) error {
+		var err error
+		c, err = s.CancelTx(ctx, tx, bookingID, amount)
+		return err
+	})
+	if err != nil {
+		return Cancellation{}, err
+	}
+	return c, nil
+}
+
+// CancelTx is Cancel inside the caller's transaction, for cancelling several
+// bookings together
+func (s *DBStore) CancelTx(ctx context.Context, tx *sqlx.Tx, bookingID int, amount *int64) (Cancellation, error) {
+	b, err := s.lockOwned(ctx, tx, bookingID)
+	if err != nil {
+		return Cancellation{}, err
+	}
+	q, err := s.quote(ctx, b)
+	if err != nil {
+		return Cancellation{}, err
+	}
+	if amount != nil {
+		if !middleware.IsAdmin(ctx) {
+			return Cancellation{}, bookings.ErrForbidden
+		}
+		if *amount < 0 || *amount > q.Paid {
+			return Cancellation{}, ErrInvalidAmount
+		}
+		q.Refund, q.Fee = *amount, q.Paid-*amount
+	}
+	c := Cancellation{Quote: q}
+	if c.Booking, err = bookings.CancelBookingTx(ctx, tx, b); err != nil {
+		return Cancellation{}, err
+	}
+	if q.Refund == 0 {
+		return c, nil
+	}
+	var r Refund
+	err = tx.GetContext(ctx, &r, `INSERT INTO refunds (booking_id, payment_id, amount, fee, currency)
+              VALUES ($1, $2, $3, $4, $5) RETURNING *`, b.ID, *b.PaymentID, q.Refund, q.Fee, q.Currency)
+	if err != nil {
+		return Cancellation{}, err
+	}
+	c.Refund = &r
+	// The refund reverses the sale in proportion; the part kept as
+	// cancellation fee stays with the organizer.
+	sale := ledger.Sale{Price: *b.Price, Currency: r.Currency}
+	if b.Discount != nil {
+		sale.Discount = *b.Discount
+	}
+	if b.PlatformFee != nil {
+		sale.Fee = *b.PlatformFee
+	}
+	if err := tx.GetContext(ctx, &sale.EventID, "SELECT id FROM events WHERE name = $1", b.Event); err != nil {
+		return Cancellation{}, err
+	}
+	if err := ledger.PostTx(ctx, tx, ledger.RefundIssued(r.ID, sale, r.Amount)); err != nil {
+		return Cancellation{}, err
+	}
+	_, err = jobs.EnqueueTx(ctx, tx, jobs.NewJob{Type: JobExecute, Payload: payments.RefundJob{RefundID: r.ID}})
+	if err != nil {
+		return Cancellation{}, err
+	}
+	return c, nil
+}
+
+// ListForBooking returns the refunds of a booking the caller owns
+func (s *DBStore) ListForBooking(ctx context.Context, bookingID int) ([]Refund, error) {
+	list := []Refund{}
+	err := database.WithTx(ctx, s.db, func(tx *sqlx.Tx) error {
+		if _, err := s.lockOwned(ctx, tx, bookingID); err != nil {
+			return err
+		}
+		return tx.SelectContext(ctx, &list, "SELECT * FROM refunds WHERE booking_id = $1 ORDER BY id", bookingID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// ExecuteJob sends a pending refund to the payment provider. The provider
+// call uses the refund ID as idempotency key, so a job retried after a crash
+// never refunds twice.
+func (s *DBStore) ExecuteJob(ctx context.Context, payload payments.RefundJob) error {
+	var r Refund
+	err := s.db.GetContext(ctx, &r, "SELECT * FROM refunds WHERE id = $1", payload.RefundID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrRefundNotFound
+	}
+	if err != nil || r.Status != StatusPending {
+		return err
+	}
+	done, err := s.payments.Refund(ctx, r.PaymentID, r.Amount, "refund-"+strconv.Itoa(r.ID))
+	if err != nil {
+		return err
+	}
+	return database.WithTx(ctx, s.db, func(tx *sqlx.Tx) error {
+		res, err := tx.ExecContext(ctx, `UPDATE refunds SET status = 'succeeded', provider_ref = $1, updated_at = now()
+              WHERE id = $2 AND status = 'pending'`, done.ID, r.ID)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil || n == 0 {
+			return err
+		}
+		if err := payments.RecordRefundTx(ctx, tx, r.PaymentID, r.Amount); err != nil {
+			return err
+		}
+		return ledger.PostTx(ctx, tx, ledger.RefundPaid(r.ID, r.Amount, r.Currency))
+	})
+}
+
+// lockOwned locks a booking the caller owns, or any booking for admins
+func (s *DBStore) lockOwned(ctx context.Context, tx *sqlx.Tx, id int) (bookings.Booking, error) {
+	b, err := bookings.LockBookingTx(ctx, tx, id)
+	if err != nil {
+		return bookings.Booking{}, err
+	}
+	if !owns(ctx, b) {
+		return bookings.Booking{}, bookings.ErrForbidden
+	}
+	return b, nil
+}
+
+// owns reports whether the caller may cancel a booking: admins any, users
+// their own. Bookings without an owner are left to admins.
+func owns(ctx context.Context, b bookings.Booking) bool {
+	if middleware.IsAdmin(ctx) {
+		return true
+	}
+	userID, ok := middleware.UserIDFromContext(ctx)
+	return ok && b.UserID != nil && *b.UserID == userID
+}
+
+// quote applies the policy of the booking's event. Only confirmed bookings
+// have been paid for; a booking whose payment is still being collected
+// cannot be cancelled until the payment settles.
+func (s *DBStore) quote(ctx context.Context, b bookings.Booking) (Quote, error) {
+	if !b.IsActive {
+		return Quote{}, ErrNotCancellable
+	}
+	if b.Status == bookings.StatusPendingPayment && b.PaymentID != nil {
+		return Quote{}, ErrPaymentInProgress
+	}
+	var paid int64
+	if b.Status == bookings.StatusConfirmed && b.PaymentID != nil && b.Price != nil {
+		paid = *b.Price
+	}
+	e, err := s.events.GetEventByName(ctx, b.Event)
+	if err != nil && !errors.Is(err, events.ErrEventNotFound) {
+		return Quote{}, err
+	}
+	q := Compute(e.Settings, paid, time.Now())
+	q.BookingID = b.ID
+	if paid > 0 {
+		q.Currency = *b.Currency
+	}
+	return q, nil
+}
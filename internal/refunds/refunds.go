@@ -0,0 +1,87 @@
+package refunds
+
+import (
+	"time"
+
+	"booking-app/internal/bookings"
+	"booking-app/internal/events"
+	"booking-app/internal/payments"
+)
+
+// Refund statuses
+const (
+	StatusPending   = "pending"
+	StatusSucceeded = "succeeded"
+)
+
+// Rules a quote can be computed under
+const (
+	RuleFullRefund      = "full_refund"
+	RuleCancellationFee = "cancellation_fee"
+	RuleNoRefund        = "no_refund"
+)
+
+// JobExecute is the job type that sends a refund to the payment provider
+const JobExecute = payments.JobExecuteRefund
+
+// Refund is money returned for a cancelled booking. It is pending until the
+// payment provider has processed it.
+type Refund struct {
+	ID          int       `json:"id" db:"id"`
+	BookingID   *int      `json:"booking_id" db:"booking_id"`
+	PaymentID   int       `json:"payment_id" db:"payment_id"`
+	Amount      int64     `json:"amount" db:"amount"`
+	Fee         int64     `json:"fee" db:"fee"`
+	Currency    string    `json:"currency" db:"currency"`
+	Status      string    `json:"status" db:"status"`
+	ProviderRef *string   `json:"provider_ref,omitempty" db:"provider_ref"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// Quote is what cancelling a booking at QuotedAt would refund. Amounts are
+// in minor currency units; Paid is zero for bookings that were never paid.
+type Quote struct {
+	BookingID int       `json:"booking_id"`
+	Paid      int64     `json:"paid"`
+	Fee       int64     `json:"fee"`
+	Refund    int64     `json:"refund"`
+	Currency  string    `json:"currency,omitempty"`
+	Rule      string    `json:"rule"`
+	QuotedAt  time.Time `json:"quoted_at"`
+}
+
+// Cancellation is the result of cancelling a booking
+type Cancellation struct {
+	Booking bookings.Booking `json:"booking"`
+	Quote   Quote            `json:"quote"`
+	Refund  *Refund          `json:"refund,omitempty"`
+}
+
+// Compute applies an event's cancellation policy to the amount paid for a
+// booking cancelled at now. Fees are rounded to the nearest minor unit.
+func Compute(s events.Settings, paid int64, now time.Time) Quote {
+	q := Quote{Paid: paid, QuotedAt: now}
+	switch {
+	case s.StartsAt == nil:
+		q.Rule = RuleFullRefund
+	case !now.Before(*s.StartsAt):
+		q.Rule = RuleNoRefund
+	case s.FullRefundHours == nil || !now.After(s.StartsAt.Add(-time.Duration(*s.FullRefundHours)*time.Hour)):
+		q.Rule = RuleFullRefund
+	case s.CancellationFeePercent == nil:
+		q.Rule = RuleNoRefund
+	default:
+		q.Rule = RuleCancellationFee
+	}
+	switch q.Rule {
+	case RuleFullRefund:
+		q.Refund = paid
+	case RuleNoRefund:
+		q.Fee = paid
+	case RuleCancellationFee:
+		q.Fee = (paid*int64(*s.CancellationFeePercent) + 50) / 100
+		q.Refund = paid - q.Fee
+	}
+	return q
+}
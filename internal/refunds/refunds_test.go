@@ -0,0 +1,75 @@
+package refunds
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"booking-app/internal/bookings"
+	"booking-app/internal/events"
+	"booking-app/internal/middleware"
+)
+
+func TestCompute(t *testing.T) {
+	start := time.Date(2026, 6, 1, 19, 0, 0, 0, time.UTC)
+	hours := func(n int) *int { return &n }
+	tests := []struct {
+		name       string
+		settings   events.Settings
+		now        time.Time
+		wantRule   string
+		wantRefund int64
+		wantFee    int64
+	}{
+		{"no start time", events.Settings{}, start, RuleFullRefund, 1000, 0},
+		{"before start without cut-off", events.Settings{StartsAt: &start}, start.Add(-time.Minute), RuleFullRefund, 1000, 0},
+		{"at start", events.Settings{StartsAt: &start}, start, RuleNoRefund, 0, 1000},
+		{"after start", events.Settings{StartsAt: &start, FullRefundHours: hours(48), CancellationFeePercent: hours(10)}, start.Add(time.Hour), RuleNoRefund, 0, 1000},
+		{"at cut-off", events.Settings{StartsAt: &start, FullRefundHours: hours(48), CancellationFeePercent: hours(10)}, start.Add(-48 * time.Hour), RuleFullRefund, 1000, 0},
+		{"after cut-off", events.Settings{StartsAt: &start, FullRefundHours: hours(48), CancellationFeePercent: hours(10)}, start.Add(-47 * time.Hour), RuleCancellationFee, 900, 100},
+		{"after cut-off without fee", events.Settings{StartsAt: &start, FullRefundHours: hours(48)}, start.Add(-time.Hour), RuleNoRefund, 0, 1000},
+		{"zero fee", events.Settings{StartsAt: &start, FullRefundHours: hours(48), CancellationFeePercent: hours(0)}, start.Add(-time.Hour), RuleCancellationFee, 1000, 0},
+	}
+	for _, tt := range tests {
+		q := Compute(tt.settings, 1000, tt.now)
+		if q.Rule != tt.wantRule || q.Refund != tt.wantRefund || q.Fee != tt.wantFee {
+			t.Errorf("%s: got rule=%s refund=%d fee=%d, want rule=%s refund=%d fee=%d",
+				tt.name, q.Rule, q.Refund, q.Fee, tt.wantRule, tt.wantRefund, tt.wantFee)
+		}
+	}
+}
+
+func TestComputeRoundsFee(t *testing.T) {
+	start := time.Date(2026, 6, 1, 19, 0, 0, 0, time.UTC)
+	cutoff, fee := 24, 15
+	q := Compute(events.Settings{StartsAt: &start, FullRefundHours: &cutoff, CancellationFeePercent: &fee}, 999, start.Add(-time.Hour))
+	// 15% of 999 is 149.85, rounded to 150
+	if q.Fee != 150 || q.Refund != 849 {
+		t.Errorf("Expected fee 150 and refund 849, got fee %d and refund %d", q.Fee, q.Refund)
+	}
+}
+
+func TestOwns(t *testing.T) {
+	owner, other := 1, 2
+	userContext := func(id int) context.Context {
+		return context.WithValue(context.Background(), middleware.UserIDKey, id)
+	}
+	admin := context.WithValue(context.Background(), middleware.RoleKey, middleware.RoleAdmin)
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		booking bookings.Booking
+		want    bool
+	}{
+		{"owner", userContext(owner), bookings.Booking{UserID: &owner}, true},
+		{"other user", userContext(other), bookings.Booking{UserID: &owner}, false},
+		{"admin", admin, bookings.Booking{UserID: &owner}, true},
+		{"ownerless booking", userContext(other), bookings.Booking{}, false},
+		{"ownerless booking as admin", admin, bookings.Booking{}, true},
+	}
+	for _, tt := range tests {
+		if got := owns(tt.ctx, tt.booking); got != tt.want {
+			t.Errorf("%s: owns = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
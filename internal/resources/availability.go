@@ -0,0 +1,131 @@
+package resources
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+// MaxSearchDays caps how far an availability search may span
+const MaxSearchDays = 62
+
+var ErrInvalidSearch = errors.New("invalid availability search")
+
+// Interval is a half-open span of time [Start, End)
+type Interval struct {
+	Start time.Time `db:"starts_at"`
+	End   time.Time `db:"ends_at"`
+}
+
+// Opening is a slot free to book, in UTC and in the resource's time zone
+type Opening struct {
+	ResourceID    int       `json:"resource_id"`
+	StartsAt      time.Time `json:"starts_at"`
+	EndsAt        time.Time `json:"ends_at"`
+	StartsAtLocal time.Time `json:"starts_at_local"`
+	EndsAtLocal   time.Time `json:"ends_at_local"`
+}
+
+// Busy is what stands in the way of appointments on a resource. Taken spans
+// are active appointments and holds, including their buffers, and may not
+// overlap a new appointment or the buffer after it, except as many as the
+// resource's overbooking allowance. Blackouts may not overlap the
+// appointment itself.
+type Busy struct {
+	Taken     []Interval
+	Blackouts []Interval
+}
+
+// Openings lists the slots of a resource, lasting slots slot lengths, that
+// start in [from, to) after now and are not blocked by busy. Candidates are
+// generated on each local day of the resource's time zone, so opening hours
+// keep their wall-clock times across daylight saving changes; slots that
+// fall into a skipped hour are left out, and slots must end by the time
+// the period closes even when the clocks change in between. Slots the
+// resource's booking rules do not allow booking at now are left out too.
+func (r Resource) Openings(from, to, now time.Time, slots int, busy Busy) ([]Opening, error) {
+	loc, err := time.LoadLocation(r.TimeZone)
+	if err != nil {
+		return nil, err
+	}
+	length := slots * r.SlotMinutes
+	buffer := time.Duration(r.BufferMinutes) * time.Minute
+	taken, blackouts := merge(busy.Taken), merge(busy.Blackouts)
+	full := taken.overlaps
+	if allowance := r.Allowance(1); allowance > 0 {
+		full = func(start, end time.Time) bool { return countOverlaps(busy.Taken, start, end) > allowance }
+	}
+	hours := append([]Hours(nil), r.Hours...)
+	sort.Slice(hours, func(i, j int) bool { return hours[i].Opens < hours[j].Opens })
+
+	openings := []Opening{}
+	local := from.In(loc)
+	for day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc); day.Before(to); day = day.AddDate(0, 0, 1) {
+		for _, h := range hours {
+			if h.Weekday != day.Weekday() {
+				continue
+			}
+			opens, closes, err := h.minutes()
+			if err != nil {
+				return nil, err
+			}
+			closing := time.Date(day.Year(), day.Month(), day.Day(), 0, closes, 0, 0, loc)
+			for minute := opens; minute < closes; minute += r.SlotMinutes {
+				start := time.Date(day.Year(), day.Month(), day.Day(), 0, minute, 0, 0, loc)
+				if start.Hour()*60+start.Minute() != minute {
+					continue
+				}
+				if start.Before(from) || !start.Before(to) || !start.After(now) {
+					continue
+				}
+				end := start.Add(time.Duration(length) * time.Minute)
+				if end.After(closing) || full(start, end.Add(buffer)) || blackouts.overlaps(start, end) ||
+					r.Rules.Check(start, now, loc) != nil {
+					continue
+				}
+				openings = append(openings, Opening{ResourceID: r.ID, StartsAt: start.UTC(), EndsAt: end.UTC(),
+					StartsAtLocal: start, EndsAtLocal: end.In(loc)})
+			}
+		}
+	}
+	return openings, nil
+}
+
+// intervals is a sorted list of disjoint intervals
+type intervals []Interval
+
+// merge sorts intervals and joins those that overlap or touch
+func merge(in []Interval) intervals {
+	sorted := append([]Interval(nil), in...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Start.Before(sorted[j].Start) })
+	var out intervals
+	for _, iv := range sorted {
+		if n := len(out); n > 0 && !iv.Start.After(out[n-1].End) {
+			if iv.End.After(out[n-1].End) {
+				out[n-1].End = iv.End
+			}
+			continue
+		}
+		out = append(out, iv)
+	}
+	return out
+}
+
+// countOverlaps counts the intervals that overlap [start, end)
+func countOverlaps(in []Interval, start, end time.Time) int {
+	n := 0
+	for _, iv := range in {
+		if iv.Start.Before(end) && iv.End.After(start) {
+			n++
+		}
+	}
+	return n
+}
+
+// overlaps reports whether [start, end) overlaps any of the intervals
+func (ivs intervals) overlaps(start, end time.Time) bool {
+	// Find the first interval ending after start; it is the only one that
+	// can overlap without starting after end.
+	i := sort.Search(len(ivs), func(i int) bool { return ivs[i].End.After(start) })
+	return i < len(ivs) && ivs[i].Start.Before(end)
+}
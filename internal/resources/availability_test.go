@@ -0,0 +1,163 @@
+package resources
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestOpenings(t *testing.T) {
+	r := Resource{ID: 1, Settings: Settings{
+		TimeZone:      "UTC",
+		SlotMinutes:   30,
+		BufferMinutes: 15,
+		Hours:         []Hours{{time.Monday, "09:00", "11:00"}},
+	}}
+	// 2026-03-02 is a Monday
+	at := func(hour, min int) time.Time { return time.Date(2026, 3, 2, hour, min, 0, 0, time.UTC) }
+	from, to, now := at(0, 0), at(23, 0), at(0, 0).AddDate(0, 0, -1)
+	starts := func(openings []Opening) []string {
+		var out []string
+		for _, o := range openings {
+			out = append(out, o.StartsAt.Format("15:04"))
+		}
+		return out
+	}
+	tests := []struct {
+		name  string
+		slots int
+		busy  Busy
+		now   time.Time
+		want  []string
+	}{
+		{"all free", 1, Busy{}, now, []string{"09:00", "09:30", "10:00", "10:30"}},
+		{"two slots", 2, Busy{}, now, []string{"09:00", "09:30", "10:00"}},
+		// An appointment 09:30-10:00 blocks until 10:15, and a new one
+		// ending at 09:30 would need its buffer until 09:45.
+		{"buffers", 1, Busy{Taken: []Interval{{at(9, 30), at(10, 15)}}}, now, []string{"10:30"}},
+		{"blackout", 1, Busy{Blackouts: []Interval{{at(10, 0), at(12, 0)}}}, now, []string{"09:00", "09:30"}},
+		{"blackout ignores buffer", 1, Busy{Blackouts: []Interval{{at(9, 30), at(12, 0)}}}, now, []string{"09:00"}},
+		{"past slots", 1, Busy{}, at(9, 45), []string{"10:00", "10:30"}},
+	}
+	for _, tt := range tests {
+		got, err := r.Openings(from, to, tt.now, tt.slots, tt.busy)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if g := starts(got); !equal(g, tt.want) {
+			t.Errorf("%s: openings start at %v, want %v", tt.name, g, tt.want)
+		}
+	}
+}
+
+func TestOpeningsWithOverbooking(t *testing.T) {
+	hundred, one := 100, 1
+	r := Resource{ID: 1, Settings: Settings{
+		TimeZone:      "UTC",
+		SlotMinutes:   30,
+		BufferMinutes: 15,
+		Hours:         []Hours{{time.Monday, "09:00", "11:00"}},
+	}}
+	r.OverbookPercent, r.OverbookLimit = &hundred, &one
+	at := func(hour, min int) time.Time { return time.Date(2026, 3, 2, hour, min, 0, 0, time.UTC) }
+	from, to, now := at(0, 0), at(23, 0), at(0, 0).AddDate(0, 0, -1)
+	tests := []struct {
+		name  string
+		taken []Interval
+		want  []string
+	}{
+		{"one booked", []Interval{{at(9, 30), at(10, 15)}}, []string{"09:00", "09:30", "10:00", "10:30"}},
+		{"oversold", []Interval{{at(9, 30), at(10, 15)}, {at(9, 30), at(10, 15)}}, []string{"10:30"}},
+	}
+	for _, tt := range tests {
+		got, err := r.Openings(from, to, now, 1, Busy{Taken: tt.taken})
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		var starts []string
+		for _, o := range got {
+			starts = append(starts, o.StartsAt.Format("15:04"))
+		}
+		if !equal(starts, tt.want) {
+			t.Errorf("%s: openings start at %v, want %v", tt.name, starts, tt.want)
+		}
+	}
+}
+
+func TestOpeningsAcrossDaylightSaving(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("time zone data not available: %v", err)
+	}
+	r := Resource{ID: 1, Settings: Settings{
+		TimeZone:    "Europe/Berlin",
+		SlotMinutes: 30,
+		Hours:       []Hours{{time.Sunday, "01:00", "04:00"}, {time.Monday, "09:00", "10:00"}},
+	}}
+	// Clocks go from 02:00 to 03:00 on Sunday 2026-03-29
+	from := time.Date(2026, 3, 29, 0, 0, 0, 0, berlin)
+	got, err := r.Openings(from, from.AddDate(0, 0, 2), from.AddDate(0, 0, -1), 1, Busy{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"29 01:00", "29 01:30", "29 03:00", "29 03:30", "30 09:00", "30 09:30"}
+	var starts []string
+	for _, o := range got {
+		starts = append(starts, o.StartsAt.In(berlin).Format("02 15:04"))
+	}
+	if !equal(starts, want) {
+		t.Errorf("Openings start at %v, want %v", starts, want)
+	}
+	// Local opening hours keep their wall-clock time after the change
+	if _, offset := got[4].StartsAtLocal.Zone(); offset != 2*3600 {
+		t.Errorf("Expected summer time after the change, got offset %d", offset)
+	}
+}
+
+func TestMergeOverlaps(t *testing.T) {
+	at := func(hour int) time.Time { return time.Date(2026, 1, 1, hour, 0, 0, 0, time.UTC) }
+	ivs := merge([]Interval{{at(5), at(6)}, {at(1), at(3)}, {at(2), at(4)}, {at(4), at(5)}, {at(8), at(9)}})
+	if len(ivs) != 2 || !ivs[0].Start.Equal(at(1)) || !ivs[0].End.Equal(at(6)) {
+		t.Fatalf("Unexpected merge result %+v", ivs)
+	}
+	tests := []struct {
+		start, end int
+		want       bool
+	}{
+		{0, 1, false},
+		{0, 2, true},
+		{6, 8, false},
+		{7, 9, true},
+		{9, 10, false},
+	}
+	for _, tt := range tests {
+		if got := ivs.overlaps(at(tt.start), at(tt.end)); got != tt.want {
+			t.Errorf("overlaps(%d, %d) = %v, want %v", tt.start, tt.end, got, tt.want)
+		}
+	}
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestAvailabilityRejectsLongDurations(t *testing.T) {
+	s := NewDBStore(nil, nil)
+	from := time.Date(2026, 3, 3, 9, 0, 0, 0, time.UTC)
+	for _, minutes := range []int{-1, MaxSearchMinutes + 1, math.MaxInt} {
+		_, err := s.Availability(context.Background(), Search{From: from, To: from.Add(time.Hour), Minutes: minutes})
+		if !errors.Is(err, ErrInvalidSearch) {
+			t.Errorf("%d minutes: expected ErrInvalidSearch, got %v", minutes, err)
+		}
+	}
+}
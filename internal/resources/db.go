@@ -0,0 +1,188 @@
+package resources
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"booking-app/internal/bookings"
+	"booking-app/internal/database"
+	"booking-app/internal/events"
+	"booking-app/internal/holds"
+	"booking-app/internal/middleware"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrResourceNotFound = errors.New("resource not found")
+	ErrResourceExists   = errors.New("a resource or event with this name already exists")
+	ErrBlackoutNotFound = errors.New("blackout not found")
+)
+
+// DBStore manages resources in PostgreSQL
+type DBStore struct {
+	db       *sqlx.DB
+	bookings *bookings.DBStore
+}
+
+func NewDBStore(db *sqlx.DB, bookingStore *bookings.DBStore) *DBStore {
+	return &DBStore{db: db, bookings: bookingStore}
+}
+
+func (s *DBStore) Create(ctx context.Context, r Resource) (Resource, error) {
+	if err := r.Check(); err != nil {
+		return Resource{}, err
+	}
+	hours := r.Hours
+	err := database.WithTx(ctx, s.db, func(tx *sqlx.Tx) error {
+		if err := events.ClaimNameTx(ctx, tx, r.Name); err != nil {
+			return err
+		}
+		err := tx.GetContext(ctx, &r, `INSERT INTO resources (name, owner_id, time_zone, slot_minutes, buffer_minutes,
+              min_notice_minutes, max_advance_days, cutoff_time, cutoff_days_before, overbook_percent, overbook_limit)
+              VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING *`, r.Name, r.OwnerID, r.TimeZone, r.SlotMinutes, r.BufferMinutes,
+			r.MinNoticeMinutes, r.MaxAdvanceDays, r.CutoffTime, r.CutoffDaysBefore, r.OverbookPercent, r.OverbookLimit)
+		if err != nil {
+			return err
+		}
+		return replaceHours(ctx, tx, r.ID, hours)
+	})
+	var pqErr *pq.Error
+	if errors.Is(err, events.ErrEventExists) || (errors.As(err, &pqErr) && pqErr.Code == "23505") {
+		return Resource{}, ErrResourceExists
+	}
+	if err != nil {
+		return Resource{}, err
+	}
+	r.Hours = hours
+	return r, nil
+}
+
+func (s *DBStore) Get(ctx context.Context, id int) (Resource, error) {
+	var r Resource
+	err := s.db.GetContext(ctx, &r, "SELECT * FROM resources WHERE id = $1", id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Resource{}, ErrResourceNotFound
+	}
+	if err != nil {
+		return Resource{}, err
+	}
+	r.Hours, err = listHours(ctx, s.db, id)
+	return r, err
+}
+
+func (s *DBStore) List(ctx context.Context) ([]Resource, error) {
+	list := []Resource{}
+	if err := s.db.SelectContext(ctx, &list, "SELECT * FROM resources ORDER BY id"); err != nil {
+		return nil, err
+	}
+	for i := range list {
+		hours, err := listHours(ctx, s.db, list[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		list[i].Hours = hours
+	}
+	return list, nil
+}
+
+// Update replaces a resource's settings. Existing appointments are kept
+// even if they no longer fit the new hours.
+func (s *DBStore) Update(ctx context.Context, id int, settings Settings) (Resource, error) {
+	if err := settings.Check(); err != nil {
+		return Resource{}, err
+	}
+	var r Resource
+	err := database.WithTx(ctx, s.db, func(tx *sqlx.Tx) error {
+		err := tx.GetContext(ctx, &r, `UPDATE resources SET time_zone = $1, slot_minutes = $2, buffer_minutes = $3,
+              min_notice_minutes = $4, max_advance_days = $5, cutoff_time = $6, cutoff_days_before = $7,
+              overbook_percent = $8, overbook_limit = $9, updated_at = now() WHERE id = $10 RETURNING *`,
+			settings.TimeZone, settings.SlotMinutes, settings.BufferMinutes,
+			settings.MinNoticeMinutes, settings.MaxAdvanceDays, settings.CutoffTime, settings.CutoffDaysBefore,
+			settings.OverbookPercent, settings.OverbookLimit, id)
+		if err != nil {
+			return err
+		}
+		return replaceHours(ctx, tx, id, settings.Hours)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return Resource{}, ErrResourceNotFound
+	}
+	if err != nil {
+		return Resource{}, err
+	}
+	r.Hours = settings.Hours
+	return r, nil
+}
+
+// Book creates an appointment on a resource for the authenticated user,
+// with the resource's name as its event. Appointments that overlap an
+// existing one, including its buffer, fail with bookings.ErrSlotTaken.
+func (s *DBStore) Book(ctx context.Context, resourceID int, userName string, start time.Time, slots int) (bookings.Booking, error) {
+	r, slot, err := s.CheckSlot(ctx, resourceID, start, slots)
+	if err != nil {
+		return bookings.Booking{}, err
+	}
+	return s.bookings.Create(ctx, bookings.NewBooking{UserName: userName, Event: r.Name, Slot: &slot})
+}
+
+// Hold holds an appointment slot for the authenticated user while they
+// check out. Converting the hold books the slot.
+func (s *DBStore) Hold(ctx context.Context, resourceID int, userName string, start time.Time, slots int, ttl time.Duration) (holds.Hold, error) {
+	r, slot, err := s.CheckSlot(ctx, resourceID, start, slots)
+	if err != nil {
+		return holds.Hold{}, err
+	}
+	nh := holds.NewHold{Event: r.Name, UserName: userName, Quantity: 1, TTL: ttl, Slot: &slot}
+	if userID, ok := middleware.UserIDFromContext(ctx); ok {
+		nh.UserID = &userID
+	}
+	var h holds.Hold
+	err = database.WithTx(ctx, s.db, func(tx *sqlx.Tx) error {
+		var err error
+		h, err = holds.CreateHoldTx(ctx, tx, nh)
+		return err
+	})
+	return h, err
+}
+
+// CheckSlot checks that a future slot of a resource is on its schedule.
+// Blackouts and booking rules are checked when the slot is booked or held.
+func (s *DBStore) CheckSlot(ctx context.Context, resourceID int, start time.Time, slots int) (Resource, bookings.Slot, error) {
+	r, err := s.Get(ctx, resourceID)
+	if err != nil {
+		return Resource{}, bookings.Slot{}, err
+	}
+	if !start.After(time.Now()) {
+		return Resource{}, bookings.Slot{}, ErrInPast
+	}
+	slot, err := r.Slot(start, slots)
+	if err != nil {
+		return Resource{}, bookings.Slot{}, err
+	}
+	return r, slot, nil
+}
+
+func replaceHours(ctx context.Context, tx *sqlx.Tx, resourceID int, hours []Hours) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM resource_hours WHERE resource_id = $1", resourceID); err != nil {
+		return err
+	}
+	for _, h := range hours {
+		_, err := tx.ExecContext(ctx, `INSERT INTO resource_hours (resource_id, weekday, opens, closes)
+              VALUES ($1, $2, $3, $4)`, resourceID, h.Weekday, h.Opens, h.Closes)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func listHours(ctx context.Context, q sqlx.QueryerContext, resourceID int) ([]Hours, error) {
+	hours := []Hours{}
+	err := sqlx.SelectContext(ctx, q, &hours, `SELECT weekday, to_char(opens, 'HH24:MI') AS opens, to_char(closes, 'HH24:MI') AS closes
+              FROM resource_hours WHERE resource_id = $1 ORDER BY weekday, opens`, resourceID)
+	return hours, err
+}
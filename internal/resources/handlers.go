@@ -0,0 +1,346 @@
+package resources
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"booking-app/internal/bookings"
+	"booking-app/internal/holds"
+	"booking-app/internal/middleware"
+	"booking-app/internal/rules"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+)
+
+var validate = validator.New()
+
+type Handler struct {
+	store *DBStore
+}
+
+func NewHandler(store *DBStore) *Handler {
+	return &Handler{store: store}
+}
+
+// CanManage reports whether the authenticated user may change a resource:
+// admins and the resource's owner.
+func CanManage(ctx context.Context, r Resource) bool {
+	if middleware.IsAdmin(ctx) {
+		return true
+	}
+	userID, ok := middleware.UserIDFromContext(ctx)
+	return ok && r.OwnerID != nil && *r.OwnerID == userID
+}
+
+// CreateHandler creates a resource owned by the authenticated user. Only
+// admins may create resources.
+func (h *Handler) CreateHandler(w http.ResponseWriter, r *http.Request) {
+	if !middleware.IsAdmin(r.Context()) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	var input struct {
+		Name string `json:"name" validate:"required,max=255"`
+		Settings
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := validate.Struct(&input); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	res := Resource{Name: input.Name, Settings: input.Settings}
+	if userID, ok := middleware.UserIDFromContext(r.Context()); ok {
+		res.OwnerID = &userID
+	}
+	res, err := h.store.Create(r.Context(), res)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, res)
+}
+
+func (h *Handler) ListHandler(w http.ResponseWriter, r *http.Request) {
+	list, err := h.store.List(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to fetch resources", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (h *Handler) GetHandler(w http.ResponseWriter, r *http.Request) {
+	res, ok := h.loadResource(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// UpdateHandler replaces a resource's settings. Fields missing from the body
+// keep their current value.
+func (h *Handler) UpdateHandler(w http.ResponseWriter, r *http.Request) {
+	res, ok := h.loadResource(w, r)
+	if !ok {
+		return
+	}
+	if !CanManage(r.Context(), res) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	settings := res.Settings
+	if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := validate.Struct(&settings); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	res, err := h.store.Update(r.Context(), res.ID, settings)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// BookHandler books an appointment of one or more consecutive slots
+func (h *Handler) BookHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	var input struct {
+		UserName string    `json:"user_name" validate:"required"`
+		StartsAt time.Time `json:"starts_at" validate:"required"`
+		Slots    int       `json:"slots" validate:"omitempty,min=1,max=96"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := validate.Struct(&input); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if input.Slots == 0 {
+		input.Slots = 1
+	}
+	b, err := h.store.Book(r.Context(), id, input.UserName, input.StartsAt, input.Slots)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, b)
+}
+
+// HoldHandler holds an appointment slot while the customer checks out
+func (h *Handler) HoldHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	var input struct {
+		UserName   string    `json:"user_name" validate:"required"`
+		StartsAt   time.Time `json:"starts_at" validate:"required"`
+		Slots      int       `json:"slots" validate:"omitempty,min=1,max=96"`
+		TTLSeconds int       `json:"ttl_seconds" validate:"omitempty,min=30,max=3600"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := validate.Struct(&input); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if input.Slots == 0 {
+		input.Slots = 1
+	}
+	ttl := holds.DefaultTTL
+	if input.TTLSeconds > 0 {
+		ttl = time.Duration(input.TTLSeconds) * time.Second
+	}
+	hold, err := h.store.Hold(r.Context(), id, input.UserName, input.StartsAt, input.Slots, ttl)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, hold)
+}
+
+// AvailabilityHandler lists open slots, e.g.
+// GET /resources/availability?resource_id=1&resource_id=2&from=2026-03-03T12:00:00Z&to=2026-03-03T18:00:00Z&duration=60
+// Without resource_id every resource is searched; duration is in minutes.
+func (h *Handler) AvailabilityHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	var search Search
+	for _, v := range q["resource_id"] {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			http.Error(w, "Invalid resource_id", http.StatusBadRequest)
+			return
+		}
+		search.ResourceIDs = append(search.ResourceIDs, id)
+	}
+	var err error
+	if search.From, err = time.Parse(time.RFC3339, q.Get("from")); err != nil {
+		http.Error(w, "from must be an RFC 3339 time", http.StatusBadRequest)
+		return
+	}
+	if search.To, err = time.Parse(time.RFC3339, q.Get("to")); err != nil {
+		http.Error(w, "to must be an RFC 3339 time", http.StatusBadRequest)
+		return
+	}
+	if v := q.Get("duration"); v != "" {
+		if search.Minutes, err = strconv.Atoi(v); err != nil || search.Minutes <= 0 {
+			http.Error(w, "Invalid duration", http.StatusBadRequest)
+			return
+		}
+	}
+	openings, err := h.store.Availability(r.Context(), search)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, openings)
+}
+
+// OverbookingReportHandler shows a resource's owner the slots it was
+// overbooked on between the from and to query parameters
+func (h *Handler) OverbookingReportHandler(w http.ResponseWriter, r *http.Request) {
+	res, ok := h.loadResource(w, r)
+	if !ok {
+		return
+	}
+	if !CanManage(r.Context(), res) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	q := r.URL.Query()
+	from, err := time.Parse(time.RFC3339, q.Get("from"))
+	if err != nil {
+		http.Error(w, "from must be an RFC 3339 time", http.StatusBadRequest)
+		return
+	}
+	to, err := time.Parse(time.RFC3339, q.Get("to"))
+	if err != nil {
+		http.Error(w, "to must be an RFC 3339 time", http.StatusBadRequest)
+		return
+	}
+	report, err := h.store.OverbookingReport(r.Context(), res, from, to)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+func (h *Handler) CreateBlackoutHandler(w http.ResponseWriter, r *http.Request) {
+	res, ok := h.loadResource(w, r)
+	if !ok {
+		return
+	}
+	if !CanManage(r.Context(), res) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	var b Blackout
+	if err := json.NewDecoder(r.Body).Decode(&b); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := validate.Struct(&b); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	b.ResourceID = res.ID
+	b, err := h.store.CreateBlackout(r.Context(), b)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, b)
+}
+
+func (h *Handler) ListBlackoutsHandler(w http.ResponseWriter, r *http.Request) {
+	res, ok := h.loadResource(w, r)
+	if !ok {
+		return
+	}
+	list, err := h.store.ListBlackouts(r.Context(), res.ID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (h *Handler) DeleteBlackoutHandler(w http.ResponseWriter, r *http.Request) {
+	res, ok := h.loadResource(w, r)
+	if !ok {
+		return
+	}
+	if !CanManage(r.Context(), res) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	id, err := strconv.Atoi(mux.Vars(r)["blackoutId"])
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	if err := h.store.DeleteBlackout(r.Context(), res.ID, id); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) loadResource(w http.ResponseWriter, r *http.Request) (Resource, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return Resource{}, false
+	}
+	res, err := h.store.Get(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return Resource{}, false
+	}
+	return res, true
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrResourceNotFound), errors.Is(err, ErrBlackoutNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrResourceExists), errors.Is(err, bookings.ErrSlotTaken), errors.Is(err, rules.ErrNotBookable),
+		errors.Is(err, bookings.ErrLimitReached), errors.Is(err, bookings.ErrOverlap):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, ErrInvalidHours), errors.Is(err, ErrInvalidSlot), errors.Is(err, ErrInPast),
+		errors.Is(err, ErrInvalidSearch):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,37 @@
+package resources
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"booking-app/internal/middleware"
+)
+
+func TestCreateHandler(t *testing.T) {
+	h := NewHandler(NewDBStore(nil, nil))
+	valid := `{"name": "Studio A", "time_zone": "Europe/Berlin", "slot_minutes": 30}`
+	tests := []struct {
+		name string
+		role string
+		body string
+		want int
+	}{
+		{"customer", "user", valid, http.StatusForbidden},
+		{"organizer", middleware.RoleOrganizer, valid, http.StatusForbidden},
+		{"anonymous", "", valid, http.StatusForbidden},
+		{"malformed", middleware.RoleAdmin, `{"name":`, http.StatusBadRequest},
+		{"no name", middleware.RoleAdmin, `{"time_zone": "Europe/Berlin", "slot_minutes": 30}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodPost, "/resources", strings.NewReader(tt.body))
+		r = r.WithContext(context.WithValue(r.Context(), middleware.RoleKey, tt.role))
+		w := httptest.NewRecorder()
+		h.CreateHandler(w, r)
+		if w.Code != tt.want {
+			t.Errorf("%s: expected status %d, got %d: %s", tt.name, tt.want, w.Code, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package resources
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"booking-app/internal/bookings"
+	"booking-app/internal/overbooking"
+	"booking-app/internal/rules"
+)
+
+var (
+	ErrInvalidHours = errors.New("invalid opening hours")
+	ErrInvalidSlot  = errors.New("time is not a bookable slot of this resource")
+	ErrInPast       = errors.New("slot has already started")
+)
+
+// Resource is something booked by the hour rather than by the place, such
+// as a consultant or a studio room. Appointments start on the slot grid
+// that begins when the resource opens and last a whole number of slots.
+type Resource struct {
+	ID        int       `json:"id" db:"id"`
+	Name      string    `json:"name" db:"name"`
+	OwnerID   *int      `json:"owner_id,omitempty" db:"owner_id"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+	Settings
+}
+
+// Settings are the owner-controlled options of a resource. BufferMinutes
+// keeps the resource free for that long after each appointment, and the
+// booking rules count from the start of each appointment. The overbooking
+// policy allows more than one appointment at a time.
+type Settings struct {
+	TimeZone      string  `json:"time_zone" db:"time_zone" validate:"required,timezone"`
+	SlotMinutes   int     `json:"slot_minutes" db:"slot_minutes" validate:"required,min=5,max=1440"`
+	BufferMinutes int     `json:"buffer_minutes" db:"buffer_minutes" validate:"min=0,max=1440"`
+	Hours         []Hours `json:"hours" db:"-" validate:"dive"`
+	rules.Rules
+	overbooking.Policy
+}
+
+// Hours is one opening period on a weekday, as local "15:04" times
+type Hours struct {
+	Weekday time.Weekday `json:"weekday" db:"weekday" validate:"min=0,max=6"`
+	Opens   string       `json:"opens" db:"opens" validate:"required,datetime=15:04"`
+	Closes  string       `json:"closes" db:"closes" validate:"required,datetime=15:04"`
+}
+
+// Check validates opening hours beyond what struct tags can express: each
+// period must close after it opens and periods on a day may not overlap.
+func (s Settings) Check() error {
+	byDay := make(map[time.Weekday][]Hours)
+	for _, h := range s.Hours {
+		opens, closes, err := h.minutes()
+		if err != nil {
+			return err
+		}
+		if closes <= opens {
+			return fmt.Errorf("%w: %s closes before it opens", ErrInvalidHours, h.Weekday)
+		}
+		byDay[h.Weekday] = append(byDay[h.Weekday], h)
+	}
+	for day, hours := range byDay {
+		sort.Slice(hours, func(i, j int) bool { return hours[i].Opens < hours[j].Opens })
+		for i := 1; i < len(hours); i++ {
+			if hours[i].Opens < hours[i-1].Closes {
+				return fmt.Errorf("%w: periods on %s overlap", ErrInvalidHours, day)
+			}
+		}
+	}
+	return nil
+}
+
+// minutes returns when a period opens and closes in minutes after midnight
+func (h Hours) minutes() (opens, closes int, err error) {
+	o, err := time.Parse("15:04", h.Opens)
+	if err != nil {
+		return 0, 0, fmt.Errorf("%w: %v", ErrInvalidHours, err)
+	}
+	c, err := time.Parse("15:04", h.Closes)
+	if err != nil {
+		return 0, 0, fmt.Errorf("%w: %v", ErrInvalidHours, err)
+	}
+	return o.Hour()*60 + o.Minute(), c.Hour()*60 + c.Minute(), nil
+}
+
+// Slot returns the time a booking of the given number of slots starting at
+// start would occupy. The start must fall on the local slot grid of an
+// opening period, and the booking must end by the time that period closes,
+// which may be an hour sooner or later on days the clocks change.
+func (r Resource) Slot(start time.Time, slots int) (bookings.Slot, error) {
+	if slots <= 0 {
+		return bookings.Slot{}, fmt.Errorf("%w: at least one slot is required", ErrInvalidSlot)
+	}
+	loc, err := time.LoadLocation(r.TimeZone)
+	if err != nil {
+		return bookings.Slot{}, err
+	}
+	local := start.In(loc)
+	if local.Second() != 0 || local.Nanosecond() != 0 {
+		return bookings.Slot{}, ErrInvalidSlot
+	}
+	minute := local.Hour()*60 + local.Minute()
+	length := slots * r.SlotMinutes
+	for _, h := range r.Hours {
+		if h.Weekday != local.Weekday() {
+			continue
+		}
+		opens, closes, err := h.minutes()
+		if err != nil {
+			return bookings.Slot{}, err
+		}
+		if minute < opens || minute >= closes || (minute-opens)%r.SlotMinutes != 0 {
+			continue
+		}
+		end := start.Add(time.Duration(length) * time.Minute)
+		if end.After(time.Date(local.Year(), local.Month(), local.Day(), 0, closes, 0, 0, loc)) {
+			continue
+		}
+		return bookings.Slot{
+			ResourceID:   r.ID,
+			StartsAt:     start,
+			EndsAt:       end,
+			BlockedUntil: end.Add(time.Duration(r.BufferMinutes) * time.Minute),
+			TimeZone:     r.TimeZone,
+		}, nil
+	}
+	return bookings.Slot{}, ErrInvalidSlot
+}
@@ -0,0 +1,118 @@
+package resources
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSettingsCheck(t *testing.T) {
+	tests := []struct {
+		name  string
+		hours []Hours
+		want  error
+	}{
+		{"valid", []Hours{{time.Monday, "09:00", "12:00"}, {time.Monday, "13:00", "17:00"}}, nil},
+		{"touching periods", []Hours{{time.Monday, "09:00", "12:00"}, {time.Monday, "12:00", "17:00"}}, nil},
+		{"closes before opening", []Hours{{time.Monday, "17:00", "09:00"}}, ErrInvalidHours},
+		{"overlapping periods", []Hours{{time.Monday, "13:00", "17:00"}, {time.Monday, "09:00", "14:00"}}, ErrInvalidHours},
+		{"same hours on different days", []Hours{{time.Monday, "09:00", "17:00"}, {time.Tuesday, "09:00", "17:00"}}, nil},
+	}
+	for _, tt := range tests {
+		s := Settings{TimeZone: "UTC", SlotMinutes: 30, Hours: tt.hours}
+		if err := s.Check(); !errors.Is(err, tt.want) {
+			t.Errorf("%s: Check = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestSlot(t *testing.T) {
+	r := Resource{ID: 4, Settings: Settings{
+		TimeZone:      "Europe/Berlin",
+		SlotMinutes:   30,
+		BufferMinutes: 10,
+		Hours:         []Hours{{time.Monday, "09:00", "12:00"}, {time.Monday, "13:00", "17:00"}},
+	}}
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("time zone data not available: %v", err)
+	}
+	// 2026-03-02 is a Monday
+	at := func(hour, min int) time.Time { return time.Date(2026, 3, 2, hour, min, 0, 0, berlin) }
+	tests := []struct {
+		name  string
+		start time.Time
+		slots int
+		ok    bool
+	}{
+		{"at opening", at(9, 0), 1, true},
+		{"on the grid", at(10, 30), 1, true},
+		{"off the grid", at(10, 15), 1, false},
+		{"several slots", at(9, 0), 4, true},
+		{"runs past closing", at(11, 30), 2, false},
+		{"ends at closing", at(11, 30), 1, true},
+		{"during lunch", at(12, 0), 1, false},
+		{"afternoon period", at(13, 0), 1, true},
+		{"closed day", at(9, 0).AddDate(0, 0, 1), 1, false},
+		{"seconds", at(9, 0).Add(time.Second), 1, false},
+		{"same instant in UTC", at(9, 0).UTC(), 1, true},
+		{"no slots", at(9, 0), 0, false},
+	}
+	for _, tt := range tests {
+		slot, err := r.Slot(tt.start, tt.slots)
+		if tt.ok != (err == nil) {
+			t.Errorf("%s: Slot error = %v, want ok = %v", tt.name, err, tt.ok)
+			continue
+		}
+		if err != nil && !errors.Is(err, ErrInvalidSlot) {
+			t.Errorf("%s: expected ErrInvalidSlot, got %v", tt.name, err)
+		}
+		if err == nil {
+			length := time.Duration(tt.slots*30) * time.Minute
+			if !slot.EndsAt.Equal(tt.start.Add(length)) || !slot.BlockedUntil.Equal(slot.EndsAt.Add(10*time.Minute)) || slot.ResourceID != 4 {
+				t.Errorf("%s: unexpected slot %+v", tt.name, slot)
+			}
+		}
+	}
+}
+
+func TestSlotAcrossDaylightSaving(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("time zone data not available: %v", err)
+	}
+	r := Resource{ID: 4, Settings: Settings{
+		TimeZone:    "Europe/Berlin",
+		SlotMinutes: 60,
+		Hours:       []Hours{{time.Sunday, "00:00", "05:00"}},
+	}}
+	// Clocks go from 02:00 to 03:00 on Sunday 2026-03-29 and back from 03:00
+	// to 02:00 on Sunday 2026-10-25.
+	spring := func(hour int) time.Time { return time.Date(2026, 3, 29, hour, 0, 0, 0, berlin) }
+	autumn := func(hour int) time.Time { return time.Date(2026, 10, 25, hour, 0, 0, 0, berlin) }
+	tests := []struct {
+		name  string
+		start time.Time
+		slots int
+		ok    bool
+	}{
+		// Four wall-clock hours from 01:00 would end at 05:00, but four
+		// real hours end at 06:00 summer time.
+		{"runs past closing after the clocks go forward", spring(1), 4, false},
+		{"ends at closing after the clocks go forward", spring(1), 3, true},
+		// The night lasts an hour longer, so a slot that would run past
+		// 05:00 by the wall clock still ends by closing.
+		{"fits the longer night", autumn(1), 5, true},
+		{"runs past closing of the longer night", autumn(1), 6, false},
+	}
+	for _, tt := range tests {
+		slot, err := r.Slot(tt.start, tt.slots)
+		if tt.ok != (err == nil) {
+			t.Errorf("%s: Slot error = %v, want ok = %v", tt.name, err, tt.ok)
+			continue
+		}
+		if err == nil && (!slot.EndsAt.Equal(tt.start.Add(time.Duration(tt.slots)*time.Hour)) || slot.TimeZone != "Europe/Berlin") {
+			t.Errorf("%s: unexpected slot %+v", tt.name, slot)
+		}
+	}
+}
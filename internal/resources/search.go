@@ -0,0 +1,225 @@
+package resources
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"booking-app/internal/timezone"
+
+	"github.com/lib/pq"
+)
+
+// Blackout is a period when a resource cannot be booked, such as a holiday
+type Blackout struct {
+	ID         int       `json:"id" db:"id"`
+	ResourceID int       `json:"resource_id" db:"resource_id"`
+	StartsAt   time.Time `json:"starts_at" db:"starts_at" validate:"required"`
+	EndsAt     time.Time `json:"ends_at" db:"ends_at" validate:"required,gtfield=StartsAt"`
+	Reason     string    `json:"reason" db:"reason" validate:"max=255"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+}
+
+// MaxSearchMinutes is the longest opening a search can ask for, a day
+const MaxSearchMinutes = 1440
+
+// Search asks for openings of one or more resources lasting Minutes, at
+// most MaxSearchMinutes. No resource IDs means every resource. Durations are
+// rounded up to whole slots of each resource; zero means a single slot.
+type Search struct {
+	ResourceIDs []int
+	From        time.Time
+	To          time.Time
+	Minutes     int
+}
+
+// slots returns how many of a resource's slots a search needs
+func (q Search) slots(r Resource) int {
+	if q.Minutes <= 0 {
+		return 1
+	}
+	return (q.Minutes + r.SlotMinutes - 1) / r.SlotMinutes
+}
+
+func (s *DBStore) CreateBlackout(ctx context.Context, b Blackout) (Blackout, error) {
+	err := s.db.GetContext(ctx, &b, `INSERT INTO resource_blackouts (resource_id, starts_at, ends_at, reason)
+              VALUES ($1, $2, $3, $4) RETURNING *`, b.ResourceID, b.StartsAt, b.EndsAt, b.Reason)
+	return b, err
+}
+
+// ListBlackouts returns a resource's blackouts that have not ended yet
+func (s *DBStore) ListBlackouts(ctx context.Context, resourceID int) ([]Blackout, error) {
+	list := []Blackout{}
+	err := s.db.SelectContext(ctx, &list, `SELECT * FROM resource_blackouts
+              WHERE resource_id = $1 AND ends_at > now() ORDER BY starts_at`, resourceID)
+	return list, err
+}
+
+func (s *DBStore) DeleteBlackout(ctx context.Context, resourceID, id int) error {
+	res, err := s.db.ExecContext(ctx, "DELETE FROM resource_blackouts WHERE id = $1 AND resource_id = $2", id, resourceID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrBlackoutNotFound
+	}
+	return nil
+}
+
+// SlotReport shows an appointment slot of a resource that was oversold or
+// where more customers checked in than it has room for, which is one
+type SlotReport struct {
+	StartsAt      time.Time `json:"starts_at" db:"starts_at"`
+	StartsAtLocal time.Time `json:"starts_at_local" db:"-"`
+	Booked        int       `json:"booked" db:"booked"`
+	Oversold      int       `json:"oversold" db:"-"`
+	CheckedIn     int       `json:"checked_in" db:"checked_in"`
+	OverCapacity  int       `json:"over_capacity" db:"-"`
+}
+
+// OverbookingReport lists the slots of a resource starting in [from, to)
+// that hold more than one active appointment, grouped by start time. Every
+// appointment beyond the first is oversold.
+func (s *DBStore) OverbookingReport(ctx context.Context, r Resource, from, to time.Time) ([]SlotReport, error) {
+	if !to.After(from) || to.Sub(from) > MaxSearchDays*24*time.Hour {
+		return nil, fmt.Errorf("%w: the range must be positive and at most %d days", ErrInvalidSearch, MaxSearchDays)
+	}
+	list := []SlotReport{}
+	err := s.db.SelectContext(ctx, &list, `SELECT starts_at, count(*) AS booked, count(checked_in_at) AS checked_in
+              FROM bookings WHERE resource_id = $1 AND is_active AND starts_at >= $2 AND starts_at < $3
+              GROUP BY starts_at HAVING count(*) > 1 ORDER BY starts_at`, r.ID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	loc := timezone.Location(r.TimeZone)
+	for i := range list {
+		list[i].StartsAtLocal = list[i].StartsAt.In(loc)
+		list[i].Oversold = list[i].Booked - 1
+		if list[i].CheckedIn > 1 {
+			list[i].OverCapacity = list[i].CheckedIn - 1
+		}
+	}
+	return list, nil
+}
+
+// span is an interval on a given resource
+type span struct {
+	ResourceID int `db:"resource_id"`
+	Interval
+}
+
+// Availability finds the openings of the searched resources. Each kind of
+// data is loaded for all resources in one query, using the GiST indexes on
+// appointment, hold and blackout ranges, so a month-long search over many
+// resources costs a handful of queries.
+func (s *DBStore) Availability(ctx context.Context, q Search) ([]Opening, error) {
+	if q.Minutes < 0 || q.Minutes > MaxSearchMinutes {
+		return nil, fmt.Errorf("%w: the duration must be at most %d minutes", ErrInvalidSearch, MaxSearchMinutes)
+	}
+	if !q.To.After(q.From) || q.To.Sub(q.From) > MaxSearchDays*24*time.Hour {
+		return nil, fmt.Errorf("%w: the range must be positive and at most %d days", ErrInvalidSearch, MaxSearchDays)
+	}
+	list, err := s.loadResources(ctx, q.ResourceIDs)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]int64, len(list))
+	pad := 0
+	for i, r := range list {
+		ids[i] = int64(r.ID)
+		if n := q.slots(r)*r.SlotMinutes + r.BufferMinutes; n > pad {
+			pad = n
+		}
+	}
+	// Anything that could block a slot starting before To lies before
+	// To plus the longest appointment and buffer.
+	until := q.To.Add(time.Duration(pad) * time.Minute)
+
+	var taken []span
+	err = s.db.SelectContext(ctx, &taken, `SELECT resource_id, starts_at, blocked_until AS ends_at FROM bookings
+              WHERE resource_id = ANY($1) AND is_active AND tstzrange(starts_at, blocked_until) && tstzrange($2, $3)
+              UNION ALL
+              SELECT resource_id, starts_at, blocked_until AS ends_at FROM holds
+              WHERE resource_id = ANY($1) AND status = 'active' AND expires_at > now()
+              AND tstzrange(starts_at, blocked_until) && tstzrange($2, $3)`, pq.Array(ids), q.From, until)
+	if err != nil {
+		return nil, err
+	}
+	var blackouts []span
+	err = s.db.SelectContext(ctx, &blackouts, `SELECT resource_id, starts_at, ends_at FROM resource_blackouts
+              WHERE resource_id = ANY($1) AND tstzrange(starts_at, ends_at) && tstzrange($2, $3)`, pq.Array(ids), q.From, until)
+	if err != nil {
+		return nil, err
+	}
+	busy := make(map[int]*Busy, len(list))
+	for _, r := range list {
+		busy[r.ID] = &Busy{}
+	}
+	for _, sp := range taken {
+		busy[sp.ResourceID].Taken = append(busy[sp.ResourceID].Taken, sp.Interval)
+	}
+	for _, sp := range blackouts {
+		busy[sp.ResourceID].Blackouts = append(busy[sp.ResourceID].Blackouts, sp.Interval)
+	}
+
+	now := time.Now()
+	openings := []Opening{}
+	for _, r := range list {
+		found, err := r.Openings(q.From, q.To, now, q.slots(r), *busy[r.ID])
+		if err != nil {
+			return nil, err
+		}
+		openings = append(openings, found...)
+	}
+	return openings, nil
+}
+
+// loadResources loads resources with their opening hours in two queries.
+// Asking for a resource that does not exist fails with ErrResourceNotFound.
+func (s *DBStore) loadResources(ctx context.Context, ids []int) ([]Resource, error) {
+	want := make([]int64, len(ids))
+	for i, id := range ids {
+		want[i] = int64(id)
+	}
+	list := []Resource{}
+	err := s.db.SelectContext(ctx, &list, `SELECT * FROM resources
+              WHERE cardinality($1::int[]) = 0 OR id = ANY($1) ORDER BY id`, pq.Array(want))
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) > 0 && len(list) != len(uniqueInts(ids)) {
+		return nil, ErrResourceNotFound
+	}
+	var hours []struct {
+		ResourceID int `db:"resource_id"`
+		Hours
+	}
+	err = s.db.SelectContext(ctx, &hours, `SELECT resource_id, weekday, to_char(opens, 'HH24:MI') AS opens,
+              to_char(closes, 'HH24:MI') AS closes FROM resource_hours
+              WHERE cardinality($1::int[]) = 0 OR resource_id = ANY($1)`, pq.Array(want))
+	if err != nil {
+		return nil, err
+	}
+	index := make(map[int]int, len(list))
+	for i, r := range list {
+		index[r.ID] = i
+	}
+	for _, h := range hours {
+		if i, ok := index[h.ResourceID]; ok {
+			list[i].Hours = append(list[i].Hours, h.Hours)
+		}
+	}
+	return list, nil
+}
+
+func uniqueInts(ids []int) map[int]bool {
+	seen := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		seen[id] = true
+	}
+	return seen
+}
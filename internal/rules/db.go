@@ -0,0 +1,73 @@
+package rules
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"booking-app/internal/timezone"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// CheckEventTx checks a booking against the rules and blackouts of the
+// named event. The rules count from the event's start, so events without a
+// start, and names without an event row, have none to break.
+func CheckEventTx(ctx context.Context, q sqlx.QueryerContext, name string, now time.Time) error {
+	var e struct {
+		ID       int        `db:"id"`
+		StartsAt *time.Time `db:"starts_at"`
+		TimeZone string     `db:"time_zone"`
+		Rules
+	}
+	err := sqlx.GetContext(ctx, q, &e, `SELECT id, starts_at, time_zone, min_notice_minutes, max_advance_days,
+              cutoff_time, cutoff_days_before FROM events WHERE name = $1`, name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if e.StartsAt == nil {
+		return nil
+	}
+	loc := timezone.Location(e.TimeZone)
+	var found []blackout
+	err = sqlx.SelectContext(ctx, q, &found, `SELECT starts_at, ends_at, reason FROM event_blackouts
+              WHERE event_id = $1 AND tstzrange(starts_at, ends_at) @> $2::timestamptz LIMIT 1`, e.ID, *e.StartsAt)
+	if err != nil {
+		return err
+	}
+	if len(found) > 0 {
+		return found[0].err(loc)
+	}
+	return e.Rules.Check(*e.StartsAt, now, loc)
+}
+
+// CheckSlotTx checks an appointment from start to end against the rules
+// and blackouts of its resource. Unlike events, an appointment may not
+// overlap a blackout at all.
+func CheckSlotTx(ctx context.Context, q sqlx.QueryerContext, resourceID int, start, end, now time.Time) error {
+	var r struct {
+		TimeZone string `db:"time_zone"`
+		Rules
+	}
+	err := sqlx.GetContext(ctx, q, &r, `SELECT time_zone, min_notice_minutes, max_advance_days, cutoff_time, cutoff_days_before
+              FROM resources WHERE id = $1`, resourceID)
+	if err != nil {
+		return err
+	}
+	loc := timezone.Location(r.TimeZone)
+	var found []blackout
+	err = sqlx.SelectContext(ctx, q, &found, `SELECT starts_at, ends_at, reason FROM resource_blackouts
+              WHERE resource_id = $1 AND tstzrange(starts_at, ends_at) && tstzrange($2, $3)
+              ORDER BY starts_at LIMIT 1`, resourceID, start, end)
+	if err != nil {
+		return err
+	}
+	if len(found) > 0 {
+		return found[0].err(loc)
+	}
+	return r.Rules.Check(start, now, loc)
+}
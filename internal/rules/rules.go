@@ -0,0 +1,101 @@
+// Package rules decides whether something may be booked yet, based on when
+// it starts: how much notice a booking needs, how far ahead bookings open,
+// when they close and which periods are blacked out. Events and resources
+// carry the same rules; both are checked whenever a booking or hold is made.
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrNotBookable is wrapped by every rejection, with the reason appended
+var ErrNotBookable = errors.New("booking not allowed")
+
+// Rules are the booking window of an event or resource. CutoffTime closes
+// bookings at that local time CutoffDaysBefore days before the day of the
+// start, the same day if not given.
+type Rules struct {
+	MinNoticeMinutes *int    `json:"min_notice_minutes" db:"min_notice_minutes" validate:"omitempty,min=0"`
+	MaxAdvanceDays   *int    `json:"max_advance_days" db:"max_advance_days" validate:"omitempty,min=1"`
+	CutoffTime       *string `json:"cutoff_time" db:"cutoff_time" validate:"omitempty,datetime=15:04"`
+	CutoffDaysBefore *int    `json:"cutoff_days_before" db:"cutoff_days_before" validate:"omitempty,min=0,max=365"`
+}
+
+// Check returns why a booking made at now for something starting at start
+// is not allowed, or nil if it is. Dates are reckoned in loc.
+func (r Rules) Check(start, now time.Time, loc *time.Location) error {
+	if r.MinNoticeMinutes != nil {
+		if notice := time.Duration(*r.MinNoticeMinutes) * time.Minute; now.Add(notice).After(start) {
+			return fmt.Errorf("%w: bookings must be made at least %s before the start", ErrNotBookable, minutes(*r.MinNoticeMinutes))
+		}
+	}
+	if r.CutoffTime != nil {
+		cutoff, err := r.cutoff(start, loc)
+		if err != nil {
+			return err
+		}
+		if !now.Before(cutoff) {
+			return fmt.Errorf("%w: bookings closed at %s", ErrNotBookable, cutoff.Format("2006-01-02 15:04 MST"))
+		}
+	}
+	if r.MaxAdvanceDays != nil {
+		local := now.In(loc)
+		if opens := start.In(loc).AddDate(0, 0, -*r.MaxAdvanceDays); local.Before(opens) {
+			return fmt.Errorf("%w: bookings open %d days before the start, at %s", ErrNotBookable, *r.MaxAdvanceDays,
+				opens.Format("2006-01-02 15:04 MST"))
+		}
+	}
+	return nil
+}
+
+// cutoff returns when bookings for something starting at start close
+func (r Rules) cutoff(start time.Time, loc *time.Location) (time.Time, error) {
+	at, err := time.Parse("15:04", *r.CutoffTime)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid cut-off time %q: %v", *r.CutoffTime, err)
+	}
+	days := 0
+	if r.CutoffDaysBefore != nil {
+		days = *r.CutoffDaysBefore
+	}
+	local := start.In(loc)
+	return time.Date(local.Year(), local.Month(), local.Day()-days, at.Hour(), at.Minute(), 0, 0, loc), nil
+}
+
+// blackout is a period in which nothing can be booked
+type blackout struct {
+	StartsAt time.Time `db:"starts_at"`
+	EndsAt   time.Time `db:"ends_at"`
+	Reason   string    `db:"reason"`
+}
+
+// err returns the rejection of a booking that falls in the blackout
+func (b blackout) err(loc *time.Location) error {
+	reason := ""
+	if b.Reason != "" {
+		reason = " (" + b.Reason + ")"
+	}
+	return fmt.Errorf("%w: blacked out from %s to %s%s", ErrNotBookable,
+		b.StartsAt.In(loc).Format("2006-01-02 15:04"), b.EndsAt.In(loc).Format("2006-01-02 15:04 MST"), reason)
+}
+
+// minutes formats a notice period in the largest whole unit
+func minutes(n int) string {
+	switch {
+	case n%(24*60) == 0 && n > 0:
+		return plural(n/(24*60), "day")
+	case n%60 == 0 && n > 0:
+		return plural(n/60, "hour")
+	default:
+		return plural(n, "minute")
+	}
+}
+
+func plural(n int, unit string) string {
+	if n == 1 {
+		return "1 " + unit
+	}
+	return fmt.Sprintf("%d %ss", n, unit)
+}
@@ -0,0 +1,82 @@
+package rules
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func intPtr(n int) *int       { return &n }
+func strPtr(s string) *string { return &s }
+
+func TestCheck(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("time zone data not available: %v", err)
+	}
+	// Starts at 10:00 in Berlin on Saturday 2026-06-13
+	start := time.Date(2026, 6, 13, 10, 0, 0, 0, berlin)
+	tests := []struct {
+		name   string
+		rules  Rules
+		now    time.Time
+		reason string // empty if the booking is allowed
+	}{
+		{"no rules", Rules{}, start.Add(-time.Minute), ""},
+		{"enough notice", Rules{MinNoticeMinutes: intPtr(120)}, start.Add(-2 * time.Hour), ""},
+		{"too little notice", Rules{MinNoticeMinutes: intPtr(120)}, start.Add(-119 * time.Minute), "at least 2 hours before"},
+		{"notice in minutes", Rules{MinNoticeMinutes: intPtr(90)}, start.Add(-time.Hour), "at least 90 minutes before"},
+		{"within the advance window", Rules{MaxAdvanceDays: intPtr(30)}, start.AddDate(0, 0, -30), ""},
+		{"too far ahead", Rules{MaxAdvanceDays: intPtr(30)}, start.AddDate(0, 0, -30).Add(-time.Minute), "open 30 days before the start, at 2026-05-14 10:00"},
+		{"before a same-day cut-off", Rules{CutoffTime: strPtr("08:00")}, start.Add(-2*time.Hour - time.Second), ""},
+		{"after a same-day cut-off", Rules{CutoffTime: strPtr("08:00")}, start.Add(-2 * time.Hour), "closed at 2026-06-13 08:00 CEST"},
+		{"before the day-before cut-off", Rules{CutoffTime: strPtr("18:00"), CutoffDaysBefore: intPtr(1)},
+			time.Date(2026, 6, 12, 17, 59, 0, 0, berlin), ""},
+		{"after the day-before cut-off", Rules{CutoffTime: strPtr("18:00"), CutoffDaysBefore: intPtr(1)},
+			time.Date(2026, 6, 12, 18, 30, 0, 0, berlin), "closed at 2026-06-12 18:00 CEST"},
+	}
+	for _, tt := range tests {
+		err := tt.rules.Check(start, tt.now, berlin)
+		if tt.reason == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", tt.name, err)
+			}
+			continue
+		}
+		if !errors.Is(err, ErrNotBookable) || !strings.Contains(err.Error(), tt.reason) {
+			t.Errorf("%s: got %v, want ErrNotBookable mentioning %q", tt.name, err, tt.reason)
+		}
+	}
+}
+
+// The advance window counts calendar days in the local time zone, so it
+// opens at the same wall-clock time across a daylight saving change.
+func TestMaxAdvanceAcrossDaylightSaving(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("time zone data not available: %v", err)
+	}
+	start := time.Date(2026, 4, 2, 9, 0, 0, 0, berlin)
+	r := Rules{MaxAdvanceDays: intPtr(7)}
+	opens := time.Date(2026, 3, 26, 9, 0, 0, 0, berlin)
+	if err := r.Check(start, opens, berlin); err != nil {
+		t.Errorf("expected bookings to open at %v: %v", opens, err)
+	}
+	if err := r.Check(start, opens.Add(-time.Minute), berlin); err == nil {
+		t.Error("expected bookings to be closed a minute before the window opens")
+	}
+}
+
+func TestBlackoutReason(t *testing.T) {
+	b := blackout{
+		StartsAt: time.Date(2026, 12, 24, 0, 0, 0, 0, time.UTC),
+		EndsAt:   time.Date(2026, 12, 27, 0, 0, 0, 0, time.UTC),
+		Reason:   "Christmas",
+	}
+	err := b.err(time.UTC)
+	want := "booking not allowed: blacked out from 2026-12-24 00:00 to 2026-12-27 00:00 UTC (Christmas)"
+	if !errors.Is(err, ErrNotBookable) || err.Error() != want {
+		t.Errorf("got %q, want %q", err, want)
+	}
+}
@@ -0,0 +1,145 @@
+package seating
+
+import "errors"
+
+var ErrNoSeatsTogether = errors.New("not enough adjacent seats available")
+
+// Request asks for a number of adjacent seats. Sections and PriceTiers list
+// acceptable values in order of preference; an empty list accepts any value.
+type Request struct {
+	Quantity   int      `json:"quantity" validate:"required,min=1,max=20"`
+	Sections   []string `json:"sections" validate:"max=20"`
+	PriceTiers []string `json:"price_tiers" validate:"max=20"`
+}
+
+// BestAvailable picks req.Quantity available seats side by side in one row.
+// seats must be ordered by section, row and position as Availability returns
+// them. Blocks are ranked by, in order: section preference, price tier
+// preference, whether the block strands a single seat at either end, row
+// (front first), distance from the centre of the row, and position. The
+// result is therefore deterministic for a given seat map and state.
+func BestAvailable(seats []SeatView, req Request) ([]Seat, error) {
+	if req.Quantity < 1 {
+		return nil, errors.New("quantity must be at least 1")
+	}
+	var best []Seat
+	var bestScore []int
+	rowNumbers := make(map[string]int)
+	for _, row := range splitRows(seats) {
+		section := row[0].Section
+		rowNumber := rowNumbers[section]
+		rowNumbers[section]++
+		sectionRank := preference(req.Sections, section)
+		if sectionRank < 0 {
+			continue
+		}
+		// Twice the row's centre, so that distances stay whole numbers.
+		centre := row[0].Position + row[len(row)-1].Position
+		for _, run := range availableRuns(row) {
+			for start := 0; start+req.Quantity <= len(run); start++ {
+				block := run[start : start+req.Quantity]
+				tierRank := blockTierRank(block, req.PriceTiers)
+				if tierRank < 0 {
+					continue
+				}
+				orphan := 0
+				if start == 1 || len(run)-start-req.Quantity == 1 {
+					orphan = 1
+				}
+				score := []int{
+					sectionRank,
+					tierRank,
+					orphan,
+					rowNumber,
+					abs(block[0].Position + block[len(block)-1].Position - centre),
+					block[0].Position,
+				}
+				if best == nil || less(score, bestScore) {
+					best = block
+					bestScore = score
+				}
+			}
+		}
+	}
+	if best == nil {
+		return nil, ErrNoSeatsTogether
+	}
+	return append([]Seat(nil), best...), nil
+}
+
+// splitRows groups consecutive seats that share a section and row
+func splitRows(seats []SeatView) [][]SeatView {
+	var rows [][]SeatView
+	for i, s := range seats {
+		if i == 0 || s.Section != seats[i-1].Section || s.Row != seats[i-1].Row {
+			rows = append(rows, nil)
+		}
+		rows[len(rows)-1] = append(rows[len(rows)-1], s)
+	}
+	return rows
+}
+
+// availableRuns returns the row's maximal runs of adjacent available seats
+func availableRuns(row []SeatView) [][]Seat {
+	var runs [][]Seat
+	var run []Seat
+	for _, s := range row {
+		if s.State != StateAvailable {
+			run = nil
+			continue
+		}
+		if len(run) > 0 && run[len(run)-1].Position+1 != s.Position {
+			run = nil
+		}
+		if run == nil {
+			runs = append(runs, nil)
+		}
+		run = append(run, s.Seat)
+		runs[len(runs)-1] = run
+	}
+	return runs
+}
+
+// blockTierRank returns the least preferred tier rank in the block, or -1 if
+// any seat's tier is not acceptable
+func blockTierRank(block []Seat, tiers []string) int {
+	worst := 0
+	for _, s := range block {
+		r := preference(tiers, s.PriceTier)
+		if r < 0 {
+			return -1
+		}
+		if r > worst {
+			worst = r
+		}
+	}
+	return worst
+}
+
+func preference(list []string, v string) int {
+	if len(list) == 0 {
+		return 0
+	}
+	for i, item := range list {
+		if item == v {
+			return i
+		}
+	}
+	return -1
+}
+
+func less(a, b []int) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return a[i] < b[i]
+		}
+	}
+	return false
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
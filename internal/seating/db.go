@@ -0,0 +1,208 @@
+package seating
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"sort"
+
+	"booking-app/internal/bookings"
+	"booking-app/internal/database"
+	"booking-app/internal/events"
+	"booking-app/internal/middleware"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	ErrSeatMapNotFound = errors.New("seat map not found")
+	ErrNoSeatMap       = errors.New("event does not have assigned seating")
+)
+
+// DBStore manages seat maps and seat reservations in PostgreSQL
+type DBStore struct {
+	db       *sqlx.DB
+	bookings *bookings.DBStore
+}
+
+func NewDBStore(db *sqlx.DB, bookingStore *bookings.DBStore) *DBStore {
+	return &DBStore{db: db, bookings: bookingStore}
+}
+
+// ImportSeatMap creates a seat map and all of its seats from a spec, owned
+// by the authenticated user
+func (s *DBStore) ImportSeatMap(ctx context.Context, spec MapSpec) (SeatMap, error) {
+	seats, err := spec.Seats()
+	if err != nil {
+		return SeatMap{}, err
+	}
+	var owner *int
+	if userID, ok := middleware.UserIDFromContext(ctx); ok {
+		owner = &userID
+	}
+	var m SeatMap
+	err = database.WithTx(ctx, s.db, func(tx *sqlx.Tx) error {
+		if err := tx.GetContext(ctx, &m, "INSERT INTO seat_maps (name, owner_id) VALUES ($1, $2) RETURNING *", spec.Name, owner); err != nil {
+			return err
+		}
+		stmt, err := tx.PreparexContext(ctx, `INSERT INTO seats (seat_map_id, section, row_label, number, position, accessible, obstructed, price_tier)
+              VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`)
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+		for _, seat := range seats {
+			if _, err := stmt.ExecContext(ctx, m.ID, seat.Section, seat.Row, seat.Number, seat.Position, seat.Accessible, seat.Obstructed, seat.PriceTier); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return m, err
+}
+
+func (s *DBStore) GetSeatMap(ctx context.Context, id int) (SeatMap, error) {
+	var m SeatMap
+	err := s.db.GetContext(ctx, &m, "SELECT * FROM seat_maps WHERE id = $1", id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return SeatMap{}, ErrSeatMapNotFound
+	}
+	return m, err
+}
+
+// GetLayout returns a seat map's seats without any booking state
+func (s *DBStore) GetLayout(ctx context.Context, id int) (Layout, error) {
+	m, err := s.GetSeatMap(ctx, id)
+	if err != nil {
+		return Layout{}, err
+	}
+	var seats []SeatView
+	err = s.db.SelectContext(ctx, &seats, "SELECT * FROM seats WHERE seat_map_id = $1 ORDER BY id", id)
+	if err != nil {
+		return Layout{}, err
+	}
+	return buildLayout(m, "", seats), nil
+}
+
+// Availability returns the event's seat map with the state of every seat
+func (s *DBStore) Availability(ctx context.Context, e events.Event) (Layout, error) {
+	if e.SeatMapID == nil {
+		return Layout{}, ErrNoSeatMap
+	}
+	m, err := s.GetSeatMap(ctx, *e.SeatMapID)
+	if err != nil {
+		return Layout{}, err
+	}
+	seats, err := seatStates(ctx, s.db, *e.SeatMapID, e.Name)
+	if err != nil {
+		return Layout{}, err
+	}
+	return buildLayout(m, e.Name, seats), nil
+}
+
+// ReserveSeats books every requested seat for the user, or none of them if
+// any is taken. Seats are booked in ID order so that concurrent reservations
+// of overlapping seats cannot deadlock.
+func (s *DBStore) ReserveSeats(ctx context.Context, e events.Event, userName string, seatIDs []int, ticketTypeID *int, answers json.RawMessage) ([]bookings.Booking, error) {
+	if e.SeatMapID == nil {
+		return nil, ErrNoSeatMap
+	}
+	ids := append([]int(nil), seatIDs...)
+	sort.Ints(ids)
+	var created []bookings.Booking
+	err := database.WithTx(ctx, s.db, func(tx *sqlx.Tx) error {
+		var err error
+		created, err = s.reserveTx(ctx, tx, e, userName, ids, ticketTypeID, answers)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// ReserveBestAvailable allocates and books adjacent seats in one
+// transaction. Capacity is reserved first, which locks the event row, so the
+// seat states the allocator sees cannot change before the bookings are made.
+// The last of the places go beyond capacity if the event is oversold.
+func (s *DBStore) ReserveBestAvailable(ctx context.Context, e events.Event, userName string, req Request, ticketTypeID *int, answers json.RawMessage) ([]bookings.Booking, error) {
+	if e.SeatMapID == nil {
+		return nil, ErrNoSeatMap
+	}
+	var created []bookings.Booking
+	err := database.WithTx(ctx, s.db, func(tx *sqlx.Tx) error {
+		oversold, err := events.ReserveCapacity(ctx, tx, e.Name, req.Quantity)
+		if err != nil {
+			return err
+		}
+		states, err := seatStates(ctx, tx, *e.SeatMapID, e.Name)
+		if err != nil {
+			return err
+		}
+		seats, err := BestAvailable(states, req)
+		if err != nil {
+			return err
+		}
+		ids := make([]int, len(seats))
+		for i, seat := range seats {
+			ids[i] = seat.ID
+		}
+		if created, err = s.reserveTx(ctx, tx, e, userName, ids, ticketTypeID, answers); err != nil {
+			return err
+		}
+		return markOversold(ctx, tx, created, oversold)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// markOversold flags the last n of the bookings as sold beyond capacity
+func markOversold(ctx context.Context, tx *sqlx.Tx, created []bookings.Booking, n int) error {
+	for i := len(created) - n; i < len(created); i++ {
+		if i < 0 || created[i].Oversold {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, "UPDATE bookings SET oversold = true WHERE id = $1", created[i].ID); err != nil {
+			return err
+		}
+		created[i].Oversold = true
+	}
+	return nil
+}
+
+// reserveTx books each seat with the same attendee answers
+func (s *DBStore) reserveTx(ctx context.Context, tx *sqlx.Tx, e events.Event, userName string, seatIDs []int, ticketTypeID *int, answers json.RawMessage) ([]bookings.Booking, error) {
+	var created []bookings.Booking
+	for i, id := range seatIDs {
+		if i > 0 && seatIDs[i-1] == id {
+			continue
+		}
+		seatID := id
+		b, err := s.bookings.CreateBookingTx(ctx, tx, bookings.NewBooking{
+			UserName:     userName,
+			Event:        e.Name,
+			SeatID:       &seatID,
+			TicketTypeID: ticketTypeID,
+			Answers:      answers,
+		})
+		if err != nil {
+			return nil, err
+		}
+		created = append(created, b)
+	}
+	return created, nil
+}
+
+func seatStates(ctx context.Context, q sqlx.QueryerContext, seatMapID int, event string) ([]SeatView, error) {
+	var seats []SeatView
+	err := sqlx.SelectContext(ctx, q, &seats, `SELECT s.*,
+              CASE WHEN b.id IS NULL THEN 'available' ELSE 'booked' END AS state
+              FROM seats s
+              LEFT JOIN bookings b ON b.seat_id = s.id AND b.event = $2 AND b.is_active
+              WHERE s.seat_map_id = $1
+              ORDER BY s.id`, seatMapID, event)
+	return seats, err
+}
@@ -0,0 +1,182 @@
+package seating
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"booking-app/internal/bookings"
+	"booking-app/internal/events"
+	"booking-app/internal/rules"
+	"booking-app/internal/waitingroom"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+)
+
+var validate = validator.New()
+
+type Handler struct {
+	store  *DBStore
+	events *events.DBStore
+}
+
+func NewHandler(store *DBStore, eventStore *events.DBStore) *Handler {
+	return &Handler{store: store, events: eventStore}
+}
+
+// ImportSeatMapHandler creates a seat map from a JSON MapSpec document.
+// Only those who may create events, admins and organizers, may import one.
+func (h *Handler) ImportSeatMapHandler(w http.ResponseWriter, r *http.Request) {
+	if !events.CanCreate(r.Context()) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	var spec MapSpec
+	if err := json.NewDecoder(r.Body).Decode(&spec); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := validate.Struct(&spec); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	m, err := h.store.ImportSeatMap(r.Context(), spec)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, m)
+}
+
+func (h *Handler) GetSeatMapHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	layout, err := h.store.GetLayout(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, layout)
+}
+
+// AvailabilityHandler returns an event's seat map with each seat's state
+func (h *Handler) AvailabilityHandler(w http.ResponseWriter, r *http.Request) {
+	e, ok := h.loadEvent(w, r)
+	if !ok {
+		return
+	}
+	layout, err := h.store.Availability(r.Context(), e)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, layout)
+}
+
+// ReserveSeatsHandler books specific seats on an event, all or nothing
+func (h *Handler) ReserveSeatsHandler(w http.ResponseWriter, r *http.Request) {
+	e, ok := h.loadEvent(w, r)
+	if !ok {
+		return
+	}
+	var input struct {
+		UserName     string `json:"user_name" validate:"required"`
+		SeatIDs      []int  `json:"seat_ids" validate:"required,min=1,max=20"`
+		TicketTypeID *int   `json:"ticket_type_id"`
+		// Answers respond to the event's attendee questions for every seat
+		Answers json.RawMessage `json:"answers"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := validate.Struct(&input); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	created, err := h.store.ReserveSeats(r.Context(), e, input.UserName, input.SeatIDs, input.TicketTypeID, input.Answers)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, created)
+}
+
+// BestAvailableHandler books the best block of adjacent seats for a quantity
+func (h *Handler) BestAvailableHandler(w http.ResponseWriter, r *http.Request) {
+	e, ok := h.loadEvent(w, r)
+	if !ok {
+		return
+	}
+	var input struct {
+		UserName     string `json:"user_name" validate:"required"`
+		TicketTypeID *int   `json:"ticket_type_id"`
+		// Answers respond to the event's attendee questions for every seat
+		Answers json.RawMessage `json:"answers"`
+		Request
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := validate.Struct(&input); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	created, err := h.store.ReserveBestAvailable(r.Context(), e, input.UserName, input.Request, input.TicketTypeID, input.Answers)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, created)
+}
+
+func (h *Handler) loadEvent(w http.ResponseWriter, r *http.Request) (events.Event, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return events.Event{}, false
+	}
+	e, err := h.events.GetEvent(r.Context(), id)
+	if errors.Is(err, events.ErrEventNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return events.Event{}, false
+	}
+	if err != nil {
+		http.Error(w, "Failed to fetch event", http.StatusInternalServerError)
+		return events.Event{}, false
+	}
+	return e, true
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrSeatMapNotFound), errors.Is(err, ErrNoSeatMap):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, waitingroom.ErrNotAdmitted):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, bookings.ErrSeatTaken), errors.Is(err, events.ErrEventFull), errors.Is(err, ErrNoSeatsTogether),
+		errors.Is(err, events.ErrSoldOut), errors.Is(err, events.ErrNotOnSale), errors.Is(err, rules.ErrNotBookable),
+		errors.Is(err, bookings.ErrLimitReached), errors.Is(err, bookings.ErrOverlap):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, bookings.ErrInvalidAnswers):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	case errors.Is(err, ErrInvalidMap), errors.Is(err, bookings.ErrUnknownSeat), errors.Is(err, events.ErrTicketTypeRequired), errors.Is(err, events.ErrTicketTypeNotFound):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,40 @@
+package seating
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"booking-app/internal/middleware"
+)
+
+func TestImportSeatMapHandler(t *testing.T) {
+	h := NewHandler(NewDBStore(nil, nil), nil)
+	tests := []struct {
+		name string
+		role string
+		body string
+		want int
+	}{
+		{"customer", "user", `{"name": "Hall", "sections": [{"name": "stalls", "rows": [{"label": "A", "count": 10}]}]}`, http.StatusForbidden},
+		{"anonymous", "", `{"name": "Hall", "sections": [{"name": "stalls", "rows": [{"label": "A", "count": 10}]}]}`, http.StatusForbidden},
+		{"malformed", middleware.RoleOrganizer, `{"name":`, http.StatusBadRequest},
+		{"no name", middleware.RoleOrganizer, `{"sections": [{"name": "stalls", "rows": [{"label": "A", "count": 10}]}]}`, http.StatusBadRequest},
+		{"no sections", middleware.RoleOrganizer, `{"name": "Hall", "sections": []}`, http.StatusBadRequest},
+		{"too many seats in a row", middleware.RoleAdmin, `{"name": "Hall", "sections": [{"name": "stalls", "rows": [{"label": "A", "count": 1001}]}]}`, http.StatusBadRequest},
+		{"row without seats", middleware.RoleOrganizer, `{"name": "Hall", "sections": [{"name": "stalls", "rows": [{"label": "A"}]}]}`, http.StatusBadRequest},
+		{"duplicate row", middleware.RoleOrganizer, `{"name": "Hall", "sections": [{"name": "stalls", "rows": [{"label": "A", "count": 1}, {"label": "A", "count": 2}]}]}`, http.StatusBadRequest},
+		{"duplicate seat", middleware.RoleOrganizer, `{"name": "Hall", "sections": [{"name": "stalls", "rows": [{"label": "A", "seats": [{"number": 1}, {"number": 1}]}]}]}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodPost, "/seat-maps", strings.NewReader(tt.body))
+		r = r.WithContext(context.WithValue(r.Context(), middleware.RoleKey, tt.role))
+		w := httptest.NewRecorder()
+		h.ImportSeatMapHandler(w, r)
+		if w.Code != tt.want {
+			t.Errorf("%s: expected status %d, got %d: %s", tt.name, tt.want, w.Code, w.Body.String())
+		}
+	}
+}
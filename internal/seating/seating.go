@@ -0,0 +1,165 @@
+package seating
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Seat states reported by the availability endpoint
+const (
+	StateAvailable = "available"
+	StateBooked    = "booked"
+)
+
+// ErrInvalidMap is returned for seat map specs that do not describe a venue
+var ErrInvalidMap = errors.New("invalid seat map")
+
+// SeatMap is a venue layout. OwnerID is the organizer who imported it, who
+// may use it for their events.
+type SeatMap struct {
+	ID        int       `json:"id" db:"id"`
+	Name      string    `json:"name" db:"name"`
+	OwnerID   *int      `json:"owner_id,omitempty" db:"owner_id"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// Seat is a physical seat. Position orders seats within a row from one end
+// to the other, so seats with consecutive positions sit side by side.
+type Seat struct {
+	ID         int    `json:"id" db:"id"`
+	SeatMapID  int    `json:"-" db:"seat_map_id"`
+	Section    string `json:"-" db:"section"`
+	Row        string `json:"-" db:"row_label"`
+	Number     int    `json:"number" db:"number"`
+	Position   int    `json:"-" db:"position"`
+	Accessible bool   `json:"accessible" db:"accessible"`
+	Obstructed bool   `json:"obstructed" db:"obstructed"`
+	PriceTier  string `json:"price_tier,omitempty" db:"price_tier"`
+}
+
+// MapSpec is the JSON document a seat map is imported from. A row lists its
+// seats in order, or gives a count to create seats numbered 1..count. A price
+// tier set on a section or row applies to every seat that does not set one.
+type MapSpec struct {
+	Name     string        `json:"name" validate:"required,max=255"`
+	Sections []SectionSpec `json:"sections" validate:"required,min=1,dive"`
+}
+
+type SectionSpec struct {
+	Name      string    `json:"name" validate:"required,max=64"`
+	PriceTier string    `json:"price_tier" validate:"max=64"`
+	Rows      []RowSpec `json:"rows" validate:"required,min=1,dive"`
+}
+
+type RowSpec struct {
+	Label     string     `json:"label" validate:"required,max=16"`
+	PriceTier string     `json:"price_tier" validate:"max=64"`
+	Count     int        `json:"count" validate:"omitempty,min=1,max=1000"`
+	Seats     []SeatSpec `json:"seats" validate:"omitempty,max=1000,dive"`
+}
+
+type SeatSpec struct {
+	Number     int    `json:"number" validate:"required,min=1"`
+	PriceTier  string `json:"price_tier" validate:"max=64"`
+	Accessible bool   `json:"accessible"`
+	Obstructed bool   `json:"obstructed"`
+}
+
+// Layout is a seat map with the state of every seat for one event
+type Layout struct {
+	SeatMapID int           `json:"seat_map_id"`
+	Name      string        `json:"name"`
+	Event     string        `json:"event"`
+	Sections  []SectionView `json:"sections"`
+}
+
+type SectionView struct {
+	Name string    `json:"name"`
+	Rows []RowView `json:"rows"`
+}
+
+type RowView struct {
+	Label string     `json:"label"`
+	Seats []SeatView `json:"seats"`
+}
+
+type SeatView struct {
+	Seat
+	State string `json:"state,omitempty"`
+}
+
+// Seats expands a spec into seats in import order
+func (m MapSpec) Seats() ([]Seat, error) {
+	var seats []Seat
+	sections := make(map[string]bool)
+	for _, section := range m.Sections {
+		if sections[section.Name] {
+			return nil, fmt.Errorf("%w: duplicate section %q", ErrInvalidMap, section.Name)
+		}
+		sections[section.Name] = true
+		rows := make(map[string]bool)
+		for _, row := range section.Rows {
+			if rows[row.Label] {
+				return nil, fmt.Errorf("%w: duplicate row %q in section %q", ErrInvalidMap, row.Label, section.Name)
+			}
+			rows[row.Label] = true
+			specs := row.Seats
+			if len(specs) == 0 {
+				if row.Count == 0 {
+					return nil, fmt.Errorf("%w: row %q in section %q needs seats or a count", ErrInvalidMap, row.Label, section.Name)
+				}
+				for n := 1; n <= row.Count; n++ {
+					specs = append(specs, SeatSpec{Number: n})
+				}
+			}
+			numbers := make(map[int]bool)
+			for i, spec := range specs {
+				if numbers[spec.Number] {
+					return nil, fmt.Errorf("%w: duplicate seat %d in row %q of section %q", ErrInvalidMap, spec.Number, row.Label, section.Name)
+				}
+				numbers[spec.Number] = true
+				tier := spec.PriceTier
+				if tier == "" {
+					tier = row.PriceTier
+				}
+				if tier == "" {
+					tier = section.PriceTier
+				}
+				seats = append(seats, Seat{
+					Section:    section.Name,
+					Row:        row.Label,
+					Number:     spec.Number,
+					Position:   i,
+					Accessible: spec.Accessible,
+					Obstructed: spec.Obstructed,
+					PriceTier:  tier,
+				})
+			}
+		}
+	}
+	if len(seats) == 0 {
+		return nil, fmt.Errorf("%w: no seats", ErrInvalidMap)
+	}
+	return seats, nil
+}
+
+// buildLayout groups seats, ordered by section, row and position, into a layout
+func buildLayout(m SeatMap, event string, seats []SeatView) Layout {
+	layout := Layout{SeatMapID: m.ID, Name: m.Name, Event: event, Sections: []SectionView{}}
+	for _, s := range seats {
+		n := len(layout.Sections)
+		if n == 0 || layout.Sections[n-1].Name != s.Section {
+			layout.Sections = append(layout.Sections, SectionView{Name: s.Section})
+			n++
+		}
+		section := &layout.Sections[n-1]
+		r := len(section.Rows)
+		if r == 0 || section.Rows[r-1].Label != s.Row {
+			section.Rows = append(section.Rows, RowView{Label: s.Row})
+			r++
+		}
+		section.Rows[r-1].Seats = append(section.Rows[r-1].Seats, s)
+	}
+	return layout
+}
@@ -0,0 +1,171 @@
+package seating
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// rowSeats builds one row from a pattern: '.' is an available seat, 'x' a
+// booked one. Seats are numbered from 1 and get IDs starting at firstID.
+func rowSeats(section, row, tier, pattern string, firstID int) []SeatView {
+	seats := make([]SeatView, len(pattern))
+	for i, c := range pattern {
+		state := StateAvailable
+		if c == 'x' {
+			state = StateBooked
+		}
+		seats[i] = SeatView{
+			Seat:  Seat{ID: firstID + i, Section: section, Row: row, Number: i + 1, Position: i, PriceTier: tier},
+			State: state,
+		}
+	}
+	return seats
+}
+
+func concat(rows ...[]SeatView) []SeatView {
+	var seats []SeatView
+	for _, r := range rows {
+		seats = append(seats, r...)
+	}
+	return seats
+}
+
+func TestBestAvailable(t *testing.T) {
+	tests := []struct {
+		name    string
+		seats   []SeatView
+		req     Request
+		wantIDs []int
+		wantErr error
+	}{
+		{
+			name:    "centre of an empty row",
+			seats:   rowSeats("stalls", "A", "", ".......", 1),
+			req:     Request{Quantity: 3},
+			wantIDs: []int{3, 4, 5},
+		},
+		{
+			name:    "front row first",
+			seats:   concat(rowSeats("stalls", "A", "", "xx.xx", 1), rowSeats("stalls", "B", "", ".....", 6)),
+			req:     Request{Quantity: 2},
+			wantIDs: []int{6, 7},
+		},
+		{
+			name:    "avoids leaving a single seat",
+			seats:   rowSeats("stalls", "A", "", "x....x", 1),
+			req:     Request{Quantity: 3},
+			wantIDs: []int{2, 3, 4},
+		},
+		{
+			name:    "whole gap is not an orphan",
+			seats:   rowSeats("stalls", "A", "", "x...x", 1),
+			req:     Request{Quantity: 3},
+			wantIDs: []int{2, 3, 4},
+		},
+		{
+			name:    "prefers a later row over an orphan",
+			seats:   concat(rowSeats("stalls", "A", "", "x...x", 1), rowSeats("stalls", "B", "", "xx..x", 6)),
+			req:     Request{Quantity: 2},
+			wantIDs: []int{8, 9},
+		},
+		{
+			name:    "accepts an orphan when nothing else fits",
+			seats:   rowSeats("stalls", "A", "", "x...x", 1),
+			req:     Request{Quantity: 2},
+			wantIDs: []int{2, 3},
+		},
+		{
+			name:    "section preference",
+			seats:   concat(rowSeats("stalls", "A", "", "....", 1), rowSeats("circle", "A", "", "....", 5)),
+			req:     Request{Quantity: 2, Sections: []string{"circle", "stalls"}},
+			wantIDs: []int{5, 6},
+		},
+		{
+			name:    "unlisted sections are skipped",
+			seats:   concat(rowSeats("stalls", "A", "", "....", 1), rowSeats("circle", "A", "", "x..x", 5)),
+			req:     Request{Quantity: 3, Sections: []string{"circle"}},
+			wantErr: ErrNoSeatsTogether,
+		},
+		{
+			name:    "price tier preference",
+			seats:   concat(rowSeats("stalls", "A", "premium", "....", 1), rowSeats("stalls", "B", "standard", "....", 5)),
+			req:     Request{Quantity: 2, PriceTiers: []string{"standard", "premium"}},
+			wantIDs: []int{5, 6},
+		},
+		{
+			name:    "booked seats split runs",
+			seats:   rowSeats("stalls", "A", "", "..x..", 1),
+			req:     Request{Quantity: 3},
+			wantErr: ErrNoSeatsTogether,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := BestAvailable(tt.seats, tt.req)
+			if err != tt.wantErr {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			var ids []int
+			for _, s := range got {
+				ids = append(ids, s.ID)
+			}
+			if !reflect.DeepEqual(ids, tt.wantIDs) {
+				t.Errorf("Expected seats %v, got %v", tt.wantIDs, ids)
+			}
+		})
+	}
+}
+
+func TestMapSpecSeats(t *testing.T) {
+	spec := MapSpec{Name: "Hall", Sections: []SectionSpec{{
+		Name:      "stalls",
+		PriceTier: "standard",
+		Rows: []RowSpec{
+			{Label: "A", Count: 2},
+			{Label: "B", PriceTier: "premium", Seats: []SeatSpec{{Number: 4, Accessible: true}, {Number: 5, PriceTier: "vip"}}},
+		},
+	}}}
+	seats, err := spec.Seats()
+	if err != nil {
+		t.Fatalf("Seats failed: %v", err)
+	}
+	want := []Seat{
+		{Section: "stalls", Row: "A", Number: 1, Position: 0, PriceTier: "standard"},
+		{Section: "stalls", Row: "A", Number: 2, Position: 1, PriceTier: "standard"},
+		{Section: "stalls", Row: "B", Number: 4, Position: 0, PriceTier: "premium", Accessible: true},
+		{Section: "stalls", Row: "B", Number: 5, Position: 1, PriceTier: "vip"},
+	}
+	if !reflect.DeepEqual(seats, want) {
+		t.Errorf("Expected %+v, got %+v", want, seats)
+	}
+
+	spec.Sections[0].Rows[1].Seats[1].Number = 4
+	if _, err := spec.Seats(); err == nil {
+		t.Error("Expected error for duplicate seat number, got nil")
+	}
+}
+
+func TestMapSpecSeatsRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		spec MapSpec
+	}{
+		{"no seats", MapSpec{Name: "Hall"}},
+		{"duplicate section", MapSpec{Name: "Hall", Sections: []SectionSpec{
+			{Name: "stalls", Rows: []RowSpec{{Label: "A", Count: 1}}},
+			{Name: "stalls", Rows: []RowSpec{{Label: "B", Count: 1}}},
+		}}},
+		{"duplicate row", MapSpec{Name: "Hall", Sections: []SectionSpec{
+			{Name: "stalls", Rows: []RowSpec{{Label: "A", Count: 1}, {Label: "A", Count: 2}}},
+		}}},
+		{"row without seats or count", MapSpec{Name: "Hall", Sections: []SectionSpec{
+			{Name: "stalls", Rows: []RowSpec{{Label: "A"}}},
+		}}},
+	}
+	for _, tt := range tests {
+		if _, err := tt.spec.Seats(); !errors.Is(err, ErrInvalidMap) {
+			t.Errorf("%s: expected ErrInvalidMap, got %v", tt.name, err)
+		}
+	}
+}
@@ -0,0 +1,418 @@
+package series
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"booking-app/internal/bookings"
+	"booking-app/internal/database"
+	"booking-app/internal/events"
+	"booking-app/internal/middleware"
+	"booking-app/internal/recurrence"
+	"booking-app/internal/refunds"
+	"booking-app/internal/resources"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrSeriesNotFound   = errors.New("series not found")
+	ErrSeriesExists     = errors.New("event series already exists")
+	ErrForbidden        = errors.New("not allowed to use this series")
+	ErrNoOccurrence     = errors.New("series has no occurrence at this time")
+	ErrOccurrenceBooked = errors.New("occurrence has active bookings or holds")
+	ErrNameTaken        = errors.New("occurrence name is already in use")
+	ErrEndless          = errors.New("a recurring booking needs COUNT or UNTIL")
+	ErrTooManyBookings  = fmt.Errorf("a recurring booking can make at most %d bookings", MaxBookings)
+)
+
+// DBStore manages recurring events and bookings in PostgreSQL
+type DBStore struct {
+	db        *sqlx.DB
+	bookings  *bookings.DBStore
+	resources *resources.DBStore
+	refunds   *refunds.DBStore
+}
+
+func NewDBStore(db *sqlx.DB, bookingStore *bookings.DBStore, resourceStore *resources.DBStore, refundStore *refunds.DBStore) *DBStore {
+	return &DBStore{db: db, bookings: bookingStore, resources: resourceStore, refunds: refundStore}
+}
+
+// CreateEventSeries creates a recurring event and its occurrences up to
+// Horizon from now
+func (s *DBStore) CreateEventSeries(ctx context.Context, es EventSeries) (EventSeries, error) {
+	if _, err := set(es.RRule, es.StartsAt, es.TimeZone, nil); err != nil {
+		return EventSeries{}, err
+	}
+	err := database.WithTx(ctx, s.db, func(tx *sqlx.Tx) error {
+		err := tx.GetContext(ctx, &es, `INSERT INTO event_series (name, organizer_id, rrule, starts_at, time_zone, capacity)
+              VALUES ($1, $2, $3, $4, $5, $6) RETURNING *`, es.Name, es.OrganizerID, es.RRule, es.StartsAt, es.TimeZone, es.Capacity)
+		if err != nil {
+			return err
+		}
+		return materialize(ctx, tx, &es, time.Now().Add(Horizon))
+	})
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return EventSeries{}, ErrSeriesExists
+	}
+	if err != nil {
+		return EventSeries{}, err
+	}
+	es.Exceptions = []time.Time{}
+	return es, nil
+}
+
+func (s *DBStore) GetEventSeries(ctx context.Context, id int) (EventSeries, error) {
+	var es EventSeries
+	err := s.db.GetContext(ctx, &es, "SELECT * FROM event_series WHERE id = $1", id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return EventSeries{}, ErrSeriesNotFound
+	}
+	if err != nil {
+		return EventSeries{}, err
+	}
+	es.Exceptions, err = exceptions(ctx, s.db, id)
+	return es, err
+}
+
+// ListOccurrences returns the events created for a series, in order
+func (s *DBStore) ListOccurrences(ctx context.Context, id int) ([]events.Event, error) {
+	list := []events.Event{}
+	err := s.db.SelectContext(ctx, &list, "SELECT * FROM events WHERE series_id = $1 ORDER BY occurrence_at", id)
+	for i := range list {
+		list[i].Localize()
+	}
+	return list, err
+}
+
+// AddException removes the occurrence the rule schedules at the given time.
+// Its event is deleted, which is refused while it has active bookings or
+// holds. Other occurrences are changed like any event, by updating their
+// settings.
+func (s *DBStore) AddException(ctx context.Context, id int, at time.Time) error {
+	return database.WithTx(ctx, s.db, func(tx *sqlx.Tx) error {
+		es, err := lockEventSeries(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		rs, err := set(es.RRule, es.StartsAt, es.TimeZone, nil)
+		if err != nil {
+			return err
+		}
+		if len(rs.Between(at, at.Add(time.Microsecond))) == 0 {
+			return ErrNoOccurrence
+		}
+		var booked bool
+		err = tx.GetContext(ctx, &booked, `SELECT EXISTS (
+              SELECT 1 FROM events e WHERE e.series_id = $1 AND e.occurrence_at = $2 AND (
+                  EXISTS (SELECT 1 FROM bookings b WHERE b.event = e.name AND b.is_active) OR
+                  EXISTS (SELECT 1 FROM holds h WHERE h.event = e.name AND h.status = 'active' AND h.expires_at > now())))`, id, at)
+		if err != nil {
+			return err
+		}
+		if booked {
+			return ErrOccurrenceBooked
+		}
+		_, err = tx.ExecContext(ctx, `INSERT INTO event_series_exceptions (series_id, occurrence_at) VALUES ($1, $2)
+              ON CONFLICT DO NOTHING`, id, at)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, "DELETE FROM events WHERE series_id = $1 AND occurrence_at = $2", id, at)
+		return err
+	})
+}
+
+// Extend creates the occurrences of every series up to Horizon from now.
+// A series that cannot be extended is logged and skipped so it does not
+// hold back the others.
+func (s *DBStore) Extend(ctx context.Context) error {
+	var ids []int
+	if err := s.db.SelectContext(ctx, &ids, "SELECT id FROM event_series ORDER BY id"); err != nil {
+		return err
+	}
+	until := time.Now().Add(Horizon)
+	for _, id := range ids {
+		err := database.WithTx(ctx, s.db, func(tx *sqlx.Tx) error {
+			es, err := lockEventSeries(ctx, tx, id)
+			if err != nil {
+				return err
+			}
+			return materialize(ctx, tx, &es, until)
+		})
+		if err != nil {
+			log.Printf("Failed to extend event series %d: %v", id, err)
+		}
+	}
+	return nil
+}
+
+// NewBookingSeries describes a recurring booking of an event series or of
+// a resource. StartsAt is the first possible occurrence; the rule is
+// expanded in the time zone of the series or resource. Answers respond to
+// the attendee questions of each occurrence of an event series.
+type NewBookingSeries struct {
+	UserName      string
+	RRule         string
+	StartsAt      time.Time
+	EventSeriesID *int
+	ResourceID    *int
+	Slots         int
+	Answers       json.RawMessage
+}
+
+// CreateBookingSeries books every occurrence of a recurring booking in one
+// transaction: if any occurrence cannot be booked, none are.
+func (s *DBStore) CreateBookingSeries(ctx context.Context, nbs NewBookingSeries) (BookingSeries, []bookings.Booking, error) {
+	if (nbs.EventSeriesID == nil) == (nbs.ResourceID == nil) {
+		return BookingSeries{}, nil, errors.New("exactly one of an event series and a resource is required")
+	}
+	if nbs.Slots <= 0 {
+		nbs.Slots = 1
+	}
+	bs := BookingSeries{UserName: nbs.UserName, RRule: nbs.RRule, StartsAt: nbs.StartsAt,
+		EventSeriesID: nbs.EventSeriesID, ResourceID: nbs.ResourceID, Slots: nbs.Slots}
+	if userID, ok := middleware.UserIDFromContext(ctx); ok {
+		bs.UserID = &userID
+	}
+	var resource resources.Resource
+	if nbs.ResourceID != nil {
+		var err error
+		if resource, err = s.resources.Get(ctx, *nbs.ResourceID); err != nil {
+			return BookingSeries{}, nil, err
+		}
+		bs.TimeZone = resource.TimeZone
+	}
+
+	var created []bookings.Booking
+	err := database.WithTx(ctx, s.db, func(tx *sqlx.Tx) error {
+		var es EventSeries
+		if nbs.EventSeriesID != nil {
+			var err error
+			if es, err = lockEventSeries(ctx, tx, *nbs.EventSeriesID); err != nil {
+				return err
+			}
+			bs.TimeZone = es.TimeZone
+		}
+		rs, err := set(bs.RRule, bs.StartsAt, bs.TimeZone, nil)
+		if err != nil {
+			return err
+		}
+		if !rs.Rule.Finite() {
+			return ErrEndless
+		}
+		occurrences := rs.All()
+		if len(occurrences) > MaxBookings {
+			return ErrTooManyBookings
+		}
+		if len(occurrences) == 0 {
+			return ErrNoOccurrence
+		}
+		if nbs.EventSeriesID != nil {
+			last := occurrences[len(occurrences)-1]
+			if err := materialize(ctx, tx, &es, last.Add(time.Microsecond)); err != nil {
+				return err
+			}
+		}
+		err = tx.GetContext(ctx, &bs, `INSERT INTO booking_series (user_id, user_name, rrule, starts_at, time_zone,
+              event_series_id, resource_id, slots) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING *`,
+			bs.UserID, bs.UserName, bs.RRule, bs.StartsAt, bs.TimeZone, bs.EventSeriesID, bs.ResourceID, bs.Slots)
+		if err != nil {
+			return err
+		}
+		for _, at := range occurrences {
+			nb := bookings.NewBooking{UserName: bs.UserName, SeriesID: &bs.ID, Answers: nbs.Answers}
+			if nbs.EventSeriesID != nil {
+				err = tx.GetContext(ctx, &nb.Event, "SELECT name FROM events WHERE series_id = $1 AND occurrence_at = $2", es.ID, at)
+				if errors.Is(err, sql.ErrNoRows) {
+					err = ErrNoOccurrence
+				}
+			} else {
+				var slot bookings.Slot
+				resource, slot, err = s.resources.CheckSlot(ctx, resource.ID, at, bs.Slots)
+				nb.Event, nb.Slot = resource.Name, &slot
+			}
+			if err != nil {
+				return fmt.Errorf("%s: %w", at.Format(time.RFC3339), err)
+			}
+			b, err := s.bookings.CreateBookingTx(ctx, tx, nb)
+			if err != nil {
+				return fmt.Errorf("%s: %w", at.Format(time.RFC3339), err)
+			}
+			created = append(created, b)
+		}
+		return nil
+	})
+	if err != nil {
+		return BookingSeries{}, nil, err
+	}
+	return bs, created, nil
+}
+
+// GetBookingSeries returns a recurring booking the caller owns with its
+// bookings, including cancelled ones
+func (s *DBStore) GetBookingSeries(ctx context.Context, id int) (BookingSeries, []bookings.Booking, error) {
+	var bs BookingSeries
+	err := s.db.GetContext(ctx, &bs, "SELECT * FROM booking_series WHERE id = $1", id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return BookingSeries{}, nil, ErrSeriesNotFound
+	}
+	if err != nil {
+		return BookingSeries{}, nil, err
+	}
+	if !canUse(ctx, bs) {
+		return BookingSeries{}, nil, ErrForbidden
+	}
+	list := []bookings.Booking{}
+	err = s.db.SelectContext(ctx, &list, "SELECT * FROM bookings WHERE series_id = $1 ORDER BY id", id)
+	return bs, list, err
+}
+
+// CancelBookingSeries cancels the active bookings of a recurring booking
+// that start at or after from, refunding each under its event's policy.
+// Cancelling from now on also ends the series. Single occurrences are
+// cancelled like any other booking.
+func (s *DBStore) CancelBookingSeries(ctx context.Context, id int, from time.Time) ([]bookings.Booking, error) {
+	cancelled := []bookings.Booking{}
+	err := database.WithTx(ctx, s.db, func(tx *sqlx.Tx) error {
+		var bs BookingSeries
+		err := tx.GetContext(ctx, &bs, "SELECT * FROM booking_series WHERE id = $1 FOR UPDATE", id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrSeriesNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if !canUse(ctx, bs) {
+			return ErrForbidden
+		}
+		var ids []int
+		err = tx.SelectContext(ctx, &ids, `SELECT b.id FROM bookings b LEFT JOIN events e ON e.name = b.event
+              WHERE b.series_id = $1 AND b.is_active AND coalesce(b.starts_at, e.starts_at, e.occurrence_at) >= $2
+              ORDER BY b.id`, id, from)
+		if err != nil {
+			return err
+		}
+		for _, bookingID := range ids {
+			c, err := s.refunds.CancelTx(ctx, tx, bookingID, nil)
+			if err != nil {
+				return fmt.Errorf("booking %d: %w", bookingID, err)
+			}
+			cancelled = append(cancelled, c.Booking)
+		}
+		if from.After(time.Now()) {
+			return nil
+		}
+		_, err = tx.ExecContext(ctx, "UPDATE booking_series SET status = 'cancelled', updated_at = now() WHERE id = $1", id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return cancelled, nil
+}
+
+// materialize creates the occurrences of a series scheduled before until
+// that have not been created yet. Existing occurrences are left alone, so
+// per-occurrence changes survive. An occurrence whose name is already used
+// by another event, a resource, or bookings or holds made under it fails
+// the whole call rather than adopting them.
+func materialize(ctx context.Context, tx *sqlx.Tx, es *EventSeries, until time.Time) error {
+	from := es.StartsAt
+	if es.MaterializedUntil != nil {
+		from = *es.MaterializedUntil
+	}
+	if !until.After(from) {
+		return nil
+	}
+	skip, err := exceptions(ctx, tx, es.ID)
+	if err != nil {
+		return err
+	}
+	rs, err := set(es.RRule, es.StartsAt, es.TimeZone, skip)
+	if err != nil {
+		return err
+	}
+	occurrences := rs.Between(from, until)
+	if len(occurrences) == recurrence.MaxOccurrences {
+		until = occurrences[len(occurrences)-1].Add(time.Microsecond)
+	}
+	for _, at := range occurrences {
+		var exists bool
+		err := tx.GetContext(ctx, &exists, "SELECT EXISTS (SELECT 1 FROM events WHERE series_id = $1 AND occurrence_at = $2)", es.ID, at)
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+		name := occurrenceName(es.Name, at)
+		if err := claimName(ctx, tx, name); err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `INSERT INTO events (name, organizer_id, capacity, starts_at, time_zone, series_id, occurrence_at)
+              VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+			name, es.OrganizerID, es.Capacity, at, es.TimeZone, es.ID, at)
+		if err != nil {
+			return err
+		}
+	}
+	es.MaterializedUntil = &until
+	_, err = tx.ExecContext(ctx, "UPDATE event_series SET materialized_until = $1, updated_at = now() WHERE id = $2", until, es.ID)
+	return err
+}
+
+// claimName reserves the name of a new occurrence. Bookings and holds are
+// made by event name, so any left under it would otherwise come under the
+// series' organizer.
+func claimName(ctx context.Context, tx *sqlx.Tx, name string) error {
+	err := events.ClaimNameTx(ctx, tx, name)
+	if errors.Is(err, events.ErrEventExists) {
+		return fmt.Errorf("%s: %w", name, ErrNameTaken)
+	}
+	if err != nil {
+		return err
+	}
+	var taken bool
+	err = tx.GetContext(ctx, &taken, `SELECT EXISTS (SELECT 1 FROM bookings WHERE event = $1)
+              OR EXISTS (SELECT 1 FROM holds WHERE event = $1)`, name)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return fmt.Errorf("%s: %w", name, ErrNameTaken)
+	}
+	return nil
+}
+
+func lockEventSeries(ctx context.Context, tx *sqlx.Tx, id int) (EventSeries, error) {
+	var es EventSeries
+	err := tx.GetContext(ctx, &es, "SELECT * FROM event_series WHERE id = $1 FOR UPDATE", id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return EventSeries{}, ErrSeriesNotFound
+	}
+	return es, err
+}
+
+func exceptions(ctx context.Context, q sqlx.QueryerContext, id int) ([]time.Time, error) {
+	list := []time.Time{}
+	err := sqlx.SelectContext(ctx, q, &list, "SELECT occurrence_at FROM event_series_exceptions WHERE series_id = $1 ORDER BY occurrence_at", id)
+	return list, err
+}
+
+// canUse reports whether the caller may see or cancel a recurring booking:
+// admins may use any, everyone else only their own
+func canUse(ctx context.Context, bs BookingSeries) bool {
+	if middleware.IsAdmin(ctx) {
+		return true
+	}
+	userID, ok := middleware.UserIDFromContext(ctx)
+	return ok && bs.UserID != nil && userID == *bs.UserID
+}
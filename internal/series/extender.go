@@ -0,0 +1,24 @@
+package series
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// RunExtender keeps occurrences of endless series created up to Horizon
+// ahead, checking every interval until ctx is cancelled
+func (s *DBStore) RunExtender(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Extend(ctx); err != nil {
+				log.Printf("Failed to list event series: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,248 @@
+package series
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"booking-app/internal/bookings"
+	"booking-app/internal/events"
+	"booking-app/internal/middleware"
+	"booking-app/internal/recurrence"
+	"booking-app/internal/refunds"
+	"booking-app/internal/resources"
+	"booking-app/internal/rules"
+	"booking-app/internal/waitingroom"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+)
+
+var validate = validator.New()
+
+type Handler struct {
+	store *DBStore
+}
+
+func NewHandler(store *DBStore) *Handler {
+	return &Handler{store: store}
+}
+
+// CreateEventSeriesHandler creates a recurring event organized by the
+// authenticated user. Only those who may create events, admins and
+// organizers, may create a series.
+func (h *Handler) CreateEventSeriesHandler(w http.ResponseWriter, r *http.Request) {
+	if !events.CanCreate(r.Context()) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	var input struct {
+		Name     string    `json:"name" validate:"required,max=200"`
+		RRule    string    `json:"rrule" validate:"required,max=500"`
+		StartsAt time.Time `json:"starts_at" validate:"required"`
+		TimeZone string    `json:"time_zone" validate:"required,timezone"`
+		Capacity *int      `json:"capacity" validate:"omitempty,min=0"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := validate.Struct(&input); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	es := EventSeries{Name: input.Name, RRule: input.RRule, StartsAt: input.StartsAt, TimeZone: input.TimeZone, Capacity: input.Capacity}
+	if userID, ok := middleware.UserIDFromContext(r.Context()); ok {
+		es.OrganizerID = &userID
+	}
+	es, err := h.store.CreateEventSeries(r.Context(), es)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, es)
+}
+
+func (h *Handler) GetEventSeriesHandler(w http.ResponseWriter, r *http.Request) {
+	es, ok := h.loadEventSeries(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, es)
+}
+
+func (h *Handler) ListOccurrencesHandler(w http.ResponseWriter, r *http.Request) {
+	es, ok := h.loadEventSeries(w, r)
+	if !ok {
+		return
+	}
+	list, err := h.store.ListOccurrences(r.Context(), es.ID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// AddExceptionHandler removes one occurrence from a series the caller manages
+func (h *Handler) AddExceptionHandler(w http.ResponseWriter, r *http.Request) {
+	es, ok := h.loadEventSeries(w, r)
+	if !ok {
+		return
+	}
+	if !events.CanManage(r.Context(), events.Event{OrganizerID: es.OrganizerID}) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	var input struct {
+		OccurrenceAt time.Time `json:"occurrence_at" validate:"required"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := validate.Struct(&input); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := h.store.AddException(r.Context(), es.ID, input.OccurrenceAt); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// CreateBookingSeriesHandler books every occurrence of a recurring booking,
+// or none if any of them is unavailable
+func (h *Handler) CreateBookingSeriesHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		UserName      string    `json:"user_name" validate:"required"`
+		RRule         string    `json:"rrule" validate:"required,max=500"`
+		StartsAt      time.Time `json:"starts_at" validate:"required"`
+		EventSeriesID *int      `json:"event_series_id"`
+		ResourceID    *int      `json:"resource_id"`
+		Slots         int       `json:"slots" validate:"omitempty,min=1,max=96"`
+		// Answers respond to the attendee questions of every occurrence
+		Answers json.RawMessage `json:"answers"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := validate.Struct(&input); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	bs, created, err := h.store.CreateBookingSeries(r.Context(), NewBookingSeries{
+		UserName:      input.UserName,
+		RRule:         input.RRule,
+		StartsAt:      input.StartsAt,
+		EventSeriesID: input.EventSeriesID,
+		ResourceID:    input.ResourceID,
+		Slots:         input.Slots,
+		Answers:       input.Answers,
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, bookingSeriesResponse{bs, created})
+}
+
+func (h *Handler) GetBookingSeriesHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	bs, list, err := h.store.GetBookingSeries(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, bookingSeriesResponse{bs, list})
+}
+
+// CancelBookingSeriesHandler cancels the bookings of a recurring booking
+// from a given time, by default now, and returns the cancelled bookings
+func (h *Handler) CancelBookingSeriesHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	var input struct {
+		From *time.Time `json:"from"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+	from := time.Now()
+	if input.From != nil {
+		from = *input.From
+	}
+	cancelled, err := h.store.CancelBookingSeries(r.Context(), id, from)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, cancelled)
+}
+
+type bookingSeriesResponse struct {
+	BookingSeries
+	Bookings []bookings.Booking `json:"bookings"`
+}
+
+func (h *Handler) loadEventSeries(w http.ResponseWriter, r *http.Request) (EventSeries, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return EventSeries{}, false
+	}
+	es, err := h.store.GetEventSeries(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return EventSeries{}, false
+	}
+	return es, true
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrSeriesNotFound), errors.Is(err, resources.ErrResourceNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrForbidden), errors.Is(err, waitingroom.ErrNotAdmitted):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, ErrSeriesExists), errors.Is(err, ErrOccurrenceBooked), errors.Is(err, ErrNameTaken),
+		errors.Is(err, refunds.ErrNotCancellable), errors.Is(err, refunds.ErrPaymentInProgress),
+		errors.Is(err, bookings.ErrPaidBooking), errors.Is(err, events.ErrEventFull),
+		errors.Is(err, events.ErrSoldOut), errors.Is(err, events.ErrNotOnSale), errors.Is(err, bookings.ErrSlotTaken),
+		errors.Is(err, rules.ErrNotBookable),
+		errors.Is(err, bookings.ErrLimitReached), errors.Is(err, bookings.ErrOverlap):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, bookings.ErrInvalidAnswers):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	case errors.Is(err, recurrence.ErrInvalidRule), errors.Is(err, ErrNoOccurrence), errors.Is(err, ErrEndless),
+		errors.Is(err, ErrTooManyBookings), errors.Is(err, resources.ErrInvalidSlot), errors.Is(err, resources.ErrInPast),
+		errors.Is(err, events.ErrTicketTypeRequired):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		log.Printf("Series error: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,37 @@
+package series
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"booking-app/internal/middleware"
+)
+
+func TestCreateEventSeriesHandler(t *testing.T) {
+	h := NewHandler(NewDBStore(nil, nil, nil, nil))
+	valid := `{"name": "Yoga", "rrule": "FREQ=WEEKLY;COUNT=4", "starts_at": "2026-01-05T09:00:00Z", "time_zone": "Europe/Berlin"}`
+	tests := []struct {
+		name string
+		role string
+		body string
+		want int
+	}{
+		{"customer", "user", valid, http.StatusForbidden},
+		{"anonymous", "", valid, http.StatusForbidden},
+		{"malformed", middleware.RoleOrganizer, `{"name":`, http.StatusBadRequest},
+		{"no rrule", middleware.RoleOrganizer, `{"name": "Yoga", "starts_at": "2026-01-05T09:00:00Z", "time_zone": "Europe/Berlin"}`, http.StatusBadRequest},
+		{"unknown time zone", middleware.RoleAdmin, `{"name": "Yoga", "rrule": "FREQ=WEEKLY;COUNT=4", "starts_at": "2026-01-05T09:00:00Z", "time_zone": "Mars/Olympus"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodPost, "/event-series", strings.NewReader(tt.body))
+		r = r.WithContext(context.WithValue(r.Context(), middleware.RoleKey, tt.role))
+		w := httptest.NewRecorder()
+		h.CreateEventSeriesHandler(w, r)
+		if w.Code != tt.want {
+			t.Errorf("%s: expected status %d, got %d: %s", tt.name, tt.want, w.Code, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,77 @@
+// Package series manages recurring events, whose occurrences are created
+// as ordinary events ahead of time, and recurring bookings, whose bookings
+// are created together or not at all.
+package series
+
+import (
+	"fmt"
+	"time"
+
+	"booking-app/internal/recurrence"
+)
+
+// Horizon is how far ahead occurrences of endless series are created
+const Horizon = 180 * 24 * time.Hour
+
+// MaxBookings caps the bookings a recurring booking can make
+const MaxBookings = 104
+
+// Booking series statuses
+const (
+	StatusActive    = "active"
+	StatusCancelled = "cancelled"
+)
+
+// EventSeries is a recurring event. StartsAt is the first possible
+// occurrence; RRule is expanded in TimeZone so occurrences keep their local
+// time across daylight saving changes.
+type EventSeries struct {
+	ID                int         `json:"id" db:"id"`
+	Name              string      `json:"name" db:"name"`
+	OrganizerID       *int        `json:"organizer_id,omitempty" db:"organizer_id"`
+	RRule             string      `json:"rrule" db:"rrule"`
+	StartsAt          time.Time   `json:"starts_at" db:"starts_at"`
+	TimeZone          string      `json:"time_zone" db:"time_zone"`
+	Capacity          *int        `json:"capacity" db:"capacity"`
+	MaterializedUntil *time.Time  `json:"materialized_until,omitempty" db:"materialized_until"`
+	CreatedAt         time.Time   `json:"created_at" db:"created_at"`
+	UpdatedAt         time.Time   `json:"updated_at" db:"updated_at"`
+	Exceptions        []time.Time `json:"exceptions" db:"-"`
+}
+
+// BookingSeries is a recurring booking of the occurrences of an event
+// series or of a resource's slots, such as every Monday for ten weeks.
+type BookingSeries struct {
+	ID            int       `json:"id" db:"id"`
+	UserID        *int      `json:"user_id,omitempty" db:"user_id"`
+	UserName      string    `json:"user_name" db:"user_name"`
+	RRule         string    `json:"rrule" db:"rrule"`
+	StartsAt      time.Time `json:"starts_at" db:"starts_at"`
+	TimeZone      string    `json:"time_zone" db:"time_zone"`
+	EventSeriesID *int      `json:"event_series_id,omitempty" db:"event_series_id"`
+	ResourceID    *int      `json:"resource_id,omitempty" db:"resource_id"`
+	Slots         int       `json:"slots" db:"slots"`
+	Status        string    `json:"status" db:"status"`
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// set returns the recurrence of a series starting at start in the named
+// time zone, minus exceptions
+func set(rrule string, start time.Time, timeZone string, exceptions []time.Time) (recurrence.Set, error) {
+	rule, err := recurrence.Parse(rrule)
+	if err != nil {
+		return recurrence.Set{}, err
+	}
+	loc, err := time.LoadLocation(timeZone)
+	if err != nil {
+		return recurrence.Set{}, fmt.Errorf("%w: unknown time zone %s", recurrence.ErrInvalidRule, timeZone)
+	}
+	return recurrence.Set{Start: start.In(loc), Rule: rule, Exclude: exceptions}, nil
+}
+
+// occurrenceName names the event of an occurrence after its series and
+// local start
+func occurrenceName(series string, at time.Time) string {
+	return series + " " + at.Format("2006-01-02 15:04")
+}
@@ -0,0 +1,77 @@
+package series
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"booking-app/internal/middleware"
+	"booking-app/internal/recurrence"
+)
+
+func TestSetKeepsLocalTime(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("time zone data not available: %v", err)
+	}
+	// 18:00 in Berlin on the Monday before clocks go back
+	start := time.Date(2025, 10, 20, 16, 0, 0, 0, time.UTC)
+	s, err := set("FREQ=WEEKLY;COUNT=2", start, "Europe/Berlin", nil)
+	if err != nil {
+		t.Fatalf("set: %v", err)
+	}
+	got := s.All()
+	if len(got) != 2 {
+		t.Fatalf("got %d occurrences, want 2", len(got))
+	}
+	for _, at := range got {
+		if local := at.In(loc); local.Hour() != 18 {
+			t.Errorf("occurrence %v is at %02d:00 local, want 18:00", at, local.Hour())
+		}
+	}
+	if name := occurrenceName("Yoga", got[1]); name != "Yoga 2025-10-27 18:00" {
+		t.Errorf("occurrenceName = %q", name)
+	}
+}
+
+func TestSetRejectsBadInput(t *testing.T) {
+	start := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name, rrule, tz string
+	}{
+		{"bad rule", "FREQ=SOMETIMES", "UTC"},
+		{"bad time zone", "FREQ=DAILY", "Mars/Olympus"},
+	}
+	for _, tt := range tests {
+		if _, err := set(tt.rrule, start, tt.tz, nil); !errors.Is(err, recurrence.ErrInvalidRule) {
+			t.Errorf("%s: err = %v, want ErrInvalidRule", tt.name, err)
+		}
+	}
+}
+
+func TestCanUse(t *testing.T) {
+	owner := 7
+	user := func(id int, role string) context.Context {
+		ctx := context.WithValue(context.Background(), middleware.UserIDKey, id)
+		return context.WithValue(ctx, middleware.RoleKey, role)
+	}
+	tests := []struct {
+		name string
+		ctx  context.Context
+		bs   BookingSeries
+		want bool
+	}{
+		{"owner", user(owner, "user"), BookingSeries{UserID: &owner}, true},
+		{"other user", user(8, "user"), BookingSeries{UserID: &owner}, false},
+		{"admin", user(8, middleware.RoleAdmin), BookingSeries{UserID: &owner}, true},
+		{"no user", context.Background(), BookingSeries{UserID: &owner}, false},
+		{"ownerless series", user(8, "user"), BookingSeries{}, false},
+		{"ownerless series as admin", user(8, middleware.RoleAdmin), BookingSeries{}, true},
+	}
+	for _, tt := range tests {
+		if got := canUse(tt.ctx, tt.bs); got != tt.want {
+			t.Errorf("%s: canUse = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
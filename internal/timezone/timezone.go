@@ -0,0 +1,27 @@
+// Package timezone shows instants, which are stored in UTC, at the local
+// time of the venue or resource they belong to.
+package timezone
+
+import "time"
+
+// Default is the time zone of events that do not name their venue's
+const Default = "UTC"
+
+// Location loads the named IANA time zone. Names that cannot be loaded,
+// which validation keeps out of the database, fall back to UTC.
+func Location(name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// In returns t at the local time of the named time zone, or nil if t is nil
+func In(t *time.Time, name string) *time.Time {
+	if t == nil {
+		return nil
+	}
+	local := t.In(Location(name))
+	return &local
+}
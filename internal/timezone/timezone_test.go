@@ -0,0 +1,23 @@
+package timezone
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIn(t *testing.T) {
+	if In(nil, "Europe/Berlin") != nil {
+		t.Error("expected nil for a nil time")
+	}
+	at := time.Date(2026, 7, 1, 16, 0, 0, 0, time.UTC)
+	local := In(&at, "America/New_York")
+	if local == nil || !local.Equal(at) {
+		t.Fatalf("In changed the instant: %v", local)
+	}
+	if _, err := time.LoadLocation("America/New_York"); err == nil && local.Format("15:04 -07:00") != "12:00 -04:00" {
+		t.Errorf("got %s, want 12:00 -04:00", local.Format("15:04 -07:00"))
+	}
+	if got := In(&at, "Nowhere/Special"); got.Location() != time.UTC {
+		t.Errorf("unknown zone should fall back to UTC, got %v", got.Location())
+	}
+}
@@ -26,11 +26,12 @@ func (s *DBStore) CreateUser(ctx context.Context, username, password string) (Us
 	if err != nil {
 		return User{}, err
 	}
+	now := time.Now().UTC()
 	u := User{
 		Username:     username,
 		PasswordHash: string(hash),
-		CreatedAt:    time.Now(),
-		UpdatedAt:    time.Now(),
+		CreatedAt:    now,
+		UpdatedAt:    now,
 	}
 	query := `INSERT INTO users (username, password_hash, created_at, updated_at) 
               VALUES (:username, :password_hash, :created_at, :updated_at) 
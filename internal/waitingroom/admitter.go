@@ -0,0 +1,25 @@
+package waitingroom
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// RunAdmitter admits queued customers every interval until ctx is
+// cancelled. Shorter intervals spread admissions more evenly over each
+// minute.
+func (s *DBStore) RunAdmitter(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.Admit(ctx, interval); err != nil {
+				log.Printf("Failed to admit from waiting rooms: %v", err)
+			}
+		}
+	}
+}
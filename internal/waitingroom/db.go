@@ -0,0 +1,222 @@
+package waitingroom
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"booking-app/internal/database"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	ErrNoRoom      = errors.New("event has no active waiting room")
+	ErrNotQueued   = errors.New("not in the queue for this event")
+	ErrNotAdmitted = errors.New("this event has a waiting room; join its queue to be admitted")
+)
+
+// DBStore manages waiting rooms and their queues in PostgreSQL
+type DBStore struct {
+	db     *sqlx.DB
+	tokens *Tokens
+}
+
+func NewDBStore(db *sqlx.DB, tokens *Tokens) *DBStore {
+	return &DBStore{db: db, tokens: tokens}
+}
+
+// SetRoom opens or reconfigures an event's waiting room
+func (s *DBStore) SetRoom(ctx context.Context, room Room) (Room, error) {
+	err := s.db.GetContext(ctx, &room, `INSERT INTO waiting_rooms (event_id, admit_per_minute, admission_minutes, active)
+              VALUES ($1, $2, $3, $4)
+              ON CONFLICT (event_id) DO UPDATE SET admit_per_minute = EXCLUDED.admit_per_minute,
+              admission_minutes = EXCLUDED.admission_minutes, active = EXCLUDED.active, updated_at = now()
+              RETURNING *`, room.EventID, room.AdmitPerMinute, room.AdmissionMinutes, room.Active)
+	return room, err
+}
+
+func (s *DBStore) GetRoom(ctx context.Context, eventID int) (Room, error) {
+	var room Room
+	err := s.db.GetContext(ctx, &room, "SELECT * FROM waiting_rooms WHERE event_id = $1", eventID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Room{}, ErrNoRoom
+	}
+	return room, err
+}
+
+// DeleteRoom closes an event's waiting room and drops its queue
+func (s *DBStore) DeleteRoom(ctx context.Context, eventID int) error {
+	res, err := s.db.ExecContext(ctx, "DELETE FROM waiting_rooms WHERE event_id = $1", eventID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNoRoom
+	}
+	return nil
+}
+
+// Join puts a user at the back of an event's queue. Joining again keeps
+// the user's place, unless their admission has expired or been used.
+func (s *DBStore) Join(ctx context.Context, eventID, userID int) error {
+	return database.WithTx(ctx, s.db, func(tx *sqlx.Tx) error {
+		var active bool
+		err := tx.GetContext(ctx, &active, "SELECT active FROM waiting_rooms WHERE event_id = $1", eventID)
+		if errors.Is(err, sql.ErrNoRows) || (err == nil && !active) {
+			return ErrNoRoom
+		}
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `DELETE FROM waiting_room_entries
+              WHERE event_id = $1 AND user_id = $2 AND (expires_at <= now() OR used_at IS NOT NULL)`, eventID, userID)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `INSERT INTO waiting_room_entries (event_id, user_id) VALUES ($1, $2)
+              ON CONFLICT (event_id, user_id) DO NOTHING`, eventID, userID)
+		return err
+	})
+}
+
+// Position tells a user where they are in an event's queue, with an
+// admission token for the event once they have been admitted
+func (s *DBStore) Position(ctx context.Context, eventID, userID int, event string) (Position, error) {
+	room, err := s.GetRoom(ctx, eventID)
+	if err != nil {
+		return Position{}, err
+	}
+	var e Entry
+	err = s.db.GetContext(ctx, &e, "SELECT * FROM waiting_room_entries WHERE event_id = $1 AND user_id = $2", eventID, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Position{}, ErrNotQueued
+	}
+	if err != nil {
+		return Position{}, err
+	}
+	if e.UsedAt != nil {
+		return Position{Status: StatusUsed, ExpiresAt: e.ExpiresAt}, nil
+	}
+	if e.ExpiresAt != nil {
+		if !e.ExpiresAt.After(time.Now()) {
+			return Position{Status: StatusExpired, ExpiresAt: e.ExpiresAt}, nil
+		}
+		token, err := s.tokens.Issue(userID, event, *e.ExpiresAt)
+		if err != nil {
+			return Position{}, err
+		}
+		return Position{Status: StatusAdmitted, Token: token, ExpiresAt: e.ExpiresAt}, nil
+	}
+	var ahead int
+	err = s.db.GetContext(ctx, &ahead, `SELECT count(*) FROM waiting_room_entries
+              WHERE event_id = $1 AND admitted_at IS NULL AND id < $2`, eventID, e.ID)
+	if err != nil {
+		return Position{}, err
+	}
+	return Position{
+		Status:               StatusWaiting,
+		Position:             ahead + 1,
+		EstimatedWaitSeconds: int(room.estimatedWait(ahead + 1).Seconds()),
+	}, nil
+}
+
+// Admit lets the next customers of every active waiting room through, as
+// many as each room's rate allows on a tick of interval. Rooms are locked
+// while admitting, so several API instances can run the admitter without
+// exceeding the rate; a room locked by another instance is skipped.
+func (s *DBStore) Admit(ctx context.Context, interval time.Duration) (int, error) {
+	var ids []int
+	if err := s.db.SelectContext(ctx, &ids, "SELECT event_id FROM waiting_rooms WHERE active"); err != nil {
+		return 0, err
+	}
+	admitted := 0
+	for _, id := range ids {
+		err := database.WithTx(ctx, s.db, func(tx *sqlx.Tx) error {
+			var room Room
+			err := tx.GetContext(ctx, &room, `SELECT * FROM waiting_rooms WHERE event_id = $1 AND active
+              FOR UPDATE SKIP LOCKED`, id)
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			var lastMinute int
+			err = tx.GetContext(ctx, &lastMinute, `SELECT count(*) FROM waiting_room_entries
+              WHERE event_id = $1 AND admitted_at > now() - interval '1 minute'`, id)
+			if err != nil {
+				return err
+			}
+			n := room.batch(interval, lastMinute)
+			if n == 0 {
+				return nil
+			}
+			res, err := tx.ExecContext(ctx, `UPDATE waiting_room_entries
+              SET admitted_at = now(), expires_at = now() + make_interval(mins => $2)
+              WHERE id IN (SELECT id FROM waiting_room_entries
+                  WHERE event_id = $1 AND admitted_at IS NULL ORDER BY id LIMIT $3)`, id, room.AdmissionMinutes, n)
+			if err != nil {
+				return err
+			}
+			count, err := res.RowsAffected()
+			admitted += int(count)
+			return err
+		})
+		if err != nil {
+			return admitted, err
+		}
+	}
+	return admitted, nil
+}
+
+// GatedEvents returns the names of the events whose waiting room is active
+func (s *DBStore) GatedEvents(ctx context.Context) ([]string, error) {
+	var names []string
+	err := s.db.SelectContext(ctx, &names, `SELECT e.name FROM waiting_rooms w
+              JOIN events e ON e.id = w.event_id WHERE w.active`)
+	return names, err
+}
+
+// UseAdmissionTx checks that a user has been admitted to an event's active
+// waiting room and uses the admission up, failing with ErrNotAdmitted
+// otherwise. Events without an active waiting room need no admission.
+//
+// An admission covers every booking made in the transaction that first
+// uses it, such as the seats of one reservation or the items of one
+// checkout; later transactions need a new admission.
+func UseAdmissionTx(ctx context.Context, tx *sqlx.Tx, event string, userID *int) error {
+	var eventID int
+	err := tx.GetContext(ctx, &eventID, `SELECT w.event_id FROM waiting_rooms w
+              JOIN events e ON e.id = w.event_id WHERE e.name = $1 AND w.active`, event)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if userID == nil {
+		return ErrNotAdmitted
+	}
+	// now() is the start of the transaction, so an admission used earlier
+	// in this transaction still matches
+	res, err := tx.ExecContext(ctx, `UPDATE waiting_room_entries SET used_at = now()
+              WHERE event_id = $1 AND user_id = $2 AND expires_at > now() AND (used_at IS NULL OR used_at = now())`,
+		eventID, *userID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotAdmitted
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package waitingroom
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"booking-app/internal/middleware"
+)
+
+// maxBody bounds how much of a request body the gate reads to find its event
+const maxBody = 1 << 20
+
+// Gate is middleware for booking routes. A POST whose JSON body names an
+// event with an active waiting room must carry an admission token for the
+// authenticated user and that event in the X-Admission-Token header.
+// Other requests pass straight through. It must run after Auth.
+//
+// The gate only turns away requests early; the stores enforce admission,
+// with UseAdmissionTx, on every route that books a gated event.
+//
+// The set of gated events is cached for refresh, so that a rush of
+// booking requests does not also mean a rush of lookups.
+type Gate struct {
+	tokens  *Tokens
+	load    func(ctx context.Context) ([]string, error)
+	refresh time.Duration
+
+	mu       sync.Mutex
+	gated    map[string]bool
+	loadedAt time.Time
+}
+
+func NewGate(store *DBStore, refresh time.Duration) *Gate {
+	return &Gate{tokens: store.tokens, load: store.GatedEvents, refresh: refresh}
+}
+
+func (g *Gate) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Body == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxBody))
+		if err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		var probe struct {
+			Event string `json:"event"`
+		}
+		if json.Unmarshal(body, &probe) != nil || probe.Event == "" || !g.isGated(r.Context(), probe.Event) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		userID, ok := middleware.UserIDFromContext(r.Context())
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if err := g.tokens.Verify(r.Header.Get(TokenHeader), userID, probe.Event); err != nil {
+			http.Error(w, "This event has a waiting room; join its queue to get an admission token", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isGated reports whether an event's waiting room is active. If the set
+// of gated events cannot be refreshed the last one known is kept.
+func (g *Gate) isGated(ctx context.Context, event string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.gated == nil || time.Since(g.loadedAt) >= g.refresh {
+		names, err := g.load(ctx)
+		if err != nil {
+			log.Printf("Failed to load waiting rooms: %v", err)
+		} else {
+			g.gated = make(map[string]bool, len(names))
+			for _, name := range names {
+				g.gated[name] = true
+			}
+		}
+		// Retry failed loads on the next refresh, not on every request
+		g.loadedAt = time.Now()
+	}
+	return g.gated[event]
+}
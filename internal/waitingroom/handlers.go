@@ -0,0 +1,160 @@
+package waitingroom
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"booking-app/internal/events"
+	"booking-app/internal/middleware"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+)
+
+var validate = validator.New()
+
+type Handler struct {
+	store  *DBStore
+	events *events.DBStore
+}
+
+func NewHandler(store *DBStore, eventStore *events.DBStore) *Handler {
+	return &Handler{store: store, events: eventStore}
+}
+
+// SetRoomHandler opens or reconfigures the waiting room of an event the
+// caller manages. Rooms are active unless the body says otherwise.
+func (h *Handler) SetRoomHandler(w http.ResponseWriter, r *http.Request) {
+	e, ok := h.loadEvent(w, r)
+	if !ok {
+		return
+	}
+	if !events.CanManage(r.Context(), e) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	room := Room{Active: true}
+	if err := json.NewDecoder(r.Body).Decode(&room); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := validate.Struct(&room); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	room.EventID = e.ID
+	room, err := h.store.SetRoom(r.Context(), room)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, room)
+}
+
+func (h *Handler) GetRoomHandler(w http.ResponseWriter, r *http.Request) {
+	e, ok := h.loadEvent(w, r)
+	if !ok {
+		return
+	}
+	room, err := h.store.GetRoom(r.Context(), e.ID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, room)
+}
+
+func (h *Handler) DeleteRoomHandler(w http.ResponseWriter, r *http.Request) {
+	e, ok := h.loadEvent(w, r)
+	if !ok {
+		return
+	}
+	if !events.CanManage(r.Context(), e) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	if err := h.store.DeleteRoom(r.Context(), e.ID); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// JoinHandler queues the authenticated user for an event and returns their
+// position
+func (h *Handler) JoinHandler(w http.ResponseWriter, r *http.Request) {
+	e, ok := h.loadEvent(w, r)
+	if !ok {
+		return
+	}
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if err := h.store.Join(r.Context(), e.ID, userID); err != nil {
+		writeError(w, err)
+		return
+	}
+	p, err := h.store.Position(r.Context(), e.ID, userID, e.Name)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, p)
+}
+
+// PositionHandler shows the authenticated user's place in an event's queue,
+// including their admission token once admitted
+func (h *Handler) PositionHandler(w http.ResponseWriter, r *http.Request) {
+	e, ok := h.loadEvent(w, r)
+	if !ok {
+		return
+	}
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	p, err := h.store.Position(r.Context(), e.ID, userID, e.Name)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, p)
+}
+
+func (h *Handler) loadEvent(w http.ResponseWriter, r *http.Request) (events.Event, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return events.Event{}, false
+	}
+	e, err := h.events.GetEvent(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return events.Event{}, false
+	}
+	return e, true
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, events.ErrEventNotFound), errors.Is(err, ErrNoRoom), errors.Is(err, ErrNotQueued):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		log.Printf("Waiting room error: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,61 @@
+package waitingroom
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// TokenHeader carries an admission token on booking requests
+const TokenHeader = "X-Admission-Token"
+
+// audience keeps admission tokens from being mistaken for other tokens
+const audience = "admission"
+
+var ErrInvalidToken = errors.New("invalid admission token")
+
+// Tokens issues and verifies admission tokens. They are signed with their
+// own secret so that they can never pass as login tokens.
+type Tokens struct {
+	secret []byte
+}
+
+func NewTokens(secret string) *Tokens {
+	return &Tokens{secret: []byte(secret)}
+}
+
+type admissionClaims struct {
+	Event string `json:"event"`
+	jwt.RegisteredClaims
+}
+
+// Issue signs a token admitting a user to book an event until expires
+func (t *Tokens) Issue(userID int, event string, expires time.Time) (string, error) {
+	claims := admissionClaims{
+		Event: event,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.Itoa(userID),
+			Audience:  jwt.ClaimStrings{audience},
+			ExpiresAt: jwt.NewNumericDate(expires),
+		},
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(t.secret)
+}
+
+// Verify checks that a token admits the user to book the event now
+func (t *Tokens) Verify(token string, userID int, event string) error {
+	var claims admissionClaims
+	_, err := jwt.ParseWithClaims(token, &claims, func(*jwt.Token) (interface{}, error) {
+		return t.secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithAudience(audience), jwt.WithExpirationRequired())
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if claims.Subject != strconv.Itoa(userID) || claims.Event != event {
+		return fmt.Errorf("%w: issued for another user or event", ErrInvalidToken)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+// Package waitingroom queues customers for high-demand events and admits
+// them to the booking flow at a controlled rate. Admitted customers get a
+// signed admission token, which the Gate middleware requires on booking
+// requests for events whose waiting room is active. The stores check the
+// admission again when they book such an event, whatever the route, and
+// use it up, so one admission is good for one booking transaction.
+package waitingroom
+
+import "time"
+
+// Queue entry statuses as shown to customers
+const (
+	StatusWaiting  = "waiting"
+	StatusAdmitted = "admitted"
+	StatusExpired  = "expired"
+	StatusUsed     = "used"
+)
+
+// Room is the waiting room of an event. AdmitPerMinute customers are let
+// through each minute, and each may book for AdmissionMinutes.
+type Room struct {
+	EventID          int       `json:"event_id" db:"event_id"`
+	AdmitPerMinute   int       `json:"admit_per_minute" db:"admit_per_minute" validate:"required,min=1,max=100000"`
+	AdmissionMinutes int       `json:"admission_minutes" db:"admission_minutes" validate:"required,min=1,max=120"`
+	Active           bool      `json:"active" db:"active"`
+	CreatedAt        time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// Entry is a customer's place in a waiting room
+type Entry struct {
+	ID         int64      `db:"id"`
+	EventID    int        `db:"event_id"`
+	UserID     int        `db:"user_id"`
+	JoinedAt   time.Time  `db:"joined_at"`
+	AdmittedAt *time.Time `db:"admitted_at"`
+	ExpiresAt  *time.Time `db:"expires_at"`
+	UsedAt     *time.Time `db:"used_at"`
+}
+
+// Position is what a customer in the queue sees. Position counts from 1
+// for the next customer to be admitted; Token is set once admitted.
+type Position struct {
+	Status               string     `json:"status"`
+	Position             int        `json:"position,omitempty"`
+	EstimatedWaitSeconds int        `json:"estimated_wait_seconds,omitempty"`
+	Token                string     `json:"token,omitempty"`
+	ExpiresAt            *time.Time `json:"expires_at,omitempty"`
+}
+
+// estimatedWait is how long the customer at position waits at the room's
+// rate, rounded up to whole minutes since customers are admitted per minute
+func (r Room) estimatedWait(position int) time.Duration {
+	minutes := (position + r.AdmitPerMinute - 1) / r.AdmitPerMinute
+	return time.Duration(minutes) * time.Minute
+}
+
+// batch is how many customers a room may admit on a tick of interval,
+// given how many it admitted in the last minute. Admissions are spread
+// over the minute rather than let in all at once.
+func (r Room) batch(interval time.Duration, lastMinute int) int {
+	perTick := (int64(r.AdmitPerMinute)*int64(interval) + int64(time.Minute) - 1) / int64(time.Minute)
+	n := r.AdmitPerMinute - lastMinute
+	if int64(n) > perTick {
+		n = int(perTick)
+	}
+	if n < 0 {
+		return 0
+	}
+	return n
+}
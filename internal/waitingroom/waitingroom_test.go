@@ -0,0 +1,156 @@
+package waitingroom
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"booking-app/internal/middleware"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestBatch(t *testing.T) {
+	tests := []struct {
+		name       string
+		rate       int
+		interval   time.Duration
+		lastMinute int
+		want       int
+	}{
+		{"spread over the minute", 120, time.Second, 0, 2},
+		{"rounds up slow rates", 10, time.Second, 0, 1},
+		{"whole minute", 120, time.Minute, 0, 120},
+		{"capped by the last minute", 120, time.Minute, 100, 20},
+		{"nothing left this minute", 60, time.Second, 60, 0},
+		{"over the rate after a change", 60, time.Second, 90, 0},
+	}
+	for _, tt := range tests {
+		if got := (Room{AdmitPerMinute: tt.rate}).batch(tt.interval, tt.lastMinute); got != tt.want {
+			t.Errorf("%s: batch = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestEstimatedWait(t *testing.T) {
+	r := Room{AdmitPerMinute: 100}
+	tests := []struct {
+		position int
+		want     time.Duration
+	}{
+		{1, time.Minute},
+		{100, time.Minute},
+		{101, 2 * time.Minute},
+		{1000, 10 * time.Minute},
+	}
+	for _, tt := range tests {
+		if got := r.estimatedWait(tt.position); got != tt.want {
+			t.Errorf("estimatedWait(%d) = %s, want %s", tt.position, got, tt.want)
+		}
+	}
+}
+
+func TestTokens(t *testing.T) {
+	tokens := NewTokens("admission-secret")
+	valid, err := tokens.Issue(7, "Concert", time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	if err := tokens.Verify(valid, 7, "Concert"); err != nil {
+		t.Errorf("Verify valid token: %v", err)
+	}
+	expired, _ := tokens.Issue(7, "Concert", time.Now().Add(-time.Minute))
+	other, _ := NewTokens("other-secret").Issue(7, "Concert", time.Now().Add(time.Minute))
+	// A login token signed with the same secret has no admission audience
+	login, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub": "7", "exp": time.Now().Add(time.Hour).Unix(),
+	}).SignedString([]byte("admission-secret"))
+
+	tests := []struct {
+		name   string
+		token  string
+		userID int
+		event  string
+	}{
+		{"another user", valid, 8, "Concert"},
+		{"another event", valid, 7, "Theatre"},
+		{"expired", expired, 7, "Concert"},
+		{"another secret", other, 7, "Concert"},
+		{"login token", login, 7, "Concert"},
+		{"missing", "", 7, "Concert"},
+	}
+	for _, tt := range tests {
+		if err := tokens.Verify(tt.token, tt.userID, tt.event); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("%s: err = %v, want ErrInvalidToken", tt.name, err)
+		}
+	}
+}
+
+func TestGate(t *testing.T) {
+	tokens := NewTokens("admission-secret")
+	g := &Gate{
+		tokens:  tokens,
+		load:    func(context.Context) ([]string, error) { return []string{"Concert"}, nil },
+		refresh: time.Minute,
+	}
+	admitted, _ := tokens.Issue(7, "Concert", time.Now().Add(time.Minute))
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The booking handler still sees the whole body
+		body, _ := io.ReadAll(r.Body)
+		if !strings.Contains(string(body), "user_name") {
+			t.Errorf("body not passed on: %q", body)
+		}
+		w.WriteHeader(http.StatusCreated)
+	})
+	handler := g.Middleware(next)
+
+	tests := []struct {
+		name   string
+		method string
+		body   string
+		token  string
+		want   int
+	}{
+		{"gated without token", http.MethodPost, `{"user_name":"a","event":"Concert"}`, "", http.StatusForbidden},
+		{"gated with token", http.MethodPost, `{"user_name":"a","event":"Concert"}`, admitted, http.StatusCreated},
+		{"ungated event", http.MethodPost, `{"user_name":"a","event":"Theatre"}`, "", http.StatusCreated},
+		{"not a post", http.MethodGet, `{"user_name":"a"}`, "", http.StatusCreated},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(tt.method, "/bookings", strings.NewReader(tt.body))
+		r = r.WithContext(context.WithValue(r.Context(), middleware.UserIDKey, 7))
+		if tt.token != "" {
+			r.Header.Set(TokenHeader, tt.token)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, w.Code, tt.want)
+		}
+	}
+}
+
+func TestGateKeepsLastSetOnError(t *testing.T) {
+	calls := 0
+	g := &Gate{
+		load: func(context.Context) ([]string, error) {
+			calls++
+			if calls > 1 {
+				return nil, errors.New("database down")
+			}
+			return []string{"Concert"}, nil
+		},
+		refresh: 0,
+	}
+	if !g.isGated(context.Background(), "Concert") {
+		t.Fatal("Concert not gated after first load")
+	}
+	if !g.isGated(context.Background(), "Concert") {
+		t.Error("Concert no longer gated after a failed refresh")
+	}
+}
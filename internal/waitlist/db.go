@@ -0,0 +1,261 @@
+package waitlist
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"booking-app/internal/bookings"
+	"booking-app/internal/database"
+	"booking-app/internal/events"
+	"booking-app/internal/holds"
+	"booking-app/internal/middleware"
+	"booking-app/internal/notify"
+	"booking-app/internal/waitingroom"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrEntryNotFound  = errors.New("waitlist entry not found")
+	ErrAlreadyWaiting = errors.New("already on the waitlist for this event")
+	ErrNotOffered     = errors.New("waitlist entry has no open offer")
+	ErrForbidden      = errors.New("not allowed to use this waitlist entry")
+)
+
+// selectEntries selects entries together with their current position
+const selectEntries = `SELECT w.*, CASE WHEN w.status = 'waiting' THEN (
+              SELECT count(*) FROM waitlist_entries o
+              WHERE o.event = w.event AND o.status = 'waiting' AND o.id <= w.id) END AS position
+              FROM waitlist_entries w`
+
+// DBStore manages waitlists in PostgreSQL
+type DBStore struct {
+	db       *sqlx.DB
+	events   *events.DBStore
+	holds    *holds.DBStore
+	notifier notify.Notifier
+}
+
+func NewDBStore(db *sqlx.DB, eventStore *events.DBStore, holdStore *holds.DBStore, notifier notify.Notifier) *DBStore {
+	return &DBStore{db: db, events: eventStore, holds: holdStore, notifier: notifier}
+}
+
+// Join adds the authenticated user to an event's waitlist. If places are
+// already free the entry is offered straight away.
+func (s *DBStore) Join(ctx context.Context, event, userName string, quantity int, ticketTypeID *int) (Entry, error) {
+	var userID *int
+	if id, ok := middleware.UserIDFromContext(ctx); ok {
+		userID = &id
+	}
+	// Check the ticket type now rather than when an offer is made. Asking for
+	// no tickets only fails if the type is wrong for the event or not on sale.
+	err := database.WithTx(ctx, s.db, func(tx *sqlx.Tx) error {
+		_, err := events.ReserveTicket(ctx, tx, event, ticketTypeID, 0)
+		return err
+	})
+	if err != nil {
+		return Entry{}, err
+	}
+	var e Entry
+	err = s.db.GetContext(ctx, &e, `INSERT INTO waitlist_entries (event, user_id, user_name, quantity, ticket_type_id)
+              VALUES ($1, $2, $3, $4, $5) RETURNING *`, event, userID, userName, quantity, ticketTypeID)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return Entry{}, ErrAlreadyWaiting
+	}
+	if err != nil {
+		return Entry{}, err
+	}
+	if _, err := s.Promote(ctx, event); err != nil {
+		log.Printf("Failed to promote waitlist for %s: %v", event, err)
+	}
+	return s.GetEntry(ctx, e.ID)
+}
+
+func (s *DBStore) GetEntry(ctx context.Context, id int) (Entry, error) {
+	var e Entry
+	err := s.db.GetContext(ctx, &e, selectEntries+" WHERE w.id = $1", id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Entry{}, ErrEntryNotFound
+	}
+	return e, err
+}
+
+// ListForUser returns a user's entries, most recent first
+func (s *DBStore) ListForUser(ctx context.Context, userID int) ([]Entry, error) {
+	var entries []Entry
+	err := s.db.SelectContext(ctx, &entries, selectEntries+" WHERE w.user_id = $1 ORDER BY w.id DESC", userID)
+	return entries, err
+}
+
+// ListForEvent returns the open entries of an event in waitlist order
+func (s *DBStore) ListForEvent(ctx context.Context, event string) ([]Entry, error) {
+	var entries []Entry
+	err := s.db.SelectContext(ctx, &entries,
+		selectEntries+" WHERE w.event = $1 AND w.status IN ('waiting', 'offered') ORDER BY w.id", event)
+	return entries, err
+}
+
+// Leave removes an entry from the waitlist, giving up any open offer
+func (s *DBStore) Leave(ctx context.Context, id int) error {
+	var event string
+	err := database.WithTx(ctx, s.db, func(tx *sqlx.Tx) error {
+		e, err := lockEntry(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if e.Status != StatusWaiting && e.Status != StatusOffered {
+			return nil
+		}
+		event = e.Event
+		if _, err := tx.ExecContext(ctx, "UPDATE waitlist_entries SET status = 'left', updated_at = now() WHERE id = $1", id); err != nil {
+			return err
+		}
+		if e.Status == StatusOffered && e.HoldID != nil {
+			return holds.ReleaseHoldTx(ctx, tx, *e.HoldID)
+		}
+		return nil
+	})
+	if err == nil && event != "" {
+		// The release event also promotes, but doing it now is quicker.
+		if _, err := s.Promote(ctx, event); err != nil {
+			log.Printf("Failed to promote waitlist for %s: %v", event, err)
+		}
+	}
+	return err
+}
+
+// Claim turns an open offer into confirmed bookings, each with the given
+// attendee answers. Offers are made without a waiting room admission, so
+// claiming one needs it instead.
+func (s *DBStore) Claim(ctx context.Context, id int, answers json.RawMessage) ([]bookings.Booking, error) {
+	var created []bookings.Booking
+	err := database.WithTx(ctx, s.db, func(tx *sqlx.Tx) error {
+		e, err := lockEntry(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if e.Status != StatusOffered || e.HoldID == nil {
+			return ErrNotOffered
+		}
+		if err := waitingroom.UseAdmissionTx(ctx, tx, e.Event, e.UserID); err != nil {
+			return err
+		}
+		if created, err = s.holds.ConvertHoldTx(ctx, tx, *e.HoldID, answers); err != nil {
+			if errors.Is(err, holds.ErrHoldNotActive) {
+				return ErrNotOffered
+			}
+			return err
+		}
+		_, err = tx.ExecContext(ctx, "UPDATE waitlist_entries SET status = 'claimed', updated_at = now() WHERE id = $1", id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// Promote offers free places to waiting entries in order until the event is
+// full or nobody is left waiting, and returns how many offers were made.
+func (s *DBStore) Promote(ctx context.Context, event string) (int, error) {
+	window := DefaultClaimWindow
+	if ev, err := s.events.GetEventByName(ctx, event); err == nil && ev.WaitlistClaimMinutes != nil {
+		window = time.Duration(*ev.WaitlistClaimMinutes) * time.Minute
+	} else if err != nil && !errors.Is(err, events.ErrEventNotFound) {
+		return 0, err
+	}
+	offered := 0
+	for {
+		e, ok, err := s.offerNext(ctx, event, window)
+		if err != nil || !ok {
+			return offered, err
+		}
+		offered++
+		n := notify.Notification{
+			UserID:   e.UserID,
+			UserName: e.UserName,
+			Subject:  fmt.Sprintf("Places available for %s", e.Event),
+			Body: fmt.Sprintf("%d place(s) are held for you until %s. Claim them with POST /waitlist/%d/claim.",
+				e.Quantity, e.OfferExpiresAt.Format(time.RFC3339), e.ID),
+		}
+		if err := s.notifier.Notify(ctx, n); err != nil {
+			log.Printf("Failed to notify waitlist entry %d: %v", e.ID, err)
+		}
+	}
+}
+
+// offerNext holds places for the first waiting entry if they fit. The entry
+// row is locked without SKIP LOCKED so concurrent promotions keep FIFO order.
+func (s *DBStore) offerNext(ctx context.Context, event string, window time.Duration) (Entry, bool, error) {
+	var e Entry
+	err := database.WithTx(ctx, s.db, func(tx *sqlx.Tx) error {
+		err := tx.GetContext(ctx, &e, `SELECT * FROM waitlist_entries
+              WHERE event = $1 AND status = 'waiting' ORDER BY id LIMIT 1 FOR UPDATE`, event)
+		if err != nil {
+			return err
+		}
+		h, err := holds.CreateHoldTx(ctx, tx, holds.NewHold{
+			Event:        e.Event,
+			UserID:       e.UserID,
+			UserName:     e.UserName,
+			Quantity:     e.Quantity,
+			TicketTypeID: e.TicketTypeID,
+			TTL:          window,
+		})
+		if err != nil {
+			return err
+		}
+		return tx.GetContext(ctx, &e, `UPDATE waitlist_entries
+              SET status = 'offered', hold_id = $1, offered_at = now(), offer_expires_at = $2, updated_at = now()
+              WHERE id = $3 RETURNING *`, h.ID, h.ExpiresAt, e.ID)
+	})
+	if errors.Is(err, sql.ErrNoRows) || errors.Is(err, events.ErrEventFull) || errors.Is(err, events.ErrSoldOut) {
+		return Entry{}, false, nil
+	}
+	if err != nil {
+		return Entry{}, false, err
+	}
+	return e, true, nil
+}
+
+// holdEnded settles the offer backed by a hold that was converted, released
+// or expired outside the waitlist endpoints.
+func (s *DBStore) holdEnded(ctx context.Context, holdID int, holdStatus string) error {
+	status := StatusExpired
+	if holdStatus == holds.StatusConverted {
+		status = StatusClaimed
+	}
+	_, err := s.db.ExecContext(ctx, `UPDATE waitlist_entries SET status = $1, updated_at = now()
+              WHERE hold_id = $2 AND status = 'offered'`, status, holdID)
+	return err
+}
+
+func lockEntry(ctx context.Context, tx *sqlx.Tx, id int) (Entry, error) {
+	var e Entry
+	err := tx.GetContext(ctx, &e, "SELECT * FROM waitlist_entries WHERE id = $1 FOR UPDATE", id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Entry{}, ErrEntryNotFound
+	}
+	if err != nil {
+		return Entry{}, err
+	}
+	if !canUse(ctx, e) {
+		return Entry{}, ErrForbidden
+	}
+	return e, nil
+}
+
+func canUse(ctx context.Context, e Entry) bool {
+	if e.UserID == nil || middleware.IsAdmin(ctx) {
+		return true
+	}
+	userID, ok := middleware.UserIDFromContext(ctx)
+	return ok && userID == *e.UserID
+}
@@ -0,0 +1,165 @@
+package waitlist
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"booking-app/internal/bookings"
+	"booking-app/internal/events"
+	"booking-app/internal/middleware"
+	"booking-app/internal/rules"
+	"booking-app/internal/waitingroom"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+)
+
+var validate = validator.New()
+
+type Handler struct {
+	store  *DBStore
+	events *events.DBStore
+}
+
+func NewHandler(store *DBStore, eventStore *events.DBStore) *Handler {
+	return &Handler{store: store, events: eventStore}
+}
+
+func (h *Handler) JoinHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		UserName     string `json:"user_name" validate:"required"`
+		Event        string `json:"event" validate:"required"`
+		Quantity     int    `json:"quantity" validate:"omitempty,min=1,max=50"`
+		TicketTypeID *int   `json:"ticket_type_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := validate.Struct(&input); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if input.Quantity == 0 {
+		input.Quantity = 1
+	}
+	entry, err := h.store.Join(r.Context(), input.Event, input.UserName, input.Quantity, input.TicketTypeID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, entry)
+}
+
+// ListHandler returns the caller's entries, or with ?event= the open entries
+// of an event the caller manages.
+func (h *Handler) ListHandler(w http.ResponseWriter, r *http.Request) {
+	if name := r.URL.Query().Get("event"); name != "" {
+		ev, err := h.events.GetEventByName(r.Context(), name)
+		if err != nil && !errors.Is(err, events.ErrEventNotFound) {
+			http.Error(w, "Failed to fetch event", http.StatusInternalServerError)
+			return
+		}
+		if !middleware.IsAdmin(r.Context()) && (err != nil || !events.CanManage(r.Context(), ev)) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		entries, err := h.store.ListForEvent(r.Context(), name)
+		if err != nil {
+			http.Error(w, "Failed to fetch waitlist", http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, entries)
+		return
+	}
+	userID, _ := middleware.UserIDFromContext(r.Context())
+	entries, err := h.store.ListForUser(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "Failed to fetch waitlist", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, entries)
+}
+
+func (h *Handler) GetHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	entry, err := h.store.GetEntry(r.Context(), id)
+	if err == nil && !canUse(r.Context(), entry) {
+		err = ErrForbidden
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, entry)
+}
+
+func (h *Handler) LeaveHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	if err := h.store.Leave(r.Context(), id); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) ClaimHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	var input struct {
+		// Answers respond to the event's attendee questions for every place
+		Answers json.RawMessage `json:"answers"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+	created, err := h.store.Claim(r.Context(), id, input.Answers)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, created)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrEntryNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrForbidden), errors.Is(err, waitingroom.ErrNotAdmitted):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, ErrAlreadyWaiting), errors.Is(err, ErrNotOffered), errors.Is(err, events.ErrEventFull),
+		errors.Is(err, events.ErrSoldOut), errors.Is(err, events.ErrNotOnSale), errors.Is(err, rules.ErrNotBookable),
+		errors.Is(err, bookings.ErrLimitReached), errors.Is(err, bookings.ErrOverlap):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, bookings.ErrInvalidAnswers):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	case errors.Is(err, events.ErrTicketTypeRequired), errors.Is(err, events.ErrTicketTypeNotFound):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
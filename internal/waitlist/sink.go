@@ -0,0 +1,62 @@
+package waitlist
+
+import (
+	"context"
+	"encoding/json"
+
+	"booking-app/internal/bookings"
+	"booking-app/internal/events"
+	"booking-app/internal/holds"
+	"booking-app/internal/outbox"
+)
+
+// Sink promotes waitlists when outbox events show places being freed:
+// cancelled bookings, holds that ended without converting, and events whose
+// capacity may have grown.
+type Sink struct {
+	store *DBStore
+}
+
+func NewSink(store *DBStore) *Sink {
+	return &Sink{store: store}
+}
+
+func (s *Sink) Name() string { return "waitlist" }
+
+func (s *Sink) Publish(ctx context.Context, e outbox.Event) error {
+	switch e.EventType {
+	case bookings.EventCancelled:
+		var b bookings.Booking
+		if err := json.Unmarshal(e.Payload, &b); err != nil {
+			return err
+		}
+		return s.promote(ctx, b.Event)
+	case holds.EventConverted, holds.EventReleased, holds.EventExpired:
+		var h holds.Hold
+		if err := json.Unmarshal(e.Payload, &h); err != nil {
+			return err
+		}
+		if err := s.store.holdEnded(ctx, h.ID, h.Status); err != nil {
+			return err
+		}
+		if h.Status == holds.StatusConverted {
+			return nil
+		}
+		return s.promote(ctx, h.Event)
+	case events.EventUpdated:
+		var ev events.Event
+		if err := json.Unmarshal(e.Payload, &ev); err != nil {
+			return err
+		}
+		return s.promote(ctx, ev.Name)
+	}
+	return nil
+}
+
+func (s *Sink) promote(ctx context.Context, event string) error {
+	if event == "" {
+		return nil
+	}
+	_, err := s.store.Promote(ctx, event)
+	return err
+}
@@ -0,0 +1,36 @@
+package waitlist
+
+import "time"
+
+// Entry statuses
+const (
+	StatusWaiting = "waiting"
+	StatusOffered = "offered"
+	StatusClaimed = "claimed"
+	StatusExpired = "expired"
+	StatusLeft    = "left"
+)
+
+// DefaultClaimWindow is how long a promoted customer has to claim their
+// places when the event does not configure its own window.
+const DefaultClaimWindow = 30 * time.Minute
+
+// Entry is a customer's place in an event's waitlist. When places free up the
+// first waiting entry is offered a hold lasting the claim window; if it is not
+// claimed in time the offer lapses and the next entry is offered.
+type Entry struct {
+	ID             int        `json:"id" db:"id"`
+	Event          string     `json:"event" db:"event"`
+	UserID         *int       `json:"user_id,omitempty" db:"user_id"`
+	UserName       string     `json:"user_name" db:"user_name"`
+	Quantity       int        `json:"quantity" db:"quantity"`
+	TicketTypeID   *int       `json:"ticket_type_id,omitempty" db:"ticket_type_id"`
+	Status         string     `json:"status" db:"status"`
+	HoldID         *int       `json:"hold_id,omitempty" db:"hold_id"`
+	OfferedAt      *time.Time `json:"offered_at,omitempty" db:"offered_at"`
+	OfferExpiresAt *time.Time `json:"offer_expires_at,omitempty" db:"offer_expires_at"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at" db:"updated_at"`
+	// Position is 1 for the next entry to be offered; only set while waiting
+	Position *int `json:"position,omitempty" db:"position"`
+}
@@ -0,0 +1,44 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Client posts signed webhook payloads to subscriber URLs
+type Client struct {
+	http *http.Client
+	now  func() time.Time
+}
+
+func NewClient(timeout time.Duration) *Client {
+	return &Client{http: &http.Client{Timeout: timeout}, now: time.Now}
+}
+
+// Send posts body to url and returns the response status code. Any non-2xx
+// response is reported as an error.
+func (c *Client) Send(ctx context.Context, url, secret, eventType, deliveryID string, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "booking-app-webhooks/1.0")
+	req.Header.Set(EventHeader, eventType)
+	req.Header.Set(DeliveryHeader, deliveryID)
+	req.Header.Set(SignatureHeader, Sign(secret, c.now(), body))
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("subscriber responded with status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
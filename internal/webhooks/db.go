@@ -0,0 +1,187 @@
+package webhooks
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"booking-app/internal/database"
+	"booking-app/internal/jobs"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	ErrSubscriptionNotFound = errors.New("webhook subscription not found")
+	ErrDeliveryNotFound     = errors.New("webhook delivery not found")
+	ErrDuplicateDelivery    = errors.New("event already delivered to subscription")
+	ErrDeliveryPending      = errors.New("webhook delivery is still being attempted")
+)
+
+// Store manages webhook subscriptions and deliveries in PostgreSQL
+type Store struct {
+	db *sqlx.DB
+}
+
+func NewStore(db *sqlx.DB) *Store {
+	return &Store{db: db}
+}
+
+func (s *Store) CreateSubscription(ctx context.Context, sub Subscription) (Subscription, error) {
+	var created Subscription
+	query := `INSERT INTO webhook_subscriptions (url, secret, event_types, is_active, created_by)
+              VALUES ($1, $2, $3, TRUE, $4) RETURNING *`
+	err := s.db.GetContext(ctx, &created, query, sub.URL, sub.Secret, sub.EventTypes, sub.CreatedBy)
+	return created, err
+}
+
+func (s *Store) GetSubscription(ctx context.Context, id int) (Subscription, error) {
+	var sub Subscription
+	err := s.db.GetContext(ctx, &sub, "SELECT * FROM webhook_subscriptions WHERE id = $1", id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Subscription{}, ErrSubscriptionNotFound
+	}
+	return sub, err
+}
+
+func (s *Store) ListSubscriptions(ctx context.Context) ([]Subscription, error) {
+	var subs []Subscription
+	err := s.db.SelectContext(ctx, &subs, "SELECT * FROM webhook_subscriptions ORDER BY id")
+	return subs, err
+}
+
+func (s *Store) ActiveSubscriptionsFor(ctx context.Context, eventType string) ([]Subscription, error) {
+	var subs []Subscription
+	err := s.db.SelectContext(ctx, &subs,
+		"SELECT * FROM webhook_subscriptions WHERE is_active AND $1 = ANY(event_types) ORDER BY id", eventType)
+	return subs, err
+}
+
+func (s *Store) DeleteSubscription(ctx context.Context, id int) error {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM webhook_subscriptions WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrSubscriptionNotFound
+	}
+	return nil
+}
+
+// deliverPayload is the payload of a JobDeliver job. Try counts the attempts
+// of the current round, which a redelivery starts afresh.
+type deliverPayload struct {
+	DeliveryID int64 `json:"delivery_id"`
+	Try        int   `json:"try"`
+}
+
+// CreateDelivery records a delivery and queues its first attempt, returning
+// ErrDuplicateDelivery if the subscription already has one for the same event.
+func (s *Store) CreateDelivery(ctx context.Context, d Delivery) (Delivery, error) {
+	var created Delivery
+	err := database.WithTx(ctx, s.db, func(tx *sqlx.Tx) error {
+		query := `INSERT INTO webhook_deliveries (subscription_id, event_id, event_type, payload, status)
+              VALUES ($1, $2, $3, $4, $5)
+              ON CONFLICT (subscription_id, event_id) DO NOTHING RETURNING *`
+		err := tx.GetContext(ctx, &created, query, d.SubscriptionID, d.EventID, d.EventType, string(d.Payload), d.Status)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrDuplicateDelivery
+		}
+		if err != nil {
+			return err
+		}
+		_, err = jobs.EnqueueTx(ctx, tx, jobs.NewJob{Type: JobDeliver, Payload: deliverPayload{DeliveryID: created.ID, Try: 1}})
+		return err
+	})
+	if err != nil {
+		return Delivery{}, err
+	}
+	return created, nil
+}
+
+func (s *Store) GetDelivery(ctx context.Context, id int64) (Delivery, error) {
+	var d Delivery
+	err := s.db.GetContext(ctx, &d, "SELECT * FROM webhook_deliveries WHERE id = $1", id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Delivery{}, ErrDeliveryNotFound
+	}
+	return d, err
+}
+
+func (s *Store) ListDeliveries(ctx context.Context, subscriptionID int) ([]Delivery, error) {
+	var deliveries []Delivery
+	err := s.db.SelectContext(ctx, &deliveries,
+		"SELECT * FROM webhook_deliveries WHERE subscription_id = $1 ORDER BY id DESC LIMIT 100", subscriptionID)
+	return deliveries, err
+}
+
+// ResetDelivery marks a delivery pending again and queues an attempt ahead
+// of a manual redelivery. Only finished deliveries can be reset: a pending
+// one already has an attempt queued, and a second would run alongside it.
+func (s *Store) ResetDelivery(ctx context.Context, id int64) (Delivery, error) {
+	var d Delivery
+	err := database.WithTx(ctx, s.db, func(tx *sqlx.Tx) error {
+		var status string
+		err := tx.GetContext(ctx, &status, "SELECT status FROM webhook_deliveries WHERE id = $1 FOR UPDATE", id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrDeliveryNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if status == StatusPending {
+			return ErrDeliveryPending
+		}
+		err = tx.GetContext(ctx, &d,
+			"UPDATE webhook_deliveries SET status = $1, updated_at = $2 WHERE id = $3 RETURNING *", StatusPending, time.Now(), id)
+		if err != nil {
+			return err
+		}
+		_, err = jobs.EnqueueTx(ctx, tx, jobs.NewJob{Type: JobDeliver, Payload: deliverPayload{DeliveryID: id, Try: 1}})
+		return err
+	})
+	if err != nil {
+		return Delivery{}, err
+	}
+	return d, nil
+}
+
+// RecordAttempt stores an attempt and updates its delivery's status in one
+// transaction. A delivery left pending is tried again at retryAt, as try
+// number next of its round.
+func (s *Store) RecordAttempt(ctx context.Context, a Attempt, status string, next int, retryAt time.Time) error {
+	return database.WithTx(ctx, s.db, func(tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(ctx, `INSERT INTO webhook_delivery_attempts (delivery_id, attempt, status_code, error, duration_ms)
+              VALUES ($1, $2, $3, $4, $5)`, a.DeliveryID, a.Attempt, a.StatusCode, a.Error, a.DurationMS)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `UPDATE webhook_deliveries
+              SET status = $1, attempts = $2, last_status_code = $3, last_error = $4, updated_at = $5
+              WHERE id = $6`, status, a.Attempt, a.StatusCode, a.Error, time.Now(), a.DeliveryID)
+		if err != nil || status != StatusPending {
+			return err
+		}
+		_, err = jobs.EnqueueTx(ctx, tx, jobs.NewJob{Type: JobDeliver, Payload: deliverPayload{DeliveryID: a.DeliveryID, Try: next}, RunAt: retryAt})
+		return err
+	})
+}
+
+// failDelivery gives up on a delivery without attempting it
+func (s *Store) failDelivery(ctx context.Context, id int64, reason string) error {
+	_, err := s.db.ExecContext(ctx, `UPDATE webhook_deliveries SET status = $1, last_error = $2, updated_at = $3
+              WHERE id = $4`, StatusFailed, reason, time.Now(), id)
+	return err
+}
+
+func (s *Store) ListAttempts(ctx context.Context, deliveryID int64) ([]Attempt, error) {
+	var attempts []Attempt
+	err := s.db.SelectContext(ctx, &attempts,
+		"SELECT * FROM webhook_delivery_attempts WHERE delivery_id = $1 ORDER BY attempt", deliveryID)
+	return attempts, err
+}
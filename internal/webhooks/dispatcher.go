@@ -0,0 +1,140 @@
+package webhooks
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"booking-app/internal/jobs"
+)
+
+var ErrSubscriptionInactive = errors.New("webhook subscription is inactive")
+
+// Dispatcher fans events out to subscriptions and delivers them with retries.
+// Attempts run as jobs, so they must be registered with a jobs.Worker
+// through DeliverJob.
+type Dispatcher struct {
+	store       *Store
+	client      *Client
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+}
+
+func NewDispatcher(store *Store, client *Client) *Dispatcher {
+	return &Dispatcher{
+		store:       store,
+		client:      client,
+		maxAttempts: 6,
+		baseDelay:   time.Second,
+		maxDelay:    5 * time.Minute,
+	}
+}
+
+// PublishEvent records a delivery of the event for every active subscription
+// to eventType and queues them for sending. Publishing the same event ID
+// again does not create duplicate deliveries.
+func (d *Dispatcher) PublishEvent(ctx context.Context, eventID, eventType string, data json.RawMessage) error {
+	subs, err := d.store.ActiveSubscriptionsFor(ctx, eventType)
+	if err != nil {
+		return err
+	}
+	if len(subs) == 0 {
+		return nil
+	}
+	payload, err := json.Marshal(Payload{ID: eventID, Type: eventType, CreatedAt: time.Now().UTC(), Data: data})
+	if err != nil {
+		return err
+	}
+	for _, sub := range subs {
+		_, err := d.store.CreateDelivery(ctx, Delivery{
+			SubscriptionID: sub.ID,
+			EventID:        eventID,
+			EventType:      eventType,
+			Payload:        payload,
+			Status:         StatusPending,
+		})
+		if errors.Is(err, ErrDuplicateDelivery) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Redeliver sends a finished delivery again, starting a fresh round of
+// retries
+func (d *Dispatcher) Redeliver(ctx context.Context, deliveryID int64) (Delivery, error) {
+	delivery, err := d.store.GetDelivery(ctx, deliveryID)
+	if err != nil {
+		return Delivery{}, err
+	}
+	sub, err := d.store.GetSubscription(ctx, delivery.SubscriptionID)
+	if err != nil {
+		return Delivery{}, err
+	}
+	if !sub.IsActive {
+		return Delivery{}, ErrSubscriptionInactive
+	}
+	return d.store.ResetDelivery(ctx, deliveryID)
+}
+
+// DeliverJob makes one attempt at a pending delivery and schedules the next
+// one if it fails, until the round runs out of attempts. Errors are only
+// returned when the outcome could not be recorded, so the job is retried.
+func (d *Dispatcher) DeliverJob(ctx context.Context, p deliverPayload) error {
+	delivery, err := d.store.GetDelivery(ctx, p.DeliveryID)
+	if errors.Is(err, ErrDeliveryNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if delivery.Status != StatusPending {
+		return nil
+	}
+	sub, err := d.store.GetSubscription(ctx, delivery.SubscriptionID)
+	if errors.Is(err, ErrSubscriptionNotFound) {
+		return d.store.failDelivery(ctx, delivery.ID, "subscription deleted")
+	}
+	if err != nil {
+		return err
+	}
+	if !sub.IsActive {
+		return d.store.failDelivery(ctx, delivery.ID, ErrSubscriptionInactive.Error())
+	}
+	attempt := Attempt{DeliveryID: delivery.ID, Attempt: delivery.Attempts + 1}
+	start := time.Now()
+	code, sendErr := d.client.Send(ctx, sub.URL, sub.Secret, delivery.EventType, strconv.FormatInt(delivery.ID, 10), delivery.Payload)
+	attempt.DurationMS = int(time.Since(start).Milliseconds())
+	if code != 0 {
+		attempt.StatusCode = &code
+	}
+	status := StatusSucceeded
+	if sendErr != nil {
+		msg := sendErr.Error()
+		attempt.Error = &msg
+		status = StatusPending
+		if p.Try >= d.maxAttempts {
+			status = StatusFailed
+		}
+		log.Printf("Webhook delivery %d attempt %d failed: %v", delivery.ID, attempt.Attempt, sendErr)
+	}
+	retryAt := time.Now().Add(jobs.Backoff(p.Try, d.baseDelay, d.maxDelay))
+	return d.store.RecordAttempt(ctx, attempt, status, p.Try+1, retryAt)
+}
+
+func newID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,142 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"booking-app/internal/middleware"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+)
+
+var validate = validator.New()
+
+type Handler struct {
+	store      *Store
+	dispatcher *Dispatcher
+}
+
+func NewHandler(store *Store, dispatcher *Dispatcher) *Handler {
+	return &Handler{store: store, dispatcher: dispatcher}
+}
+
+// CreateSubscription registers a webhook. When no secret is supplied one is
+// generated; this is the only response that includes the secret.
+func (h *Handler) CreateSubscription(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		URL        string   `json:"url" validate:"required,url,startswith=http"`
+		Secret     string   `json:"secret" validate:"omitempty,min=16"`
+		EventTypes []string `json:"event_types" validate:"required,min=1,dive,oneof=booking.created booking.updated booking.cancelled"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := validate.Struct(&input); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if input.Secret == "" {
+		secret, err := newID()
+		if err != nil {
+			http.Error(w, "Failed to generate secret", http.StatusInternalServerError)
+			return
+		}
+		input.Secret = secret
+	}
+	sub := Subscription{URL: input.URL, Secret: input.Secret, EventTypes: input.EventTypes}
+	if userID, ok := middleware.UserIDFromContext(r.Context()); ok {
+		sub.CreatedBy = &userID
+	}
+	sub, err := h.store.CreateSubscription(r.Context(), sub)
+	if err != nil {
+		http.Error(w, "Failed to create subscription", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusCreated, sub)
+}
+
+func (h *Handler) ListSubscriptions(w http.ResponseWriter, r *http.Request) {
+	subs, err := h.store.ListSubscriptions(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to fetch subscriptions", http.StatusInternalServerError)
+		return
+	}
+	for i := range subs {
+		subs[i].Secret = ""
+	}
+	writeJSON(w, http.StatusOK, subs)
+}
+
+func (h *Handler) DeleteSubscription(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	if err := h.store.DeleteSubscription(r.Context(), id); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	deliveries, err := h.store.ListDeliveries(r.Context(), id)
+	if err != nil {
+		http.Error(w, "Failed to fetch deliveries", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, deliveries)
+}
+
+func (h *Handler) ListAttempts(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	attempts, err := h.store.ListAttempts(r.Context(), id)
+	if err != nil {
+		http.Error(w, "Failed to fetch delivery attempts", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, attempts)
+}
+
+func (h *Handler) Redeliver(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	delivery, err := h.dispatcher.Redeliver(r.Context(), id)
+	switch {
+	case errors.Is(err, ErrDeliveryNotFound), errors.Is(err, ErrSubscriptionNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, ErrSubscriptionInactive), errors.Is(err, ErrDeliveryPending):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, "Failed to redeliver", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, delivery)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,72 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers sent with every webhook request
+const (
+	SignatureHeader = "X-Webhook-Signature"
+	EventHeader     = "X-Webhook-Event"
+	DeliveryHeader  = "X-Webhook-Delivery"
+)
+
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// Sign computes the signature header value for body sent at ts. The HMAC
+// covers "<unix timestamp>.<body>" so a captured request cannot be replayed
+// with a fresh timestamp.
+func Sign(secret string, ts time.Time, body []byte) string {
+	return fmt.Sprintf("t=%d,v1=%s", ts.Unix(), computeMAC(secret, ts.Unix(), body))
+}
+
+// Verify checks a signature header produced by Sign, rejecting signatures
+// older than tolerance. Receivers can use it to authenticate deliveries.
+func Verify(secret, header string, body []byte, tolerance time.Duration, now time.Time) error {
+	var ts int64
+	var sigs []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "t":
+			parsed, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return ErrInvalidSignature
+			}
+			ts = parsed
+		case "v1":
+			sigs = append(sigs, value)
+		}
+	}
+	if ts == 0 || len(sigs) == 0 {
+		return ErrInvalidSignature
+	}
+	if age := now.Sub(time.Unix(ts, 0)); age > tolerance || age < -tolerance {
+		return fmt.Errorf("%w: timestamp outside tolerance", ErrInvalidSignature)
+	}
+	expected := computeMAC(secret, ts, body)
+	for _, sig := range sigs {
+		if hmac.Equal([]byte(sig), []byte(expected)) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
+
+func computeMAC(secret string, ts int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(ts, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
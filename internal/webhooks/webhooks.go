@@ -0,0 +1,74 @@
+package webhooks
+
+import (
+	"time"
+
+	"github.com/jmoiron/sqlx/types"
+	"github.com/lib/pq"
+)
+
+// Delivery statuses
+const (
+	StatusPending   = "pending"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// JobDeliver is the job type that makes one attempt at a delivery. Each
+// retry is a job of its own, so pending deliveries survive restarts.
+const JobDeliver = "webhook.deliver"
+
+type Subscription struct {
+	ID         int            `json:"id" db:"id"`
+	URL        string         `json:"url" db:"url"`
+	Secret     string         `json:"secret,omitempty" db:"secret"`
+	EventTypes pq.StringArray `json:"event_types" db:"event_types"`
+	IsActive   bool           `json:"is_active" db:"is_active"`
+	CreatedBy  *int           `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt  time.Time      `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at" db:"updated_at"`
+}
+
+// Delivery is one event sent to one subscription, possibly over several attempts
+type Delivery struct {
+	ID             int64          `json:"id" db:"id"`
+	SubscriptionID int            `json:"subscription_id" db:"subscription_id"`
+	EventID        string         `json:"event_id" db:"event_id"`
+	EventType      string         `json:"event_type" db:"event_type"`
+	Payload        types.JSONText `json:"payload" db:"payload"`
+	Status         string         `json:"status" db:"status"`
+	Attempts       int            `json:"attempts" db:"attempts"`
+	LastStatusCode *int           `json:"last_status_code,omitempty" db:"last_status_code"`
+	LastError      *string        `json:"last_error,omitempty" db:"last_error"`
+	CreatedAt      time.Time      `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at" db:"updated_at"`
+}
+
+// Attempt records a single HTTP request made for a delivery
+type Attempt struct {
+	ID          int64     `json:"id" db:"id"`
+	DeliveryID  int64     `json:"delivery_id" db:"delivery_id"`
+	Attempt     int       `json:"attempt" db:"attempt"`
+	StatusCode  *int      `json:"status_code,omitempty" db:"status_code"`
+	Error       *string   `json:"error,omitempty" db:"error"`
+	DurationMS  int       `json:"duration_ms" db:"duration_ms"`
+	AttemptedAt time.Time `json:"attempted_at" db:"attempted_at"`
+}
+
+// Payload is the JSON body posted to subscribers
+type Payload struct {
+	ID        string      `json:"id"`
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// Subscribes reports whether the subscription wants events of the given type
+func (s Subscription) Subscribes(eventType string) bool {
+	for _, t := range s.EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,73 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSendSignsPayload(t *testing.T) {
+	secret := "test-secret-0123456789"
+	body := []byte(`{"id":"evt_1","type":"booking.created","data":{"id":1}}`)
+	received := make(chan *http.Request, 1)
+	var receivedBody []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		receivedBody, _ = io.ReadAll(r.Body)
+		received <- r
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client := NewClient(5 * time.Second)
+	code, err := client.Send(context.Background(), server.URL, secret, "booking.created", "42", body)
+	if err != nil {
+		t.Fatalf("Failed to send webhook: %v", err)
+	}
+	if code != http.StatusNoContent {
+		t.Errorf("Expected status 204, got %d", code)
+	}
+	r := <-received
+	if r.Header.Get(EventHeader) != "booking.created" || r.Header.Get(DeliveryHeader) != "42" {
+		t.Errorf("Unexpected event headers: %v", r.Header)
+	}
+	if err := Verify(secret, r.Header.Get(SignatureHeader), receivedBody, 5*time.Minute, time.Now()); err != nil {
+		t.Errorf("Expected valid signature, got %v", err)
+	}
+	if err := Verify("wrong-secret", r.Header.Get(SignatureHeader), receivedBody, 5*time.Minute, time.Now()); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Expected signature mismatch for wrong secret, got %v", err)
+	}
+}
+
+func TestSendReportsFailureStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	code, err := NewClient(5*time.Second).Send(context.Background(), server.URL, "secret", "booking.updated", "1", []byte(`{}`))
+	if err == nil {
+		t.Fatal("Expected error for 503 response, got nil")
+	}
+	if code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503, got %d", code)
+	}
+}
+
+func TestVerifyRejectsTamperedAndStale(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	body := []byte(`{"a":1}`)
+	header := Sign("secret", now, body)
+	if err := Verify("secret", header, []byte(`{"a":2}`), time.Minute, now); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Expected tampered body to be rejected, got %v", err)
+	}
+	if err := Verify("secret", header, body, time.Minute, now.Add(2*time.Minute)); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Expected stale signature to be rejected, got %v", err)
+	}
+	if err := Verify("secret", "garbage", body, time.Minute, now); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Expected malformed header to be rejected, got %v", err)
+	}
+}